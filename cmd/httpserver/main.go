@@ -1,32 +1,171 @@
 package main
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
-	"io"
+	"context"
+	"flag"
+	"fmt"
 	"log"
-	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
+	"github.com/paokimsiwoong/httpfromtcp/internal/conditional"
+	"github.com/paokimsiwoong/httpfromtcp/internal/fileserver"
 	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/metrics"
+	"github.com/paokimsiwoong/httpfromtcp/internal/middleware"
+	"github.com/paokimsiwoong/httpfromtcp/internal/proxy"
 	"github.com/paokimsiwoong/httpfromtcp/internal/request"
 	"github.com/paokimsiwoong/httpfromtcp/internal/response"
 	"github.com/paokimsiwoong/httpfromtcp/internal/server"
+	"github.com/paokimsiwoong/httpfromtcp/internal/sse"
+	"github.com/paokimsiwoong/httpfromtcp/internal/websocket"
 )
 
 const port = 42069
 
+// 서버가 연결을 받을 주소 (ex: -addr 127.0.0.1:8080, -network unix -addr /tmp/httpfromtcp.sock)
+var (
+	network = flag.String("network", "tcp", "network to listen on: tcp, tcp4, tcp6 or unix")
+	addr    = flag.String("addr", fmt.Sprintf(":%v", port), "address to listen on (host:port or unix socket path)")
+	// 둘 다 주어지면 HTTPS로 동작
+	tlsCert = flag.String("tls-cert", "", "PEM certificate file for serving HTTPS")
+	tlsKey  = flag.String("tls-key", "", "PEM private key file for serving HTTPS")
+	// 접근 로그 형식
+	accessLog = flag.String("access-log", "combined", "access log format: common, combined, json or off")
+	// Prometheus 메트릭을 노출할 경로 (빈 문자열이면 노출하지 않음)
+	metricsPath = flag.String("metrics-path", "/metrics", "route that serves Prometheus metrics (empty to disable)")
+	// /httpbin 아래 request들을 전달할 upstream 주소 (쉼표로 여러 개를 주면 load balancing)
+	proxyTarget   = flag.String("proxy-target", "https://httpbin.org", "comma-separated upstream URLs that /httpbin/... requests are forwarded to")
+	proxyStrategy = flag.String("proxy-strategy", "round-robin", "how to pick among several upstreams: round-robin, least-conn or hash")
+	proxyHealth   = flag.String("proxy-health-path", "", "path to poll on each upstream for active health checks (empty to disable)")
+	// forward proxy로도 동작할지 여부 (absolute-form request와 CONNECT 처리)
+	forwardProxy     = flag.Bool("forward-proxy", false, "also act as an HTTP forward proxy for absolute-form and CONNECT requests")
+	forwardProxyAuth = flag.String("forward-proxy-auth", "", "user:password required in Proxy-Authorization (empty for no auth)")
+	// Accept-Encoding에 따라 response body를 gzip/deflate로 압축할지 여부
+	compress = flag.Bool("compress", true, "compress response bodies with gzip or deflate when the client accepts it")
+)
+
+// 서버 메트릭들을 모아두는 레지스트리
+var registry = metrics.NewRegistry()
+
+// 정적 파일들이 있는 디렉토리와 /assets/ 경로로 그 파일들을 보내는 handler
+var (
+	assets        = fileserver.Dir("assets")
+	assetsHandler = fileserver.New(assets, fileserver.WithPrefix("/assets"), fileserver.WithDirectoryListing())
+)
+
+// /httpbin/... request를 -proxy-target으로 전달하는 handler (main에서 flag를 읽은 뒤 만든다)
+// ex) /httpbin/stream/3 => https://httpbin.org/stream/3
+var proxyHandler server.Handler
+
+// -forward-proxy가 켜져 있을 때 absolute-form, CONNECT request를 처리하는 handler
+var forwardHandler server.Handler
+
+// -proxy-* flag로 proxyHandler를 만드는 함수
+// upstream이 여러 개면 load balancing pool을 만들어서 반환한다 (하나면 nil)
+func newProxyHandler() (*proxy.Pool, error) {
+	targets := []*url.URL{}
+	for _, raw := range strings.Split(*proxyTarget, ",") {
+		target, err := url.Parse(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+
+	opts := []proxy.Option{proxy.WithStripPrefix("/httpbin"), proxy.WithTimeout(30 * time.Second)}
+
+	if len(targets) == 1 {
+		proxyHandler = proxy.New(targets[0], opts...)
+		return nil, nil
+	}
+
+	strategy := proxy.RoundRobin
+	switch *proxyStrategy {
+	case "least-conn":
+		strategy = proxy.LeastConnections
+	case "hash":
+		strategy = proxy.ConsistentHash
+	}
+
+	poolOpts := []proxy.PoolOption{proxy.WithStrategy(strategy)}
+	if *proxyHealth != "" {
+		poolOpts = append(poolOpts, proxy.WithHealthCheck(*proxyHealth, 10*time.Second, 2*time.Second))
+	}
+
+	pool := proxy.NewPool(targets, poolOpts...)
+	proxyHandler = proxy.NewBalanced(pool, opts...)
+
+	return pool, nil
+}
+
+// 종료 신호를 받은 뒤 처리 중인 request들을 기다려주는 최대 시간
+const shutdownTimeout = 10 * time.Second
+
 func main() {
-	server, err := server.Serve(port, handler)
+	flag.Parse()
+
+	if *forwardProxy {
+		opts := []proxy.ForwardOption{}
+		if user, password, ok := strings.Cut(*forwardProxyAuth, ":"); ok {
+			opts = append(opts, proxy.WithProxyUser("httpfromtcp", user, password))
+		}
+		forwardHandler = proxy.NewForward(opts...)
+	}
+
+	pool, err := newProxyHandler()
+	if err != nil {
+		log.Fatalf("Error parsing proxy target: %v", err)
+	}
+	if pool != nil {
+		defer pool.Close()
+	}
+
+	opts := []server.Option{
+		server.WithMetrics(registry),
+		server.WithReadHeaderTimeout(10 * time.Second),
+		server.WithIdleTimeout(2 * time.Minute),
+		// proxy로 넘기는 request body는 메모리에 모으지 않고 받는 대로 upstream으로 보낸다
+		server.WithStreamingBody(isProxyRoute),
+		// @@@ 압축된 body도 읽는 동안 이 크기를 넘으면 413으로 거절한다 (DecompressRequest의 크기 제한은 풀린 body에 적용)
+		server.WithMaxBodySize(middleware.DefaultMaxDecodedBody),
+	}
+	if *tlsCert != "" || *tlsKey != "" {
+		tlsConfig, err := server.LoadTLSConfig(*tlsCert, *tlsKey)
+		if err != nil {
+			log.Fatalf("Error loading TLS certificate: %v", err)
+		}
+		opts = append(opts, server.WithTLSConfig(tlsConfig))
+	}
+
+	// gzip/deflate로 압축해서 올린 request body는 풀어서 handler에 넘긴다
+	// @@@ proxy로 가는 request는 body를 그대로 upstream에 넘기므로 풀지 않는다 (읽는 대로 보내는 body를 메모리에 모으게 된다)
+	h := middleware.Chain(handler, middleware.Unless(isProxyRoute, middleware.DecompressRequest(middleware.DefaultMaxDecodedBody)))
+	if *compress {
+		h = middleware.Chain(h, middleware.Compress())
+	}
+	// @@@ 접근 로그가 압축된 body 바이트 수를 기록하도록 압축보다 바깥에 둔다
+	if *accessLog != "off" {
+		format := middleware.LogFormatCombined
+		switch *accessLog {
+		case "common":
+			format = middleware.LogFormatCommon
+		case "json":
+			format = middleware.LogFormatJSON
+		}
+		h = middleware.Chain(h, middleware.AccessLog(middleware.NewAccessLogger(os.Stdout, format)))
+	}
+
+	server, err := server.ServeAddr(*network, *addr, h, opts...)
 	if err != nil {
 		log.Fatalf("Error starting server: %v", err)
 	}
-	defer server.Close()
-	log.Println("Server started on port", port)
+	log.Println("Server started on", server.Addr())
 
 	// server는 request를 go 루틴으로 처리하고 바로 반환되는 함수이므로
 	// main 함수가 바로 끝나지 않도록 기다리게 하는 부분이 필요
@@ -41,12 +180,39 @@ func main() {
 	<-sigChan
 	// 신호가 올 때까진 여기서 sigChan이 블락해서 main 함수 종료를 막는다
 	// @@@@@@@@
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err = server.Shutdown(ctx)
+	if err != nil {
+		log.Printf("Server forced to stop: %v", err)
+		return
+	}
 	log.Println("Server gracefully stopped")
 }
 
+// request가 proxy handler(forwardHandler, proxyHandler)로 가는지 확인하는 함수
+func isProxyRoute(req *request.Request) bool {
+	if forwardHandler != nil && proxy.IsForwardRequest(req) {
+		return true
+	}
+	return strings.HasPrefix(req.RequestLine.RequestTarget, "/httpbin")
+}
+
 func handler(w *response.Writer, req *request.Request) {
 	headers := headers.NewHeaders()
 
+	if forwardHandler != nil && proxy.IsForwardRequest(req) {
+		forwardHandler(w, req)
+		return
+	}
+
+	if *metricsPath != "" && req.RequestLine.RequestTarget == *metricsPath {
+		metrics.Handler(registry)(w, req)
+		return
+	}
+
 	switch req.RequestLine.RequestTarget {
 	case "/yourproblem":
 		ErrorHandler(w, req, 400)
@@ -56,22 +222,23 @@ func handler(w *response.Writer, req *request.Request) {
 		// @@@ ErrorHandler를 쓰면서 바디 내용이 기존의 문제 답변과는 달라짐
 	case "/video":
 		if req.RequestLine.Method == "GET" {
-			videoHandler(w, req, headers)
+			videoHandler(w, req)
 			return
 		}
 		ErrorHandler(w, req, 400)
+	case "/ws":
+		wsEchoHandler(w, req)
+	case "/events":
+		eventsHandler(w, req)
 	default:
 		if strings.HasPrefix(req.RequestLine.RequestTarget, "/httpbin") {
-			proxyHandler(w, req, headers)
+			proxyHandler(w, req)
 			return
 		}
-		err := w.WriteStatusLine(response.StatusOK)
-		if err != nil {
-			log.Printf("error writing status line: %v", err)
-			ErrorHandler(w, req, 500)
+		if strings.HasPrefix(req.RequestLine.RequestTarget, "/assets/") {
+			assetsHandler(w, req)
 			return
 		}
-
 		body := `<html>
   <head>
     <title>200 OK</title>
@@ -82,6 +249,20 @@ func handler(w *response.Writer, req *request.Request) {
   </body>
 </html>`
 
+		// body가 고정되어 있으므로 내용으로 만든 ETag로 재요청 시 304 Not Modified
+		validators := conditional.Validators{ETag: conditional.StrongETag([]byte(body))}
+		if conditional.Handle(w, req, validators, headers) {
+			return
+		}
+		validators.SetHeaders(headers)
+
+		err := w.WriteStatusLine(response.StatusOK)
+		if err != nil {
+			log.Printf("error writing status line: %v", err)
+			ErrorHandler(w, req, 500)
+			return
+		}
+
 		headers.SetOverride("Content-Length", strconv.Itoa(len(body)))
 		headers.SetOverride("Connection", "close")
 		headers.SetOverride("Content-Type", "text/html")
@@ -102,209 +283,65 @@ func handler(w *response.Writer, req *request.Request) {
 	}
 }
 
-func proxyHandler(w *response.Writer, req *request.Request, headers headers.Headers) {
-	route := strings.TrimPrefix(req.RequestLine.RequestTarget, "/httpbin")
-
-	resp, err := http.Get("https://httpbin.org" + route)
-	if err != nil {
-		log.Printf("error making HTTP request: %v", err)
-		ErrorHandler(w, req, 500)
-		return
-	}
-
-	// 메모리에 올라가는 데이터 Close defer
-	defer resp.Body.Close()
-	// @@@ Go의 http.Response.Body는 chunked 인코딩을 자동으로 해제해서, 읽으면 바로 payload body만 나온다
-
-	// status code가 200이 아닌 경우 처리
-	if resp.StatusCode > 499 {
-		ErrorHandler(w, req, 500)
-		return
-	}
-	if resp.StatusCode > 399 {
-		ErrorHandler(w, req, 400)
-		return
-	}
-
-	err = w.WriteStatusLine(response.StatusOK)
-	if err != nil {
-		log.Printf("error writing status line: %v", err)
-		ErrorHandler(w, req, 500)
-		return
-	}
-
-	cotentType := resp.Header.Get("Content-Type")
-	headers.SetOverride("Content-Type", cotentType)
-
-	// @@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@
-	// @@@ https://httpbin.org/stream/{n} 자체는 Transfer-Encoding 헤더 없음
-	// @@@ 단순히 n개의 JSON response를 보낸다
-	// log.Printf("response headers key Transfer-Encoding value: %v", resp.Header.Get("Transfer-Encoding"))
-	// if resp.Header.Get("Transfer-Encoding") == "chunked" {
-	// 	headers.SetOverride("Transfer-Encoding", "chunked")
-	// } else {
-	// 	// ???
-	// 	log.Printf("error not chunks?: %v", err)
-	// 	return
-	// }
-	// @@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@
-
-	// @@@ route 상관없이 다 무조건 chunk로 쪼개기 @@@
-	headers.SetOverride("Transfer-Encoding", "chunked")
-
-	headers.SetOverride("Trailer", "X-Content-SHA256, X-Content-Length")
+// 영상 파일을 assets 디렉토리에서 메모리에 다 올리지 않고 나눠서 보내는 handler
+// @@@ os.ReadFile + video/mp4 고정 대신 fileserver.ServeFile 사용 (Content-Type은 확장자로)
+func videoHandler(w *response.Writer, req *request.Request) {
+	fileserver.ServeFile(w, req, assets, "vim.mp4")
+}
 
-	err = w.WriteHeaders(headers)
+// WebSocket으로 upgrade한 뒤 받은 메시지를 그대로 돌려보내는 handler
+func wsEchoHandler(w *response.Writer, req *request.Request) {
+	conn, err := websocket.Upgrade(w, req)
 	if err != nil {
-		log.Printf("error writing headers: %v", err)
-		ErrorHandler(w, req, 500)
+		log.Printf("websocket handshake failed: %v", err)
 		return
 	}
 
-	rawBodyLength := 0
-	// rawBody := []byte{}
-	rawBody := make([]byte, 0)
-	// 위 두 방식은 길이 0, 용량 0
-	// var rawBody []byte
-	// nil 슬라이스, 길이 0, 용량 0
-
-	// resp.Body.Read 루프
-	for {
-		// @@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@
-		// buffer := make([]byte, 0, 32)
-		// @@@ Read 메소드는 주어진 []byte 슬라이스의 길이만큼 읽는데
-		// @@@ cap만 32로 하고 길이를 0으로 둬버리면 매번 0 바이트만 읽는다
-		// @@@ ==> buffer := make([]byte, 32) 와 같이 생성
-		// @@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@
-		buffer := make([]byte, 1024)
-		n, err := resp.Body.Read(buffer)
-		log.Printf("%v bytes read from the response body", n)
-		if err == io.EOF {
-			if n != 0 {
-				rawBodyLength += n
-				rawBody = append(rawBody, buffer[:n]...)
-
-				_, err = w.WriteChunkedBody(buffer[:n]) // 실제 데이터가 들어있는 부분까지만 입력
-				if err != nil {
-					log.Printf("error writing a chunk: %v", err)
-					ErrorHandler(w, req, 500)
-					return
-				}
-
+	// @@@ Hijack한 연결은 handler가 반환된 뒤에도 쓸 수 있으므로 고루틴에서 처리하고 handler는 바로 반환
+	go func() {
+		defer conn.Close()
+		for {
+			op, data, err := conn.ReadMessage()
+			if err != nil {
+				return
 			}
-
-			// resp.Body안의 모든 chunk가 w에 저장되었으므로 w.WriteChunkedBodyDone 실행
-			_, err = w.WriteChunkedBodyDone()
+			err = conn.WriteMessage(op, data)
 			if err != nil {
-				log.Printf("error writing a chunk end: %v", err)
-				ErrorHandler(w, req, 500)
 				return
 			}
-
-			break
 		}
-
-		// 읽어낸 조각 길이와 조각을 따로 저장
-		rawBodyLength += n
-		rawBody = append(rawBody, buffer[:n]...)
-
-		_, err = w.WriteChunkedBody(buffer[:n]) // 실제 데이터가 들어있는 부분까지만 입력
-		if err != nil {
-			log.Printf("error writing a chunk: %v", err)
-			ErrorHandler(w, req, 500)
-			return
-		}
-	}
-
-	hash := sha256.Sum256(rawBody)
-	hashString := hex.EncodeToString(hash[:])
-	// hash는 길이가 정해져있으므로 [:]를 붙여서 []byte 슬라이스로 변환
-	log.Printf("hash string: %s", hashString)
-	log.Printf("raw body length: %d", rawBodyLength)
-
-	headers["X-Content-SHA256"] = hashString
-	headers["X-Content-Length"] = strconv.Itoa(rawBodyLength)
-
-	err = w.WriteTrailers(headers)
-	if err != nil {
-		log.Printf("error writing trailers: %v", err)
-		ErrorHandler(w, req, 500)
-		return
-	}
-
-	// if strings.HasPrefix(route, "/stream") {
-	// } else {
-	// 	headers.SetOverride("Trailer", "X-Content-SHA256, X-Content-Length")
-
-	// 	err = w.WriteHeaders(headers)
-	// 	if err != nil {
-	// 		log.Printf("error writing headers: %v", err)
-	// 		ErrorHandler(w, req, 500)
-	// 		return
-	// 	}
-
-	// 	body, err := io.ReadAll(resp.Body)
-	// 	if err != nil {
-	// 		log.Printf("error reading response body: %v", err)
-	// 		ErrorHandler(w, req, 500)
-	// 		return
-	// 	}
-
-	// 	_, err = w.WriteBody([]byte(body))
-	// 	if err != nil {
-	// 		log.Printf("error writing body: %v", err)
-	// 		ErrorHandler(w, req, 500)
-	// 		return
-	// 	}
-
-	// 	hash := sha256.Sum256(body)
-	// 	hashString := hex.EncodeToString(hash[:])
-	// 	// hash는 길이가 정해져있으므로 [:]를 붙여서 []byte 슬라이스로 변환
-
-	// 	headers["X-Content-SHA256"] = hashString
-	// 	headers["X-Content-Length"] = strconv.Itoa(len(body))
-
-	// 	err = w.WriteTrailers(headers)
-	// 	if err != nil {
-	// 		log.Printf("error writing trailers: %v", err)
-	// 		ErrorHandler(w, req, 500)
-	// 		return
-	// 	}
-	// }
-
+	}()
 }
 
-func videoHandler(w *response.Writer, req *request.Request, headers headers.Headers) {
-	err := w.WriteStatusLine(response.StatusOK)
+// 1초마다 현재 시각을 Server-Sent Events로 보내는 handler
+// 이벤트 id는 1씩 늘어나는 번호이고, 재연결하면 Last-Event-ID 다음 번호부터 이어서 보낸다
+func eventsHandler(w *response.Writer, req *request.Request) {
+	stream, err := sse.NewStream(w, req, sse.WithRetry(3*time.Second))
 	if err != nil {
-		log.Printf("error writing status line: %v", err)
-		ErrorHandler(w, req, 500)
+		log.Printf("error starting event stream: %v", err)
 		return
 	}
+	defer stream.Close()
 
-	data, err := os.ReadFile("assets/vim.mp4")
+	id, err := strconv.Atoi(stream.LastEventID())
 	if err != nil {
-		log.Printf("error reading a file into memory: %v", err)
-		ErrorHandler(w, req, 500)
-		return
+		id = 0
 	}
 
-	headers.SetOverride("Content-Length", strconv.Itoa(len(data)))
-	headers.SetOverride("Connection", "close")
-	headers.SetOverride("Content-Type", "video/mp4")
-
-	err = w.WriteHeaders(headers)
-	if err != nil {
-		log.Printf("error writing headers: %v", err)
-		ErrorHandler(w, req, 500)
-		return
-	}
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
 
-	_, err = w.WriteBody(data)
-	if err != nil {
-		log.Printf("error writing body: %v", err)
-		ErrorHandler(w, req, 500)
-		return
+	for {
+		select {
+		case <-stream.Done():
+			return
+		case now := <-ticker.C:
+			id++
+			err := stream.Send(sse.Event{Event: "tick", ID: strconv.Itoa(id), Data: now.Format(time.RFC3339)})
+			if err != nil {
+				return
+			}
+		}
 	}
 }
 
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+var ErrBodyClosed = errors.New("client: read on closed response body")
+
+// Response.Body 구현
+// body를 끝까지 읽으면 연결을 pool로 돌려보내고, 그 전에 Close하거나 읽기에 실패하면 연결을 닫는다
+type body struct {
+	src    io.Reader
+	closed atomic.Bool
+	// body가 끝났을 때 한번 호출 (reuse가 true면 연결을 다시 써도 된다)
+	release     func(reuse bool)
+	releaseOnce sync.Once
+}
+
+func (b *body) Read(p []byte) (int, error) {
+	if b.closed.Load() {
+		return 0, ErrBodyClosed
+	}
+
+	n, err := b.src.Read(p)
+	if errors.Is(err, io.EOF) {
+		b.finish(true)
+	} else if err != nil {
+		b.finish(false)
+		err = responseError(err)
+	}
+
+	return n, err
+}
+
+// @@@ 다른 고루틴에서 읽는 중에 호출해도 연결을 닫아서 읽기를 멈춘다
+func (b *body) Close() error {
+	b.closed.Store(true)
+	b.finish(false)
+
+	return nil
+}
+
+func (b *body) finish(reuse bool) {
+	b.releaseOnce.Do(func() {
+		b.release(reuse)
+	})
+}
+
+// body가 없는 response의 Body
+type noBody struct{}
+
+func (noBody) Read([]byte) (int, error) { return 0, io.EOF }
+func (noBody) Close() error             { return nil }
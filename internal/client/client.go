@@ -0,0 +1,241 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/paokimsiwoong/httpfromtcp/internal/transfer"
+)
+
+// 기본 설정값들
+const (
+	// host 하나마다 pool에 남겨두는 idle 연결 수
+	DefaultMaxIdleConnsPerHost = 4
+	// idle 연결을 pool에 남겨두는 시간
+	// @@@ 서버가 먼저 닫은 연결을 다시 쓰지 않도록 보통 서버의 keep-alive timeout보다 짧게
+	DefaultIdleTimeout = 30 * time.Second
+	// TCP 연결(과 TLS handshake)을 기다리는 최대 시간
+	DefaultDialTimeout = 10 * time.Second
+)
+
+// reuse한 연결이 request를 보내기 전에 이미 닫혀있었을 때의 에러 (다시 연결해서 보내도 된다)
+var errServerClosedIdle = errors.New("client: server closed idle connection")
+
+// HTTP/1.1 client
+// host(scheme + 주소)마다 keep-alive 연결들을 pool에 두고 다음 request에 다시 쓴다
+// 여러 고루틴에서 동시에 사용해도 된다
+type Client struct {
+	tlsConfig           *tls.Config
+	dialTimeout         time.Duration
+	maxIdleConnsPerHost int
+	idleTimeout         time.Duration
+
+	mu   sync.Mutex
+	idle map[string][]*conn // key: "https://example.com:443"
+}
+
+// New에 넘겨 client 설정을 바꾸는 옵션 함수 타입
+type Option func(*Client)
+
+// https 연결에 쓸 TLS 설정을 정하는 옵션 (ServerName이 비어있으면 URL의 host로 채운다)
+func WithTLSConfig(config *tls.Config) Option {
+	return func(c *Client) {
+		c.tlsConfig = config
+	}
+}
+
+// 연결을 기다리는 최대 시간을 정하는 옵션 (기본값 DefaultDialTimeout)
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.dialTimeout = timeout
+	}
+}
+
+// host마다 남겨두는 idle 연결 수를 정하는 옵션 (0 이하면 연결을 다시 쓰지 않는다)
+func WithMaxIdleConnsPerHost(n int) Option {
+	return func(c *Client) {
+		c.maxIdleConnsPerHost = n
+	}
+}
+
+// idle 연결을 남겨두는 시간을 정하는 옵션 (기본값 DefaultIdleTimeout)
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.idleTimeout = timeout
+	}
+}
+
+// 기본 설정의 Client
+var DefaultClient = New()
+
+// Client 생성 함수
+func New(opts ...Option) *Client {
+	c := &Client{
+		dialTimeout:         DefaultDialTimeout,
+		maxIdleConnsPerHost: DefaultMaxIdleConnsPerHost,
+		idleTimeout:         DefaultIdleTimeout,
+		idle:                map[string][]*conn{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// rawURL로 GET request를 보내는 메소드
+func (c *Client) Get(ctx context.Context, rawURL string) (*Response, error) {
+	req, err := NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Do(ctx, req)
+}
+
+// request를 보내고 response의 status line과 헤더까지 받는 메소드
+// body는 resp.Body로 이어서 읽으며, 끝까지 읽거나 Close를 호출해야 한다
+// redirect는 따라가지 않고, 100 Continue, 103 Early Hints 같은 1xx response는 건너뛴다
+// (101 Switching Protocols는 그대로 반환하고 Body로 연결을 계속 읽을 수 있다)
+// ctx가 끝나면 body를 읽는 중이어도 멈춘다
+func (c *Client) Do(ctx context.Context, req *Request) (*Response, error) {
+	err := req.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	rewind := req.rewinder()
+
+	for {
+		pc, err := c.getConn(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.roundTrip(ctx, pc, req)
+		// @@@ pool에 있던 연결을 서버가 먼저 닫았으면 request가 처리되지 않았으므로 새 연결로 다시 보낸다
+		// @@@ (그래도 서버가 받았을 수 있으므로 멱등 method만, body는 처음부터 다시 읽을 수 있을 때만)
+		if errors.Is(err, errServerClosedIdle) && request.Idempotent(req.Method) && rewind != nil && rewind() == nil {
+			continue
+		}
+		return resp, err
+	}
+}
+
+// pool에 남아있는 idle 연결들을 모두 닫는 메소드
+func (c *Client) CloseIdleConnections() {
+	c.mu.Lock()
+	idle := c.idle
+	c.idle = map[string][]*conn{}
+	c.mu.Unlock()
+
+	for _, conns := range idle {
+		for _, pc := range conns {
+			pc.close()
+		}
+	}
+}
+
+// 연결 하나로 request를 보내고 response 헤더까지 읽는 메소드
+// 실패하면 연결을 닫고, 성공하면 body를 다 읽었을 때 연결을 pool로 돌려보내도록 Body를 만든다
+func (c *Client) roundTrip(ctx context.Context, pc *conn, req *Request) (*Response, error) {
+	// @@@ ctx가 끝나면 이미 지난 deadline으로 대기 중인 읽기/쓰기를 깨운다
+	stop := context.AfterFunc(ctx, func() {
+		_ = pc.SetDeadline(time.Unix(1, 0))
+	})
+
+	fail := func(err error) (*Response, error) {
+		stop()
+		pc.close()
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, err
+	}
+
+	wire := req.wire()
+	var src *requestBody
+	if req.Body != nil {
+		src = &requestBody{r: req.Body}
+		wire.SetBodyReader(src, req.ContentLength)
+	}
+
+	_, err := wire.WriteTo(pc)
+	switch {
+	case err == nil:
+	case src != nil && src.err != nil:
+		return fail(fmt.Errorf("%w: %w", ErrRequestBody, src.err))
+	case errors.Is(err, request.ErrIncorrectContentLength):
+		return fail(fmt.Errorf("%w: body is shorter than ContentLength %d", ErrRequestBody, req.ContentLength))
+	case pc.reused:
+		return fail(fmt.Errorf("%w: %v", errServerClosedIdle, err))
+	default:
+		return fail(err)
+	}
+
+	// @@@ response를 한 바이트도 받지 못하고 연결이 끊기면 서버가 idle 연결을 닫은 것
+	_, err = pc.reader.Peek(1)
+	if err != nil {
+		if pc.reused && !errors.Is(err, os.ErrDeadlineExceeded) {
+			err = fmt.Errorf("%w: %v", errServerClosedIdle, err)
+		}
+		return fail(err)
+	}
+
+	var resp *Response
+	for {
+		resp, err = readResponseHead(pc.reader)
+		if err != nil {
+			return fail(err)
+		}
+		if resp.StatusCode >= 200 || resp.StatusCode == response.StatusSwitchingProtocols {
+			break
+		}
+	}
+
+	if resp.StatusCode == response.StatusSwitchingProtocols {
+		// 이후로는 HTTP가 아니므로 연결을 그대로 넘긴다
+		resp.Body = &body{src: pc.reader, release: func(bool) {
+			stop()
+			pc.close()
+		}}
+		return resp, nil
+	}
+
+	f, length, err := response.BodyFraming(resp.StatusCode, req.Method, resp.Headers)
+	if err != nil {
+		return fail(responseError(err))
+	}
+	resp.ContentLength = length
+	reuse := reusable(req, resp, f)
+
+	release := func(complete bool) {
+		// @@@ stop이 false면 ctx가 끝나서 deadline이 바뀌었으므로 다시 쓰지 않는다
+		if stop() && complete && reuse {
+			c.putIdle(pc)
+			return
+		}
+		pc.close()
+	}
+
+	switch f {
+	case response.FramingNone:
+		resp.Body = noBody{}
+		release(true)
+	case response.FramingLength:
+		resp.Body = &body{src: transfer.NewLengthReader(pc.reader, length), release: release}
+	case response.FramingChunked:
+		resp.Body = &body{src: transfer.NewChunkedReader(pc.reader, resp.Trailers), release: release}
+	case response.FramingClose:
+		resp.Body = &body{src: pc.reader, release: release}
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,396 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 새 연결 수를 세는 httptest server를 띄우는 함수
+func startServer(t *testing.T, handler http.HandlerFunc) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	conns := &atomic.Int32{}
+	s := httptest.NewUnstartedServer(handler)
+	s.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	s.Start()
+	t.Cleanup(s.Close)
+
+	return s, conns
+}
+
+// 연결마다 request 하나를 읽고 raw response를 그대로 쓴 뒤 닫는 server를 띄우는 함수
+func startRawServer(t *testing.T, raw string) (string, *atomic.Int32) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	conns := &atomic.Int32{}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conns.Add(1)
+			go func() {
+				defer conn.Close()
+				_, err := http.ReadRequest(bufio.NewReader(conn))
+				if err != nil {
+					return
+				}
+				_, _ = io.WriteString(conn, raw)
+			}()
+		}
+	}()
+
+	return "http://" + l.Addr().String(), conns
+}
+
+func readAll(t *testing.T, resp *Response) string {
+	t.Helper()
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestDo(t *testing.T) {
+	s, _ := startServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Method", r.Method)
+		w.Header().Set("X-Uri", r.RequestURI)
+		w.Header().Set("X-Token", r.Header.Get("X-Token"))
+		w.Header().Set("X-Host", r.Host)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(body)
+	})
+
+	req, err := NewRequest("POST", s.URL+"/items?id=7", strings.NewReader("hello"))
+	require.NoError(t, err)
+	req.Headers.SetOverride("x-token", "abc")
+
+	resp, err := DefaultClient.Do(context.Background(), req)
+	require.NoError(t, err)
+
+	// Test: status line, 헤더(소문자 key), Content-Length, body
+	assert.Equal(t, "1.1", resp.HttpVersion)
+	assert.Equal(t, response.StatusCode(201), resp.StatusCode)
+	assert.Equal(t, "Created", resp.ReasonPhrase)
+	assert.Equal(t, "POST", resp.Headers.Get("X-Method"))
+	assert.Equal(t, "/items?id=7", resp.Headers.Get("x-uri"))
+	assert.Equal(t, "abc", resp.Headers.Get("x-token"))
+	assert.Equal(t, strings.TrimPrefix(s.URL, "http://"), resp.Headers.Get("x-host"))
+	assert.Equal(t, int64(5), resp.ContentLength)
+	assert.Equal(t, "hello", readAll(t, resp))
+}
+
+func TestRequestBody(t *testing.T) {
+	s, _ := startServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Length", strconv.FormatInt(r.ContentLength, 10))
+		w.Header().Set("X-Transfer-Encoding", strings.Join(r.TransferEncoding, ","))
+		_, _ = w.Write(body)
+	})
+
+	// Test: 길이를 모르는 body는 읽는 대로 chunked로 보낸다
+	pr, pw := io.Pipe()
+	go func() {
+		_, _ = io.WriteString(pw, "hello, ")
+		_, _ = io.WriteString(pw, "world")
+		pw.Close()
+	}()
+	req, err := NewRequest("PUT", s.URL+"/upload", pr)
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), req.ContentLength)
+
+	resp, err := DefaultClient.Do(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "chunked", resp.Headers.Get("x-transfer-encoding"))
+	assert.Equal(t, "hello, world", readAll(t, resp))
+
+	// Test: 길이를 알면 Content-Length로
+	req, err = NewRequest("PUT", s.URL+"/upload", bytes.NewReader([]byte("hello")))
+	require.NoError(t, err)
+	resp, err = DefaultClient.Do(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "5", resp.Headers.Get("x-length"))
+	assert.Equal(t, "hello", readAll(t, resp))
+
+	// Test: body를 읽다가 실패하거나 ContentLength보다 짧으면 ErrRequestBody
+	readErr := errors.New("upload aborted")
+	req, err = NewRequest("PUT", s.URL+"/upload", io.MultiReader(strings.NewReader("part"), iotest.ErrReader(readErr)))
+	require.NoError(t, err)
+	_, err = DefaultClient.Do(context.Background(), req)
+	require.ErrorIs(t, err, ErrRequestBody)
+	require.ErrorIs(t, err, readErr)
+
+	req, err = NewRequest("PUT", s.URL+"/upload", strings.NewReader("short"))
+	require.NoError(t, err)
+	req.ContentLength = 10
+	_, err = DefaultClient.Do(context.Background(), req)
+	require.ErrorIs(t, err, ErrRequestBody)
+}
+
+func TestKeepAlive(t *testing.T) {
+	s, conns := startServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunked" {
+			_, _ = io.WriteString(w, "part1")
+			w.(http.Flusher).Flush()
+			_, _ = io.WriteString(w, "part2")
+			return
+		}
+		_, _ = io.WriteString(w, "ok")
+	})
+	c := New()
+
+	// Test: body를 끝까지 읽은 연결은 다시 쓴다
+	for _, path := range []string{"/", "/chunked", "/", "/chunked"} {
+		resp, err := c.Get(context.Background(), s.URL+path)
+		require.NoError(t, err)
+		readAll(t, resp)
+	}
+	assert.Equal(t, int32(1), conns.Load())
+
+	// Test: body를 다 읽기 전에 Close하면 연결을 닫고 다음 request는 새 연결로
+	resp, err := c.Get(context.Background(), s.URL+"/chunked")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	_, err = resp.Body.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, ErrBodyClosed)
+
+	resp, err = c.Get(context.Background(), s.URL+"/")
+	require.NoError(t, err)
+	assert.Equal(t, "ok", readAll(t, resp))
+	assert.Equal(t, int32(2), conns.Load())
+
+	// Test: Connection: close request는 연결을 다시 쓰지 않는다
+	req, err := NewRequest("GET", s.URL+"/", nil)
+	require.NoError(t, err)
+	req.Headers.SetOverride("connection", "close")
+	resp, err = c.Do(context.Background(), req)
+	require.NoError(t, err)
+	readAll(t, resp)
+
+	resp, err = c.Get(context.Background(), s.URL+"/")
+	require.NoError(t, err)
+	readAll(t, resp)
+	assert.Equal(t, int32(3), conns.Load())
+}
+
+func TestChunkedTrailers(t *testing.T) {
+	s, _ := startServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "X-Checksum")
+		_, _ = io.WriteString(w, "hello ")
+		w.(http.Flusher).Flush()
+		_, _ = io.WriteString(w, "world")
+		w.Header().Set("X-Checksum", "abc123")
+	})
+
+	resp, err := DefaultClient.Get(context.Background(), s.URL)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(-1), resp.ContentLength)
+	assert.Equal(t, "chunked", resp.Headers.Get("transfer-encoding"))
+	assert.Equal(t, "hello world", readAll(t, resp))
+	assert.Equal(t, "abc123", resp.Trailers.Get("x-checksum"))
+}
+
+func TestRawFraming(t *testing.T) {
+	tests := []struct {
+		name   string
+		raw    string
+		method string
+		body   string
+		length int64
+		conns  int32 // 같은 request를 두 번 보냈을 때 맺은 연결 수
+	}{
+		{
+			name:   "close delimited",
+			raw:    "HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\n\r\nuntil close",
+			method: "GET", body: "until close", length: -1, conns: 2,
+		},
+		{
+			name:   "chunk extensions and hex sizes",
+			raw:    "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\nA;name=value\r\n0123456789\r\n1\r\n!\r\n0\r\n\r\n",
+			method: "GET", body: "0123456789!", length: -1, conns: 2,
+		},
+		{
+			name:   "interim responses are skipped",
+			raw:    "HTTP/1.1 100 Continue\r\n\r\nHTTP/1.1 103 Early Hints\r\nLink: </a.css>; rel=preload\r\n\r\nHTTP/1.1 200 OK\r\nContent-Length: 4\r\n\r\ndone",
+			method: "GET", body: "done", length: 4, conns: 2,
+		},
+		{
+			name:   "HEAD keeps content length without body",
+			raw:    "HTTP/1.1 200 OK\r\nContent-Length: 1234\r\n\r\n",
+			method: "HEAD", body: "", length: 1234, conns: 2,
+		},
+		{
+			name:   "204 has no body",
+			raw:    "HTTP/1.1 204 No Content\r\n\r\n",
+			method: "GET", body: "", length: -1, conns: 2,
+		},
+		{
+			name:   "HTTP/1.0 and empty reason phrase",
+			raw:    "HTTP/1.0 200\r\nContent-Length: 2\r\n\r\nok",
+			method: "GET", body: "ok", length: 2, conns: 2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			url, conns := startRawServer(t, tc.raw)
+			c := New()
+
+			for range 2 {
+				req, err := NewRequest(tc.method, url, nil)
+				require.NoError(t, err)
+				resp, err := c.Do(context.Background(), req)
+				require.NoError(t, err)
+
+				assert.Equal(t, tc.length, resp.ContentLength)
+				assert.Equal(t, tc.body, readAll(t, resp))
+			}
+			// @@@ raw server는 response 뒤에 연결을 닫으므로 keep-alive response여도 새 연결로 다시 보내야 한다
+			assert.Equal(t, tc.conns, conns.Load())
+		})
+	}
+}
+
+func TestMalformedResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		headErr error // Do가 반환하는 에러 (nil이면 body를 읽을 때 bodyErr)
+		bodyErr error
+	}{
+		{"bad version", "HTTP/2 200 OK\r\n\r\n", ErrMalformedResponse, nil},
+		{"bad status code", "HTTP/1.1 20x OK\r\n\r\n", ErrMalformedResponse, nil},
+		{"bare LF", "HTTP/1.1 200 OK\nContent-Length: 0\n\n", ErrMalformedResponse, nil},
+		{"invalid header name", "HTTP/1.1 200 OK\r\nBad Name: x\r\n\r\n", ErrMalformedResponse, nil},
+		{"conflicting content length", "HTTP/1.1 200 OK\r\nContent-Length: 2\r\nContent-Length: 3\r\n\r\nok", ErrMalformedResponse, nil},
+		{"truncated headers", "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n", io.ErrUnexpectedEOF, nil},
+		{"short body", "HTTP/1.1 200 OK\r\nContent-Length: 10\r\n\r\nshort", nil, io.ErrUnexpectedEOF},
+		{"bad chunk size", "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n-1\r\n", nil, ErrMalformedResponse},
+		{"chunk longer than size", "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n2\r\nabc\r\n0\r\n\r\n", nil, ErrMalformedResponse},
+		{"missing last chunk", "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n2\r\nab\r\n", nil, io.ErrUnexpectedEOF},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			url, _ := startRawServer(t, tc.raw)
+
+			resp, err := New().Get(context.Background(), url)
+			if tc.headErr != nil {
+				assert.ErrorIs(t, err, tc.headErr)
+				return
+			}
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			_, err = io.ReadAll(resp.Body)
+			assert.ErrorIs(t, err, tc.bodyErr)
+		})
+	}
+}
+
+func TestContextCancel(t *testing.T) {
+	release := make(chan struct{})
+	s, _ := startServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/body" {
+			_, _ = io.WriteString(w, "first")
+			w.(http.Flusher).Flush()
+		}
+		<-release
+	})
+	defer close(release)
+
+	// Test: response 헤더가 오기 전에 시간이 다 되면 ctx 에러
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := New().Get(ctx, s.URL)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Test: body를 읽는 중에 ctx가 끝나도 읽기가 멈춘다
+	ctx, cancel = context.WithCancel(context.Background())
+	resp, err := New().Get(ctx, s.URL+"/body")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(resp.Body, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "first", string(buf))
+
+	time.AfterFunc(20*time.Millisecond, cancel)
+	_, err = io.ReadAll(resp.Body)
+	assert.Error(t, err)
+}
+
+func TestTLS(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "secure")
+	}))
+	defer s.Close()
+
+	// Test: 인증서를 믿지 않으면 handshake 실패
+	_, err := New().Get(context.Background(), s.URL)
+	assert.Error(t, err)
+
+	config := s.Client().Transport.(*http.Transport).TLSClientConfig
+	resp, err := New(WithTLSConfig(config)).Get(context.Background(), s.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "secure", readAll(t, resp))
+}
+
+func TestInvalidRequest(t *testing.T) {
+	_, err := DefaultClient.Get(context.Background(), "ftp://example.com/")
+	assert.ErrorIs(t, err, ErrUnsupportedScheme)
+
+	_, err = DefaultClient.Get(context.Background(), "http:///path")
+	assert.ErrorIs(t, err, ErrMissingHost)
+
+	req, err := NewRequest("get", "http://example.com/", nil)
+	require.NoError(t, err)
+	_, err = DefaultClient.Do(context.Background(), req)
+	assert.ErrorIs(t, err, ErrInvalidMethod)
+
+	// Test: 헤더 값에 CRLF가 있으면 연결하기 전에 거절
+	req, err = NewRequest("GET", "http://example.com/", nil)
+	require.NoError(t, err)
+	req.Headers.SetOverride("x-note", "a\r\nX-Injected: 1")
+	_, err = DefaultClient.Do(context.Background(), req)
+	assert.ErrorIs(t, err, request.ErrInvalidHeaderValue)
+
+	// Test: 연결할 수 없으면 dial 에러
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+	_, err = DefaultClient.Get(context.Background(), "http://"+addr)
+	var opErr *net.OpError
+	assert.True(t, errors.As(err, &opErr))
+}
@@ -0,0 +1,160 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"os"
+	"time"
+)
+
+// 서버와의 연결 하나
+type conn struct {
+	net.Conn
+	reader *bufio.Reader
+	key    string
+
+	reused    bool        // pool에서 꺼낸 연결인지
+	idleTimer *time.Timer // pool에 있는 동안 idleTimeout이 지나면 연결을 빼서 닫는다
+}
+
+func (pc *conn) close() {
+	_ = pc.Conn.Close()
+}
+
+// pool에 있던 연결을 서버가 아직 닫지 않았는지 확인하는 메소드
+// @@@ 이미 지난 deadline으로 읽어보고 timeout이면 읽을 것도 없고 닫히지도 않은 연결
+// @@@ (EOF나 request를 보내기도 전에 온 데이터가 있으면 다시 쓸 수 없다)
+func (pc *conn) alive() bool {
+	_ = pc.SetReadDeadline(time.Unix(1, 0))
+	_, err := pc.reader.Peek(1)
+	_ = pc.SetReadDeadline(time.Time{})
+
+	return errors.Is(err, os.ErrDeadlineExceeded)
+}
+
+// request를 보낼 연결을 pool에서 꺼내거나 새로 만드는 메소드
+func (c *Client) getConn(ctx context.Context, req *Request) (*conn, error) {
+	key, addr := connKey(req)
+
+	for {
+		pc := c.takeIdle(key)
+		if pc == nil {
+			break
+		}
+		if pc.alive() {
+			return pc, nil
+		}
+		pc.close()
+	}
+
+	return c.dial(ctx, req, key, addr)
+}
+
+// 새 연결을 맺는 메소드 (https면 TLS handshake까지)
+func (c *Client) dial(ctx context.Context, req *Request, key, addr string) (*conn, error) {
+	if c.dialTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.dialTimeout)
+		defer cancel()
+	}
+
+	dialer := &net.Dialer{}
+	nc, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.URL.Scheme == "https" {
+		config := &tls.Config{}
+		if c.tlsConfig != nil {
+			config = c.tlsConfig.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName = req.URL.Hostname()
+		}
+
+		tc := tls.Client(nc, config)
+		err = tc.HandshakeContext(ctx)
+		if err != nil {
+			_ = nc.Close()
+			return nil, err
+		}
+		nc = tc
+	}
+
+	return &conn{
+		Conn:   nc,
+		reader: bufio.NewReader(nc),
+		key:    key,
+	}, nil
+}
+
+// request URL로 pool key와 연결할 주소를 만드는 함수 (포트가 없으면 scheme의 기본 포트)
+func connKey(req *Request) (string, string) {
+	port := req.URL.Port()
+	if port == "" {
+		port = "80"
+		if req.URL.Scheme == "https" {
+			port = "443"
+		}
+	}
+	addr := net.JoinHostPort(req.URL.Hostname(), port)
+
+	return req.URL.Scheme + "://" + addr, addr
+}
+
+// key의 idle 연결을 하나 꺼내는 메소드 (없으면 nil)
+// @@@ 가장 최근에 넣은 연결부터 꺼내서 오래된 연결은 idleTimeout으로 정리되도록 한다
+func (c *Client) takeIdle(key string) *conn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	conns := c.idle[key]
+	if len(conns) == 0 {
+		return nil
+	}
+
+	pc := conns[len(conns)-1]
+	c.idle[key] = conns[:len(conns)-1]
+	pc.idleTimer.Stop()
+	pc.reused = true
+
+	return pc
+}
+
+// body를 다 읽은 연결을 pool에 넣는 메소드 (host마다 최대 maxIdleConnsPerHost개)
+func (c *Client) putIdle(pc *conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.idle[pc.key]) >= c.maxIdleConnsPerHost {
+		pc.close()
+		return
+	}
+
+	c.idle[pc.key] = append(c.idle[pc.key], pc)
+	pc.idleTimer = time.AfterFunc(c.idleTimeout, func() {
+		if c.removeIdle(pc) {
+			pc.close()
+		}
+	})
+}
+
+// pool에서 pc를 빼는 메소드 (이미 꺼내간 연결이면 false)
+func (c *Client) removeIdle(pc *conn) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	conns := c.idle[pc.key]
+	for i, idle := range conns {
+		if idle == pc {
+			c.idle[pc.key] = append(conns[:i:i], conns[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,149 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+)
+
+var ErrUnsupportedScheme = errors.New("client: URL scheme must be http or https")
+var ErrMissingHost = errors.New("client: URL has no host")
+var ErrInvalidMethod = errors.New("client: request method must be capital alphabetic characters")
+
+// Request.Body를 읽다가 실패했거나 Body가 ContentLength보다 짧을 때의 에러
+var ErrRequestBody = errors.New("client: error reading request body")
+
+// 서버로 보낼 request 하나
+type Request struct {
+	Method string
+	// 연결할 주소와 request target (Scheme은 http 또는 https)
+	URL *url.URL
+	// request.Request와 같이 key는 소문자로 저장한다 (Get도 소문자로 찾는다)
+	// host 헤더가 없으면 URL.Host를 보내고, content-length와 transfer-encoding은 ContentLength로 정한다
+	Headers headers.Headers
+	// 보낼 body (nil이면 body 없음)
+	// @@@ 메모리에 모으지 않고 읽는 대로 보낸다 (proxy가 받은 body를 upstream으로 바로 넘길 수 있도록)
+	// @@@ 서버가 idle 연결을 먼저 닫아서 다시 보내야 할 때는 io.Seeker면 처음 위치로 되돌려서 보내고, 아니면 다시 보내지 않는다
+	Body io.Reader
+	// Body 길이 (-1이면 모르는 길이로 chunked로 보낸다, Body가 nil이면 무시)
+	ContentLength int64
+}
+
+// method, URL, body로 Request를 만드는 함수
+// body가 *bytes.Reader, *bytes.Buffer, *strings.Reader면 남은 길이를 ContentLength로, 다른 reader면 -1로 정한다
+func NewRequest(method, rawURL string, body io.Reader) (*Request, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &Request{
+		Method:  method,
+		URL:     u,
+		Headers: headers.NewHeaders(),
+		Body:    body,
+	}
+
+	switch b := body.(type) {
+	case nil:
+	case *bytes.Reader:
+		req.ContentLength = int64(b.Len())
+	case *bytes.Buffer:
+		req.ContentLength = int64(b.Len())
+	case *strings.Reader:
+		req.ContentLength = int64(b.Len())
+	default:
+		req.ContentLength = -1
+	}
+
+	return req, nil
+}
+
+// 보내기 전에 request가 쓸 수 있는 값인지 확인하는 메소드
+func (r *Request) validate() error {
+	if r.URL == nil || (r.URL.Scheme != "http" && r.URL.Scheme != "https") {
+		return ErrUnsupportedScheme
+	}
+	if r.URL.Host == "" {
+		return ErrMissingHost
+	}
+	if r.Method == "" || strings.IndexFunc(r.Method, func(c rune) bool { return c < 'A' || c > 'Z' }) != -1 {
+		return ErrInvalidMethod
+	}
+	// @@@ 헤더 값의 CRLF처럼 쓸 수 없는 값은 연결하기 전에 거절한다 (보내다가 실패하면 연결 문제로 보고 다시 보내게 된다)
+	return r.wire().Validate()
+}
+
+// 보낼 request를 request.Request로 바꾸는 메소드 (request.Request.WriteTo로 서버와 같은 형식으로 쓴다)
+// host 헤더가 없으면 URL.Host를 넣고, body가 있어야 하는 method는 비어있어도 길이 0을 알려준다
+// @@@ Headers는 복사해서 쓰므로 호출한 쪽의 Request는 바뀌지 않는다
+func (r *Request) wire() *request.Request {
+	h := headers.NewHeaders()
+	for key, value := range r.Headers {
+		key = strings.ToLower(key)
+		// @@@ body를 보내는 방식은 ContentLength로 정하므로 transfer-encoding은 그대로 보내지 않는다
+		if key == "transfer-encoding" {
+			continue
+		}
+		h.SetOverride(key, value)
+	}
+	if h.Get("host") == "" {
+		h.SetOverride("host", r.URL.Host)
+	}
+	delete(h, "content-length")
+	switch r.Method {
+	case "POST", "PUT", "PATCH":
+		h.SetOverride("content-length", "0")
+	}
+
+	return &request.Request{
+		RequestLine: request.RequestLine{
+			Method:        r.Method,
+			RequestTarget: r.URL.RequestURI(),
+			HttpVersion:   "1.1",
+		},
+		Headers: h,
+	}
+}
+
+// body를 보내기 전 위치를 기억해두고 다시 보낼 때 그 위치로 되돌리는 함수를 반환하는 메소드
+// 되돌릴 수 없는 body면 nil 반환 (body가 없으면 아무것도 하지 않는 함수)
+func (r *Request) rewinder() func() error {
+	if r.Body == nil {
+		return func() error { return nil }
+	}
+
+	seeker, ok := r.Body.(io.Seeker)
+	if !ok {
+		return nil
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil
+	}
+
+	return func() error {
+		_, err := seeker.Seek(start, io.SeekStart)
+		return err
+	}
+}
+
+// 보내는 동안 Body에서 난 읽기 에러를 기억하는 reader
+// @@@ 쓰기 에러(연결 문제)와 구분해서 idle 연결 문제로 보고 다시 보내지 않도록 한다
+type requestBody struct {
+	r   io.Reader
+	err error
+}
+
+func (b *requestBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err != nil && err != io.EOF {
+		b.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,107 @@
+package client
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/paokimsiwoong/httpfromtcp/internal/transfer"
+)
+
+var ErrMalformedResponse = errors.New("client: malformed response")
+var ErrHeadersTooLarge = errors.New("client: response headers exceed the size limit")
+
+// 서버에서 받은 response 하나
+type Response struct {
+	// HTTP-version의 숫자 부분 ("1.1" 또는 "1.0")
+	HttpVersion  string
+	StatusCode   response.StatusCode
+	ReasonPhrase string
+	// key는 소문자로 저장된다 (같은 이름이 여러 번 오면 ", "로 합친다)
+	Headers headers.Headers
+	// body 길이 (Content-Length가 없는 chunked, 연결 종료로 끝나는 body는 -1)
+	// @@@ HEAD request의 response면 body는 없지만 Content-Length 값을 그대로 담는다
+	ContentLength int64
+	// body를 읽는 reader (body가 없으면 바로 io.EOF)
+	// 끝까지 읽거나 Close를 호출해야 연결을 다시 쓰거나 닫을 수 있다
+	Body io.ReadCloser
+	// chunked body의 trailer 필드들 (Body를 끝까지 읽은 뒤에 채워진다)
+	Trailers headers.Headers
+}
+
+// reader에서 status line과 헤더를 읽어서 Response를 만드는 함수 (Body는 채우지 않는다)
+// @@@ 줄을 읽고 파싱하는 부분은 response.ResponseFromReader와 같은 headers.LineReader, response.ParseStatusLine을 쓴다
+func readResponseHead(r *bufio.Reader) (*Response, error) {
+	var lines headers.LineReader
+	lines.Reset(r, headers.MaxHeaderBytes)
+
+	line, err := lines.ReadLine()
+	if err != nil {
+		return nil, headError(err)
+	}
+	statusLine, err := response.ParseStatusLine(line[:len(line)-len("\r\n")])
+	if err != nil {
+		return nil, responseError(err)
+	}
+
+	resp := &Response{
+		HttpVersion:   statusLine.HttpVersion,
+		StatusCode:    statusLine.StatusCode,
+		ReasonPhrase:  statusLine.ReasonPhrase,
+		Headers:       headers.NewHeaders(),
+		Trailers:      headers.NewHeaders(),
+		ContentLength: -1,
+	}
+
+	err = lines.ReadHeaders(resp.Headers)
+	if err != nil {
+		return nil, headError(err)
+	}
+
+	return resp, nil
+}
+
+// status line과 헤더를 읽다가 생긴 에러를 Do가 반환할 에러로 바꾸는 함수
+// @@@ 헤더가 끝나기 전에 연결이 끊긴 것이므로 io.EOF도 io.ErrUnexpectedEOF
+func headError(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return responseError(err)
+}
+
+// 공유하는 파서들의 에러를 client의 에러로 바꾸는 함수 (형식이 틀린 경우는 모두 ErrMalformedResponse)
+func responseError(err error) error {
+	switch {
+	case errors.Is(err, headers.ErrTooLarge):
+		return ErrHeadersTooLarge
+	case errors.Is(err, headers.ErrMalformed),
+		errors.Is(err, transfer.ErrInvalidChunk),
+		errors.Is(err, transfer.ErrInvalidContentLength),
+		errors.Is(err, response.ErrInvalidStatusLine),
+		errors.Is(err, response.ErrInvalidResponseVersion):
+		return fmt.Errorf("%w: %w", ErrMalformedResponse, err)
+	default:
+		return err
+	}
+}
+
+// 같은 연결로 다음 request를 보내도 되는 response인지 확인하는 함수
+func reusable(req *Request, resp *Response, f response.Framing) bool {
+	if f == response.FramingClose || headers.HasToken(req.Headers.Get("connection"), "close") {
+		return false
+	}
+	// @@@ Transfer-Encoding과 Content-Length가 같이 오면 중간에서 요청 경계가 어긋났을 수 있으므로 닫는다 (RFC 9112 6.1)
+	if resp.Headers.Get("transfer-encoding") != "" && resp.Headers.Get("content-length") != "" {
+		return false
+	}
+
+	connection := resp.Headers.Get("connection")
+	if resp.HttpVersion == "1.0" {
+		return headers.HasToken(connection, "keep-alive")
+	}
+	return !headers.HasToken(connection, "close")
+}
@@ -0,0 +1,248 @@
+package conditional
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+)
+
+// 조건부 request 평가 결과
+type Result int
+
+const (
+	// 조건을 만족하므로 평소대로 response를 보낸다
+	Proceed Result = iota
+	// client가 가진 사본이 최신이므로 304 Not Modified
+	NotModified
+	// 조건을 만족하지 않으므로 412 Precondition Failed
+	PreconditionFailed
+)
+
+// 현재 representation을 식별하는 값들 (ETag, Last-Modified)
+// 비어있는(zero) 값은 해당 validator가 없다는 뜻
+type Validators struct {
+	ETag         string    // 따옴표 포함, weak이면 W/ 포함 (ex: `"abc"`, `W/"abc"`)
+	LastModified time.Time // 초 단위로 비교
+	// 대상 representation이 아직 없으면 true (ex: PUT으로 새로 만들 자원)
+	// @@@ ETag가 없어도 representation이 있으면 If-Match: *는 일치하므로 ETag로 있는지를 판단하지 않는다
+	Missing bool
+}
+
+// 내용으로 strong ETag를 만드는 함수 (sha256 앞 16바이트)
+// 같은 body면 항상 같은 값이라 바이트 단위로 같음을 보장
+func StrongETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// 주어진 값으로 weak ETag를 만드는 함수
+// 의미상 같은 내용이면 되고 바이트 단위로 같을 필요는 없을 때 사용 (ex: 버전 번호)
+func WeakETag(opaque string) string {
+	return `W/"` + strings.ReplaceAll(opaque, `"`, "") + `"`
+}
+
+// 파일의 수정 시각과 크기로 ETag를 만드는 함수 ("<수정시각 16진수>-<크기 16진수>")
+// @@@ 내용을 다 읽어서 해시하지 않아도 되므로 큰 파일에도 쓸 수 있다
+func FileETag(info fs.FileInfo) string {
+	return fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size())
+}
+
+// 헤더에 ETag, Last-Modified를 설정하는 메소드 (없는 값은 생략)
+func (v Validators) SetHeaders(h headers.Headers) {
+	if v.ETag != "" {
+		h.SetOverride("ETag", v.ETag)
+	}
+	if !v.LastModified.IsZero() {
+		h.SetOverride("Last-Modified", v.LastModified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// request의 조건부 헤더들을 RFC 9110 13.2.2의 순서대로 평가하는 함수
+// 1. If-Match  2. (If-Match가 없으면) If-Unmodified-Since
+// 3. If-None-Match  4. (If-None-Match가 없고 GET/HEAD면) If-Modified-Since
+// @@@ If-Range는 Range 처리 쪽에서 따로 평가
+func Check(req *request.Request, v Validators) Result {
+	method := req.RequestLine.Method
+	safe := method == "GET" || method == "HEAD"
+
+	if ifMatch := req.Headers.Get("If-Match"); ifMatch != "" {
+		if !matchETag(ifMatch, v, false) {
+			return PreconditionFailed
+		}
+	} else if since, ok := parseDate(req.Headers.Get("If-Unmodified-Since")); ok && !v.LastModified.IsZero() {
+		if v.LastModified.Truncate(time.Second).After(since) {
+			return PreconditionFailed
+		}
+	}
+
+	if ifNoneMatch := req.Headers.Get("If-None-Match"); ifNoneMatch != "" {
+		if matchETag(ifNoneMatch, v, true) {
+			if safe {
+				return NotModified
+			}
+			return PreconditionFailed
+		}
+	} else if since, ok := parseDate(req.Headers.Get("If-Modified-Since")); ok && safe && !v.LastModified.IsZero() {
+		if !v.LastModified.Truncate(time.Second).After(since) {
+			return NotModified
+		}
+	}
+
+	return Proceed
+}
+
+// If-Match/If-None-Match 값의 ETag 목록 중 현재 ETag와 같은 것이 있는지 확인하는 함수
+// "*"는 representation이 있으면(v.Missing이 false면) ETag가 없어도 항상 일치 (RFC 9110 13.1.1)
+// weak가 true면 W/ 차이를 무시하고 비교(If-None-Match), false면 둘 다 strong일 때만 일치(If-Match)
+func matchETag(list string, v Validators, weak bool) bool {
+	if strings.TrimSpace(list) == "*" {
+		return !v.Missing
+	}
+
+	current := v.ETag
+	if v.Missing || current == "" {
+		return false
+	}
+
+	for _, tag := range splitETags(list) {
+		if weak {
+			if strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(current, "W/") {
+				return true
+			}
+			continue
+		}
+
+		if !strings.HasPrefix(tag, "W/") && !strings.HasPrefix(current, "W/") && tag == current {
+			return true
+		}
+	}
+
+	return false
+}
+
+// 쉼표로 구분된 ETag 목록을 나누는 함수
+// @@@ ETag 따옴표 안에 쉼표가 들어갈 수도 있어서 strings.Split 대신 따옴표를 따라가며 나눈다
+func splitETags(list string) []string {
+	tags := []string{}
+
+	for {
+		list = strings.TrimLeft(list, " \t,")
+		if list == "" {
+			return tags
+		}
+
+		prefix := ""
+		if strings.HasPrefix(list, "W/") {
+			prefix = "W/"
+			list = list[2:]
+		}
+
+		if !strings.HasPrefix(list, `"`) {
+			// 잘못된 형식이면 나머지는 버린다
+			return tags
+		}
+
+		end := strings.Index(list[1:], `"`)
+		if end == -1 {
+			return tags
+		}
+
+		tags = append(tags, prefix+list[:end+2])
+		list = list[end+2:]
+	}
+}
+
+// HTTP 날짜 헤더 값을 파싱하는 함수 (값이 없거나 잘못되면 false)
+// @@@ 잘못된 날짜의 조건부 헤더는 RFC 9110에 따라 무시
+func parseDate(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return t, true
+}
+
+// 304 Not Modified response를 쓰는 함수
+// body 없이 validator와 캐시 관련 헤더만 보낸다
+func WriteNotModified(w *response.Writer, v Validators, h headers.Headers) {
+	// @@@ 304에는 body가 없으므로 body 관련 헤더는 빼기
+	for key := range h {
+		switch strings.ToLower(key) {
+		case "content-length", "content-type", "transfer-encoding", "content-encoding", "content-range", "trailer":
+			delete(h, key)
+		}
+	}
+	v.SetHeaders(h)
+
+	err := w.WriteStatusLine(response.StatusNotModified)
+	if err != nil {
+		log.Printf("error writing status line: %v", err)
+		return
+	}
+
+	err = w.WriteHeaders(h)
+	if err != nil {
+		log.Printf("error writing headers: %v", err)
+		return
+	}
+}
+
+// 412 Precondition Failed response를 쓰는 함수
+func WritePreconditionFailed(w *response.Writer) {
+	body := "412 Precondition Failed\n"
+
+	err := w.WriteStatusLine(response.StatusPreconditionFailed)
+	if err != nil {
+		log.Printf("error writing status line: %v", err)
+		return
+	}
+
+	h := headers.NewHeaders()
+	h.SetOverride("Content-Length", fmt.Sprint(len(body)))
+	h.SetOverride("Content-Type", "text/plain; charset=utf-8")
+
+	err = w.WriteHeaders(h)
+	if err != nil {
+		log.Printf("error writing headers: %v", err)
+		return
+	}
+
+	_, err = w.WriteBody([]byte(body))
+	if err != nil {
+		log.Printf("error writing body: %v", err)
+		return
+	}
+}
+
+// Check 결과에 따라 304/412 response를 쓰는 함수
+// response를 썼으면 true를 반환하며, 호출한 쪽은 더 이상 쓰지 않고 종료해야 한다
+// h는 304에 함께 보낼 헤더 (Cache-Control 등, nil 가능)
+func Handle(w *response.Writer, req *request.Request, v Validators, h headers.Headers) bool {
+	switch Check(req, v) {
+	case NotModified:
+		if h == nil {
+			h = headers.NewHeaders()
+		}
+		WriteNotModified(w, v, h)
+		return true
+	case PreconditionFailed:
+		WritePreconditionFailed(w)
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,123 @@
+package conditional
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var lastModified = time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)
+
+// 주어진 메소드와 헤더들로 request를 만드는 함수
+func newRequest(t *testing.T, method string, extra ...string) *request.Request {
+	t.Helper()
+
+	raw := method + " / HTTP/1.1\r\nHost: localhost\r\n"
+	for _, line := range extra {
+		raw += line + "\r\n"
+	}
+	raw += "\r\n"
+
+	req, err := request.RequestFromReader(strings.NewReader(raw))
+	require.NoError(t, err)
+
+	return req
+}
+
+func httpDate(t time.Time) string {
+	return t.Format(http.TimeFormat)
+}
+
+func TestCheck(t *testing.T) {
+	v := Validators{ETag: `"v1"`, LastModified: lastModified}
+	weak := Validators{ETag: `W/"v1"`, LastModified: lastModified}
+
+	tests := []struct {
+		name     string
+		v        Validators
+		method   string
+		headers  []string
+		expected Result
+	}{
+		{"no conditions", v, "GET", nil, Proceed},
+
+		{"If-None-Match hit", v, "GET", []string{`If-None-Match: "v0", "v1"`}, NotModified},
+		{"If-None-Match weak comparison", weak, "GET", []string{`If-None-Match: "v1"`}, NotModified},
+		{"If-None-Match miss", v, "GET", []string{`If-None-Match: "v2"`}, Proceed},
+		{"If-None-Match star", v, "HEAD", []string{`If-None-Match: *`}, NotModified},
+		{"If-None-Match on POST", v, "POST", []string{`If-None-Match: "v1"`}, PreconditionFailed},
+		{"If-None-Match comma in tag", Validators{ETag: `"a,b"`}, "GET", []string{`If-None-Match: "x", "a,b"`}, NotModified},
+
+		{"If-Modified-Since not modified", v, "GET", []string{"If-Modified-Since: " + httpDate(lastModified)}, NotModified},
+		{"If-Modified-Since modified", v, "GET", []string{"If-Modified-Since: " + httpDate(lastModified.Add(-time.Second))}, Proceed},
+		{"If-Modified-Since ignored on POST", v, "POST", []string{"If-Modified-Since: " + httpDate(lastModified)}, Proceed},
+		{"If-Modified-Since invalid date", v, "GET", []string{"If-Modified-Since: yesterday"}, Proceed},
+		{"If-None-Match takes precedence over If-Modified-Since", v, "GET",
+			[]string{`If-None-Match: "v2"`, "If-Modified-Since: " + httpDate(lastModified)}, Proceed},
+
+		{"If-Match hit", v, "PUT", []string{`If-Match: "v1"`}, Proceed},
+		{"If-Match miss", v, "PUT", []string{`If-Match: "v2"`}, PreconditionFailed},
+		{"If-Match weak never matches", weak, "PUT", []string{`If-Match: W/"v1"`}, PreconditionFailed},
+		{"If-Match star", v, "DELETE", []string{"If-Match: *"}, Proceed},
+		{"If-Match star without representation", Validators{Missing: true}, "PUT", []string{"If-Match: *"}, PreconditionFailed},
+		{"If-Match star without ETag", Validators{LastModified: lastModified}, "PUT", []string{"If-Match: *"}, Proceed},
+		{"If-None-Match star without ETag", Validators{LastModified: lastModified}, "PUT", []string{"If-None-Match: *"}, PreconditionFailed},
+		{"If-None-Match star without representation", Validators{Missing: true}, "PUT", []string{"If-None-Match: *"}, Proceed},
+
+		{"If-Unmodified-Since ok", v, "PUT", []string{"If-Unmodified-Since: " + httpDate(lastModified)}, Proceed},
+		{"If-Unmodified-Since failed", v, "PUT", []string{"If-Unmodified-Since: " + httpDate(lastModified.Add(-time.Hour))}, PreconditionFailed},
+		{"If-Match takes precedence over If-Unmodified-Since", v, "PUT",
+			[]string{`If-Match: "v1"`, "If-Unmodified-Since: " + httpDate(lastModified.Add(-time.Hour))}, Proceed},
+
+		{"If-Match evaluated before If-None-Match", v, "GET", []string{`If-Match: "v2"`, `If-None-Match: "v1"`}, PreconditionFailed},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, Check(newRequest(t, tc.method, tc.headers...), tc.v), tc.name)
+	}
+}
+
+func TestETags(t *testing.T) {
+	assert.Equal(t, StrongETag([]byte("hello")), StrongETag([]byte("hello")))
+	assert.NotEqual(t, StrongETag([]byte("hello")), StrongETag([]byte("hello!")))
+	assert.True(t, strings.HasPrefix(StrongETag(nil), `"`))
+	assert.Equal(t, `W/"v2"`, WeakETag(`v"2`))
+	assert.Equal(t, []string{`"a"`, `W/"b"`, `"c,d"`}, splitETags(` "a",W/"b" , "c,d"`))
+}
+
+func TestHandle(t *testing.T) {
+	v := Validators{ETag: `"v1"`, LastModified: lastModified}
+
+	// Test: 304에는 body와 body 관련 헤더가 없고 validator는 있다
+	w := &response.Writer{}
+	h := headers.NewHeaders()
+	h.SetOverride("Content-Length", "10")
+	h.SetOverride("Cache-Control", "max-age=60")
+	assert.True(t, Handle(w, newRequest(t, "GET", `If-None-Match: "v1"`), v, h))
+
+	resp := string(w.Data)
+	assert.True(t, strings.HasPrefix(resp, "HTTP/1.1 304 Not Modified\r\n"), resp)
+	assert.Contains(t, resp, "ETag: \"v1\"\r\n")
+	assert.Contains(t, resp, "Last-Modified: Tue, 04 Mar 2025 05:06:07 GMT\r\n")
+	assert.Contains(t, resp, "Cache-Control: max-age=60\r\n")
+	assert.NotContains(t, resp, "Content-Length")
+	assert.True(t, strings.HasSuffix(resp, "\r\n\r\n"))
+	assert.True(t, w.KeepAlive())
+
+	// Test: 412
+	w = &response.Writer{}
+	assert.True(t, Handle(w, newRequest(t, "PUT", `If-Match: "v0"`), v, nil))
+	assert.True(t, strings.HasPrefix(string(w.Data), "HTTP/1.1 412 Precondition Failed\r\n"))
+
+	// Test: 조건 통과면 아무것도 쓰지 않는다
+	w = &response.Writer{}
+	assert.False(t, Handle(w, newRequest(t, "GET"), v, nil))
+	assert.Empty(t, w.Data)
+}
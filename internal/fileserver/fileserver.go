@@ -0,0 +1,411 @@
+package fileserver
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/conditional"
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/paokimsiwoong/httpfromtcp/internal/server"
+)
+
+// 디렉토리 요청 시 대신 보내는 파일 이름
+const indexPage = "index.html"
+
+// 확장자로 Content-Type을 알 수 없을 때 내용 추측에 쓰는 앞부분 크기
+// @@@ http.DetectContentType은 최대 512 바이트만 본다
+const sniffLen = 512
+
+var ErrInvalidPath = errors.New("request target is not a valid file path")
+
+type fileServer struct {
+	fsys    fs.FS
+	prefix  string // request target에서 떼어낼 경로 앞부분 (ex: "/static")
+	listing bool   // index.html이 없는 디렉토리의 목록을 보여줄지 여부
+}
+
+// New에 넘겨 파일 서버 설정을 바꾸는 옵션 함수 타입
+type Option func(*fileServer)
+
+// request target 앞의 prefix를 떼고 나머지 경로로 파일을 찾도록 하는 옵션
+// ex) WithPrefix("/static") => /static/css/a.css 요청은 css/a.css 파일
+func WithPrefix(prefix string) Option {
+	return func(f *fileServer) {
+		f.prefix = strings.TrimSuffix(prefix, "/")
+	}
+}
+
+// index.html이 없는 디렉토리를 요청하면 파일 목록 HTML을 보여주도록 하는 옵션
+// (설정하지 않으면 403 Forbidden)
+func WithDirectoryListing() Option {
+	return func(f *fileServer) {
+		f.listing = true
+	}
+}
+
+// 디스크의 root 디렉토리를 fs.FS로 반환하는 함수
+func Dir(root string) fs.FS {
+	return os.DirFS(root)
+}
+
+// fsys 안의 파일들을 보내주는 handler를 반환하는 함수
+// embed.FS도 fs.FS를 구현하므로 그대로 넘길 수 있다
+func New(fsys fs.FS, opts ...Option) server.Handler {
+	f := &fileServer{fsys: fsys}
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	return f.serve
+}
+
+func (f *fileServer) serve(w *response.Writer, req *request.Request) {
+	if !allowMethod(w, req) {
+		return
+	}
+
+	target, err := cleanTarget(req.RequestLine.RequestTarget)
+	if err != nil {
+		writeError(w, response.StatusBadRequest)
+		return
+	}
+
+	// prefix로 시작하지 않는 경로는 이 handler가 담당하지 않는다
+	rest, ok := strings.CutPrefix(target, f.prefix)
+	if !ok || (rest != "" && !strings.HasPrefix(rest, "/")) {
+		writeError(w, response.StatusNotFound)
+		return
+	}
+
+	name, err := toName(rest)
+	if err != nil {
+		writeError(w, response.StatusBadRequest)
+		return
+	}
+
+	info, err := fs.Stat(f.fsys, name)
+	if err != nil {
+		writeError(w, statusForError(err))
+		return
+	}
+
+	if !info.IsDir() {
+		ServeFile(w, req, f.fsys, name)
+		return
+	}
+
+	// 디렉토리는 /로 끝나는 주소로 보내야 상대 경로 링크가 제대로 동작
+	if !strings.HasSuffix(target, "/") {
+		redirect(w, dirLocation(target, req.RequestLine.RequestTarget))
+		return
+	}
+
+	index := path.Join(name, indexPage)
+	if indexInfo, err := fs.Stat(f.fsys, index); err == nil && !indexInfo.IsDir() {
+		ServeFile(w, req, f.fsys, index)
+		return
+	}
+
+	if !f.listing {
+		writeError(w, response.StatusForbidden)
+		return
+	}
+
+	serveDirList(w, req, f.fsys, name, target)
+}
+
+// fsys 안의 name 파일 하나를 보내는 함수
+// Content-Type은 확장자로 정하고, 확장자로 알 수 없으면 파일 앞부분을 보고 추측한다
+// 파일 전체를 메모리에 올리지 않고 Writer.Write로 나눠서 보낸다
+// Range 헤더가 있으면 요청한 구간만 206 Partial Content로 보낸다
+func ServeFile(w *response.Writer, req *request.Request, fsys fs.FS, name string) {
+	if !allowMethod(w, req) {
+		return
+	}
+
+	file, err := fsys.Open(name)
+	if err != nil {
+		writeError(w, statusForError(err))
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		writeError(w, statusForError(err))
+		return
+	}
+	if info.IsDir() {
+		writeError(w, response.StatusForbidden)
+		return
+	}
+
+	var body io.Reader = file
+	// Range 요청은 원하는 위치로 이동할 수 있는 파일일 때만 처리
+	seeker, seekable := file.(io.ReadSeeker)
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		// 앞부분을 읽어서 추측
+		sniffed := make([]byte, sniffLen)
+		n, err := io.ReadFull(file, sniffed)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			writeError(w, response.StatusInternalServerError)
+			return
+		}
+		contentType = http.DetectContentType(sniffed[:n])
+
+		// 읽은 부분을 다시 body 앞에 붙이기 (이동 가능한 파일이면 처음으로 되돌리기)
+		if seekable {
+			_, err = seeker.Seek(0, io.SeekStart)
+			if err != nil {
+				writeError(w, response.StatusInternalServerError)
+				return
+			}
+		} else {
+			body = io.MultiReader(strings.NewReader(string(sniffed[:n])), file)
+		}
+	}
+
+	size := info.Size()
+
+	h := headers.NewHeaders()
+
+	// 파일 수정 시각과 크기로 validator를 만들고 조건부 request 처리 (304, 412)
+	validators := conditional.Validators{
+		ETag:         conditional.FileETag(info),
+		LastModified: info.ModTime(),
+	}
+	if conditional.Handle(w, req, validators, h) {
+		return
+	}
+	validators.SetHeaders(h)
+
+	h.SetOverride("Content-Type", contentType)
+
+	var ranges []byteRange
+	if seekable {
+		h.SetOverride("Accept-Ranges", "bytes")
+
+		rangeHeader := req.Headers.Get("Range")
+		if rangeHeader != "" && ifRangeMatches(req.Headers.Get("If-Range"), info.ModTime(), validators.ETag) {
+			ranges, err = parseRange(rangeHeader, size)
+			switch {
+			case errors.Is(err, errUnsatisfiableRange):
+				h.SetOverride("Content-Range", fmt.Sprintf("bytes */%d", size))
+				writeStatus(w, response.StatusRangeNotSatisfiable, h)
+				return
+			case err != nil:
+				// 문법이 틀리거나 구간이 너무 많은 Range 헤더는 무시하고 전체를 보낸다
+				ranges = nil
+			}
+		}
+	}
+
+	switch len(ranges) {
+	case 0:
+		serveContent(w, req, h, response.StatusOK, size, func() error {
+			_, err := io.Copy(w, body)
+			return err
+		})
+	case 1:
+		r := ranges[0]
+		h.SetOverride("Content-Range", r.contentRange(size))
+		serveContent(w, req, h, response.StatusPartialContent, r.length, func() error {
+			return copyRange(w, seeker, r)
+		})
+	default:
+		boundary := randomBoundary()
+		h.SetOverride("Content-Type", "multipart/byteranges; boundary="+boundary)
+		length := multipartLength(boundary, contentType, ranges, size)
+		serveContent(w, req, h, response.StatusPartialContent, length, func() error {
+			for _, r := range ranges {
+				_, err := io.WriteString(w, partHeader(boundary, contentType, r, size))
+				if err != nil {
+					return err
+				}
+				err = copyRange(w, seeker, r)
+				if err != nil {
+					return err
+				}
+				_, err = io.WriteString(w, "\r\n")
+				if err != nil {
+					return err
+				}
+			}
+			_, err := io.WriteString(w, closingBoundary(boundary))
+			return err
+		})
+	}
+}
+
+// status line과 헤더(Content-Length 포함)를 쓰고 writeBody로 body를 쓰는 함수
+// HEAD 요청이면 body는 쓰지 않는다
+func serveContent(w *response.Writer, req *request.Request, h headers.Headers, statusCode response.StatusCode, length int64, writeBody func() error) {
+	err := w.WriteStatusLine(statusCode)
+	if err != nil {
+		log.Printf("error writing status line: %v", err)
+		return
+	}
+
+	h.SetOverride("Content-Length", strconv.FormatInt(length, 10))
+
+	err = w.WriteHeaders(h)
+	if err != nil {
+		log.Printf("error writing headers: %v", err)
+		return
+	}
+
+	// HEAD 요청에는 헤더만 보낸다
+	if req.RequestLine.Method == "HEAD" {
+		return
+	}
+
+	err = writeBody()
+	if err != nil {
+		log.Printf("error writing file body: %v", err)
+		return
+	}
+}
+
+// 파일의 구간 r을 w에 쓰는 함수
+func copyRange(w io.Writer, file io.ReadSeeker, r byteRange) error {
+	_, err := file.Seek(r.start, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.CopyN(w, file, r.length)
+	return err
+}
+
+// GET, HEAD 이외의 메소드에는 405를 보내고 false 반환하는 함수
+func allowMethod(w *response.Writer, req *request.Request) bool {
+	switch req.RequestLine.Method {
+	case "GET", "HEAD":
+		return true
+	}
+
+	h := headers.NewHeaders()
+	h.SetOverride("Allow", "GET, HEAD")
+	writeStatus(w, response.StatusMethodNotAllowed, h)
+
+	return false
+}
+
+// request target에서 query를 떼고 %XX 인코딩을 푼 경로를 반환하는 함수
+func cleanTarget(target string) (string, error) {
+	target, _, _ = strings.Cut(target, "?")
+
+	if !strings.HasPrefix(target, "/") {
+		return "", ErrInvalidPath
+	}
+
+	unescaped, err := url.PathUnescape(target)
+	if err != nil {
+		return "", ErrInvalidPath
+	}
+
+	return unescaped, nil
+}
+
+// URL 경로를 fs.FS에서 쓰는 파일 이름으로 바꾸는 함수
+// root 밖으로 나가려는 경로(..)나 NUL, \ 문자가 들어간 경로는 거부
+func toName(urlPath string) (string, error) {
+	if strings.ContainsAny(urlPath, "\x00\\") {
+		return "", ErrInvalidPath
+	}
+
+	for _, segment := range strings.Split(urlPath, "/") {
+		if segment == ".." {
+			return "", ErrInvalidPath
+		}
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+	if name == "" {
+		name = "."
+	}
+
+	if !fs.ValidPath(name) {
+		return "", ErrInvalidPath
+	}
+
+	return name, nil
+}
+
+// 파일 열기 에러를 알맞은 status code로 바꾸는 함수
+func statusForError(err error) response.StatusCode {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return response.StatusNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return response.StatusForbidden
+	case errors.Is(err, fs.ErrInvalid):
+		return response.StatusBadRequest
+	default:
+		return response.StatusInternalServerError
+	}
+}
+
+// /로 끝나지 않는 디렉토리 경로를 /를 붙인 Location 값으로 바꾸는 함수 (원래 request target의 query는 유지)
+// @@@ target은 퍼센트 디코딩된 경로이므로 공백이나 한글이 그대로 들어가지 않도록 다시 인코딩한다
+// @@@ "//name"을 그대로 쓰면 host가 name인 protocol-relative 주소가 되므로 앞의 /는 하나만 남긴다
+func dirLocation(target, rawTarget string) string {
+	location := (&url.URL{Path: "/" + strings.TrimLeft(target, "/") + "/"}).EscapedPath()
+	if _, query, ok := strings.Cut(rawTarget, "?"); ok {
+		location += "?" + query
+	}
+
+	return location
+}
+
+// location으로 301 redirect response를 보내는 함수
+func redirect(w *response.Writer, location string) {
+	h := headers.NewHeaders()
+	h.SetOverride("Location", location)
+	writeStatus(w, response.StatusMovedPermanently, h)
+}
+
+// status code와 reason phrase를 body로 보내는 함수
+func writeError(w *response.Writer, statusCode response.StatusCode) {
+	writeStatus(w, statusCode, headers.NewHeaders())
+}
+
+// 주어진 헤더에 짧은 text/plain body를 붙여 response를 보내는 함수
+func writeStatus(w *response.Writer, statusCode response.StatusCode, h headers.Headers) {
+	body := strconv.Itoa(int(statusCode)) + " " + response.StatusText(statusCode) + "\n"
+
+	err := w.WriteStatusLine(statusCode)
+	if err != nil {
+		log.Printf("error writing status line: %v", err)
+		return
+	}
+
+	h.SetOverride("Content-Length", strconv.Itoa(len(body)))
+	h.SetOverride("Content-Type", "text/plain; charset=utf-8")
+
+	err = w.WriteHeaders(h)
+	if err != nil {
+		log.Printf("error writing headers: %v", err)
+		return
+	}
+
+	_, err = w.WriteBody([]byte(body))
+	if err != nil {
+		log.Printf("error writing body: %v", err)
+		return
+	}
+}
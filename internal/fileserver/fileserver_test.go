@@ -0,0 +1,162 @@
+package fileserver
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/paokimsiwoong/httpfromtcp/internal/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// PNG 시그니처로 시작하는 확장자 없는 파일 (내용으로 Content-Type 추측)
+var pngData = "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 32)
+
+var testFS = fstest.MapFS{
+	"index.html":         {Data: []byte("<h1>home</h1>")},
+	"css/site.css":       {Data: []byte("body{}")},
+	"images/logo":        {Data: []byte(pngData)},
+	"docs/a b.txt":       {Data: []byte("spaced")},
+	"docs/notes.txt":     {Data: []byte("notes")},
+	"docs/sub/readme.md": {Data: []byte("# readme")},
+}
+
+// handler에 request를 보내고 response 전체를 문자열로 반환하는 함수
+func do(t *testing.T, h server.Handler, method, target string) string {
+	t.Helper()
+
+	req, err := request.RequestFromReader(strings.NewReader(method + " " + target + " HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+
+	w := &response.Writer{}
+	h(w, req)
+
+	return string(w.Data)
+}
+
+func TestServeFiles(t *testing.T) {
+	h := New(testFS)
+
+	// Test: 확장자로 Content-Type
+	resp := do(t, h, "GET", "/css/site.css")
+	assert.True(t, strings.HasPrefix(resp, "HTTP/1.1 200 OK\r\n"), resp)
+	assert.Contains(t, resp, "Content-Type: text/css; charset=utf-8\r\n")
+	assert.Contains(t, resp, "Content-Length: 6\r\n")
+	assert.True(t, strings.HasSuffix(resp, "\r\n\r\nbody{}"))
+
+	// Test: 확장자가 없으면 내용으로 추측하고 body는 온전히 보낸다
+	resp = do(t, h, "GET", "/images/logo")
+	assert.Contains(t, resp, "Content-Type: image/png\r\n")
+	assert.True(t, strings.HasSuffix(resp, pngData))
+
+	// Test: 디렉토리는 index.html
+	resp = do(t, h, "GET", "/")
+	assert.True(t, strings.HasSuffix(resp, "<h1>home</h1>"))
+
+	// Test: %XX 인코딩과 query string
+	resp = do(t, h, "GET", "/docs/a%20b.txt?download=1")
+	assert.True(t, strings.HasSuffix(resp, "spaced"))
+
+	// Test: HEAD는 헤더만
+	resp = do(t, h, "HEAD", "/docs/notes.txt")
+	assert.Contains(t, resp, "Content-Length: 5\r\n")
+	assert.True(t, strings.HasSuffix(resp, "\r\n\r\n"))
+
+	// Test: 없는 파일, 잘못된 메소드
+	assert.Contains(t, do(t, h, "GET", "/missing.txt"), "404 Not Found")
+	resp = do(t, h, "POST", "/index.html")
+	assert.Contains(t, resp, "405 Method Not Allowed")
+	assert.Contains(t, resp, "Allow: GET, HEAD\r\n")
+}
+
+func TestPathTraversal(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, "public"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "public", "ok.txt"), []byte("ok"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "secret.txt"), []byte("secret"), 0o644))
+
+	h := New(Dir(filepath.Join(root, "public")))
+
+	assert.True(t, strings.HasSuffix(do(t, h, "GET", "/ok.txt"), "ok"))
+
+	for _, target := range []string{
+		"/../secret.txt",
+		"/%2e%2e/secret.txt",
+		"/..%2fsecret.txt",
+		"/sub/../../secret.txt",
+		"/..\\secret.txt",
+		"/ok.txt%00",
+		"/%zz",
+		"relative.txt",
+	} {
+		resp := do(t, h, "GET", target)
+		assert.NotContains(t, resp, "secret\r\n", target)
+		assert.False(t, strings.HasPrefix(resp, "HTTP/1.1 200"), target)
+	}
+}
+
+func TestDirectories(t *testing.T) {
+	// Test: / 없이 디렉토리를 요청하면 redirect
+	resp := do(t, New(testFS), "GET", "/docs")
+	assert.Contains(t, resp, "301 Moved Permanently")
+	assert.Contains(t, resp, "Location: /docs/\r\n")
+
+	// Test: redirect 주소는 다시 퍼센트 인코딩하고 query는 유지한다
+	dirs := fstest.MapFS{
+		"my dir/a.txt": {Data: []byte("a")},
+		"한글/a.txt":     {Data: []byte("a")},
+		"name/a.txt":   {Data: []byte("a")},
+	}
+	resp = do(t, New(dirs), "GET", "/my%20dir?sort=name")
+	assert.Contains(t, resp, "Location: /my%20dir/?sort=name\r\n")
+	resp = do(t, New(dirs), "GET", "/%ED%95%9C%EA%B8%80")
+	assert.Contains(t, resp, "Location: /%ED%95%9C%EA%B8%80/\r\n")
+
+	// Test: //name은 host가 name인 주소(//name/)가 아니라 같은 host의 /name/으로 보낸다
+	resp = do(t, New(dirs), "GET", "//name")
+	assert.Contains(t, resp, "301 Moved Permanently")
+	assert.Contains(t, resp, "Location: /name/\r\n")
+
+	// Test: index.html이 없고 목록 옵션이 없으면 403
+	assert.Contains(t, do(t, New(testFS), "GET", "/docs/"), "403 Forbidden")
+
+	// Test: 목록 옵션
+	resp = do(t, New(testFS, WithDirectoryListing()), "GET", "/docs/")
+	assert.Contains(t, resp, "Content-Type: text/html; charset=utf-8\r\n")
+	assert.Contains(t, resp, `<a href="./a%20b.txt">a b.txt</a>`)
+	assert.Contains(t, resp, `<a href="./sub/">sub/</a>`)
+	assert.Contains(t, resp, `<a href="../">../</a>`)
+}
+
+func TestPrefix(t *testing.T) {
+	h := New(testFS, WithPrefix("/static/"))
+
+	assert.True(t, strings.HasSuffix(do(t, h, "GET", "/static/css/site.css"), "body{}"))
+	assert.True(t, strings.HasSuffix(do(t, h, "GET", "/static/"), "<h1>home</h1>"))
+	assert.Contains(t, do(t, h, "GET", "/staticfoo/css/site.css"), "404 Not Found")
+	assert.Contains(t, do(t, h, "GET", "/css/site.css"), "404 Not Found")
+}
+
+func TestServeFileStreams(t *testing.T) {
+	// Test: flushThreshold보다 큰 파일은 Writer의 dst로 나눠서 나간다
+	big := bytes.Repeat([]byte("0123456789abcdef"), 16*1024) // 256KiB
+	fsys := fstest.MapFS{"big.bin": {Data: big}}
+
+	req, err := request.RequestFromReader(strings.NewReader("GET /big.bin HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+
+	dst := &bytes.Buffer{}
+	w := response.NewWriter(dst)
+	ServeFile(w, req, fsys, "big.bin")
+
+	assert.Less(t, len(w.Data), len(big), "file should not be buffered entirely in the Writer")
+	require.NoError(t, w.Flush())
+	assert.True(t, bytes.HasSuffix(dst.Bytes(), big))
+	assert.Equal(t, len(big), w.BytesWritten())
+}
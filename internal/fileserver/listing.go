@@ -0,0 +1,72 @@
+package fileserver
+
+import (
+	"html"
+	"io/fs"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+)
+
+// 디렉토리 안의 파일 목록을 HTML로 보내는 함수
+// target은 /로 끝나는 URL 경로 (링크는 그 아래 상대 경로로 만든다)
+func serveDirList(w *response.Writer, req *request.Request, fsys fs.FS, name, target string) {
+	entries, err := fs.ReadDir(fsys, name)
+	if err != nil {
+		writeError(w, statusForError(err))
+		return
+	}
+
+	sb := &strings.Builder{}
+	title := html.EscapeString(target)
+
+	sb.WriteString("<!doctype html>\n<html>\n  <head>\n    <title>Index of " + title + "</title>\n  </head>\n  <body>\n")
+	sb.WriteString("    <h1>Index of " + title + "</h1>\n    <ul>\n")
+	if target != "/" {
+		sb.WriteString("      <li><a href=\"../\">../</a></li>\n")
+	}
+	// fs.ReadDir는 이름순으로 정렬된 목록을 반환
+	for _, entry := range entries {
+		entryName := entry.Name()
+		if entry.IsDir() {
+			entryName += "/"
+		}
+		// @@@ 이름에 : 이 들어있으면 scheme으로 해석되지 않도록 ./ 붙이기
+		href := "./" + (&url.URL{Path: entryName}).EscapedPath()
+		sb.WriteString("      <li><a href=\"" + html.EscapeString(href) + "\">" + html.EscapeString(entryName) + "</a></li>\n")
+	}
+	sb.WriteString("    </ul>\n  </body>\n</html>\n")
+
+	body := sb.String()
+
+	err = w.WriteStatusLine(response.StatusOK)
+	if err != nil {
+		log.Printf("error writing status line: %v", err)
+		return
+	}
+
+	h := headers.NewHeaders()
+	h.SetOverride("Content-Length", strconv.Itoa(len(body)))
+	h.SetOverride("Content-Type", "text/html; charset=utf-8")
+
+	err = w.WriteHeaders(h)
+	if err != nil {
+		log.Printf("error writing headers: %v", err)
+		return
+	}
+
+	if req.RequestLine.Method == "HEAD" {
+		return
+	}
+
+	_, err = w.WriteBody([]byte(body))
+	if err != nil {
+		log.Printf("error writing body: %v", err)
+		return
+	}
+}
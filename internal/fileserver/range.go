@@ -0,0 +1,181 @@
+package fileserver
+
+import (
+	"cmp"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Range 헤더가 문법에 맞지 않을 때의 에러 (RFC 9110에 따라 헤더를 무시하고 전체를 보낸다)
+var errInvalidRange = errors.New("invalid range header")
+
+// Range 헤더의 범위들이 전부 파일 크기를 벗어날 때의 에러 (416 Range Not Satisfiable)
+var errUnsatisfiableRange = errors.New("range not satisfiable")
+
+// 겹치는 구간을 합친 뒤 구간이 이보다 많으면 Range를 무시한다
+// @@@ 작은 구간을 잔뜩 요청하면 구간마다 part 헤더가 붙어서 파일보다 훨씬 큰 response를 만들 수 있다 (RFC 9110 14.2)
+const maxRanges = 100
+
+// 겹치지 않는 구간이 maxRanges보다 많을 때의 에러 (Range를 무시하고 전체를 보낸다)
+var errTooManyRanges = errors.New("too many ranges")
+
+// 파일의 한 구간 (start 바이트부터 length 바이트)
+type byteRange struct {
+	start  int64
+	length int64
+}
+
+// Content-Range 헤더 값을 만드는 메소드 (ex: "bytes 0-499/1234")
+func (r byteRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.start, r.start+r.length-1, size)
+}
+
+// Range 헤더 값을 크기가 size인 파일의 구간들로 바꾸는 함수
+// ex) "bytes=0-499, -500, 9500-"
+// 겹치거나 맞닿은 구간은 시작 위치 순으로 정렬해서 하나로 합친다 (RFC 9110 14.2에서 허용)
+// 만족할 수 있는 구간이 하나도 없으면 errUnsatisfiableRange, 문법이 틀리면 errInvalidRange,
+// 합친 구간이 maxRanges보다 많으면 errTooManyRanges
+func parseRange(header string, size int64) ([]byteRange, error) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok {
+		return nil, errInvalidRange
+	}
+
+	ranges := []byteRange{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		first, last, ok := strings.Cut(part, "-")
+		if !ok {
+			return nil, errInvalidRange
+		}
+		first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+
+		// "-N": 마지막 N 바이트
+		if first == "" {
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n < 0 {
+				return nil, errInvalidRange
+			}
+			if n == 0 || size == 0 {
+				continue
+			}
+			n = min(n, size)
+			ranges = append(ranges, byteRange{start: size - n, length: n})
+			continue
+		}
+
+		start, err := strconv.ParseInt(first, 10, 64)
+		if err != nil || start < 0 {
+			return nil, errInvalidRange
+		}
+
+		end := size - 1
+		// "A-B": 끝이 주어진 경우 (파일 크기를 넘으면 파일 끝까지)
+		if last != "" {
+			end, err = strconv.ParseInt(last, 10, 64)
+			if err != nil || end < start {
+				return nil, errInvalidRange
+			}
+			end = min(end, size-1)
+		}
+
+		// 시작 위치가 파일 밖인 구간은 무시
+		if start >= size {
+			continue
+		}
+
+		ranges = append(ranges, byteRange{start: start, length: end - start + 1})
+	}
+
+	if len(ranges) == 0 {
+		return nil, errUnsatisfiableRange
+	}
+
+	ranges = mergeRanges(ranges)
+	if len(ranges) > maxRanges {
+		return nil, errTooManyRanges
+	}
+
+	return ranges, nil
+}
+
+// 구간들을 시작 위치 순으로 정렬하고 겹치거나 맞닿은 구간을 합치는 함수
+// @@@ "bytes=0-,0-,0-"처럼 같은 구간을 반복해서 파일을 여러 번 보내게 만드는 요청 방어
+func mergeRanges(ranges []byteRange) []byteRange {
+	slices.SortFunc(ranges, func(a, b byteRange) int {
+		return cmp.Compare(a.start, b.start)
+	})
+
+	merged := ranges[:1]
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r.start <= last.start+last.length {
+			last.length = max(last.length, r.start+r.length-last.start)
+			continue
+		}
+		merged = append(merged, r)
+	}
+
+	return merged
+}
+
+// If-Range 조건을 확인해서 Range 요청을 그대로 처리해도 되면 true 반환하는 함수
+// If-Range 값은 ETag 또는 HTTP 날짜이며 파일이 그때와 같을 때만 일부를 보낸다
+// (다르면 Range를 무시하고 전체를 보낸다)
+func ifRangeMatches(ifRange string, modTime time.Time, etag string) bool {
+	if ifRange == "" {
+		return true
+	}
+
+	// ETag 비교는 strong 비교만 허용
+	if strings.HasPrefix(ifRange, `"`) || strings.HasPrefix(ifRange, "W/") {
+		return etag != "" && !strings.HasPrefix(etag, "W/") && ifRange == etag
+	}
+
+	date, err := http.ParseTime(ifRange)
+	if err != nil {
+		return false
+	}
+
+	return !modTime.IsZero() && modTime.Truncate(time.Second).Equal(date)
+}
+
+// multipart/byteranges의 구분자로 쓸 랜덤 문자열을 만드는 함수
+func randomBoundary() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// multipart/byteranges body에서 각 구간 앞에 붙는 part 헤더
+func partHeader(boundary, contentType string, r byteRange, size int64) string {
+	return "--" + boundary + "\r\n" +
+		"Content-Type: " + contentType + "\r\n" +
+		"Content-Range: " + r.contentRange(size) + "\r\n" +
+		"\r\n"
+}
+
+// multipart/byteranges body 마지막 구분자
+func closingBoundary(boundary string) string {
+	return "--" + boundary + "--\r\n"
+}
+
+// multipart/byteranges body 전체 길이를 미리 계산하는 함수 (Content-Length용)
+func multipartLength(boundary, contentType string, ranges []byteRange, size int64) int64 {
+	var total int64
+	for _, r := range ranges {
+		total += int64(len(partHeader(boundary, contentType, r, size))) + r.length + 2 // 데이터 뒤 \r\n
+	}
+	return total + int64(len(closingBoundary(boundary)))
+}
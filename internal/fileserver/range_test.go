@@ -0,0 +1,246 @@
+package fileserver
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const digits = "0123456789"
+
+var modTime = time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+var rangeFS = fstest.MapFS{
+	"digits.txt": {Data: []byte(digits), ModTime: modTime},
+}
+
+// 추가 헤더와 함께 request를 보내고 response 전체를 반환하는 함수
+func doWithHeaders(t *testing.T, target string, extra ...string) string {
+	t.Helper()
+
+	raw := "GET " + target + " HTTP/1.1\r\nHost: localhost\r\n"
+	for _, line := range extra {
+		raw += line + "\r\n"
+	}
+	raw += "\r\n"
+
+	req, err := request.RequestFromReader(strings.NewReader(raw))
+	require.NoError(t, err)
+
+	w := &response.Writer{}
+	New(rangeFS)(w, req)
+
+	return string(w.Data)
+}
+
+// response를 헤더 블록과 body로 나누는 함수
+func splitResponse(t *testing.T, resp string) (string, string) {
+	t.Helper()
+
+	head, body, ok := strings.Cut(resp, "\r\n\r\n")
+	require.True(t, ok)
+
+	return head, body
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected []byteRange
+		err      error
+	}{
+		{"bytes=0-4", []byteRange{{0, 5}}, nil},
+		{"bytes=5-", []byteRange{{5, 5}}, nil},
+		{"bytes=-3", []byteRange{{7, 3}}, nil},
+		{"bytes=-30", []byteRange{{0, 10}}, nil},
+		{"bytes=8-100", []byteRange{{8, 2}}, nil},
+		{"bytes=0-0, 2-3 ,, -1", []byteRange{{0, 1}, {2, 2}, {9, 1}}, nil},
+		{"bytes=20-30, 1-1", []byteRange{{1, 1}}, nil},
+		// 겹치거나 맞닿은 구간은 정렬해서 합친다
+		{"bytes=0-, 0-, 0-", []byteRange{{0, 10}}, nil},
+		{"bytes=6-8, 0-2, 1-4, 5-5", []byteRange{{0, 9}}, nil},
+		{"bytes=-2, 0-1", []byteRange{{0, 2}, {8, 2}}, nil},
+		{"bytes=10-", nil, errUnsatisfiableRange},
+		{"bytes=-0", nil, errUnsatisfiableRange},
+		{"bytes=5-2", nil, errInvalidRange},
+		{"bytes=a-b", nil, errInvalidRange},
+		{"bytes=1", nil, errInvalidRange},
+		{"items=0-1", nil, errInvalidRange},
+	}
+
+	for _, tc := range tests {
+		ranges, err := parseRange(tc.header, int64(len(digits)))
+		if tc.err != nil {
+			assert.ErrorIs(t, err, tc.err, tc.header)
+			continue
+		}
+		require.NoError(t, err, tc.header)
+		assert.Equal(t, tc.expected, ranges, tc.header)
+	}
+}
+
+func TestSingleRange(t *testing.T) {
+	head, body := splitResponse(t, doWithHeaders(t, "/digits.txt", "Range: bytes=2-5"))
+
+	assert.True(t, strings.HasPrefix(head, "HTTP/1.1 206 Partial Content\r\n"), head)
+	assert.Contains(t, head, "Content-Range: bytes 2-5/10")
+	assert.Contains(t, head, "Content-Length: 4")
+	assert.Equal(t, "2345", body)
+
+	// Test: 전체 응답에도 Accept-Ranges
+	head, body = splitResponse(t, doWithHeaders(t, "/digits.txt"))
+	assert.Contains(t, head, "Accept-Ranges: bytes")
+	assert.Equal(t, digits, body)
+
+	// Test: 문법이 틀린 Range는 무시하고 전체
+	head, body = splitResponse(t, doWithHeaders(t, "/digits.txt", "Range: bytes=5-1"))
+	assert.True(t, strings.HasPrefix(head, "HTTP/1.1 200 OK\r\n"))
+	assert.Equal(t, digits, body)
+
+	// Test: 겹치는 구간은 합쳐서 한 구간으로 보낸다
+	head, body = splitResponse(t, doWithHeaders(t, "/digits.txt", "Range: bytes=0-9,0-9"))
+	assert.Contains(t, head, "Content-Range: bytes 0-9/10")
+	assert.Equal(t, digits, body)
+}
+
+func TestTooManyRanges(t *testing.T) {
+	data := strings.Repeat("x", 2*maxRanges+2)
+	size := int64(len(data))
+
+	// 겹치지 않는 1바이트 구간들 (0-0, 2-2, 4-4, ...)
+	parts := make([]string, 0, maxRanges+1)
+	for i := range maxRanges + 1 {
+		parts = append(parts, strconv.Itoa(2*i)+"-"+strconv.Itoa(2*i))
+	}
+	header := "bytes=" + strings.Join(parts, ",")
+
+	// Test: 합친 뒤에도 구간이 maxRanges보다 많으면 거절
+	_, err := parseRange(header, size)
+	assert.ErrorIs(t, err, errTooManyRanges)
+
+	// Test: maxRanges개까지는 허용
+	ranges, err := parseRange("bytes="+strings.Join(parts[:maxRanges], ","), size)
+	require.NoError(t, err)
+	assert.Len(t, ranges, maxRanges)
+
+	// Test: 같은 구간을 아무리 반복해도 합치면 하나
+	ranges, err = parseRange("bytes="+strings.Repeat("0-,", 10000), size)
+	require.NoError(t, err)
+	assert.Equal(t, []byteRange{{0, size}}, ranges)
+
+	// Test: 구간이 너무 많은 Range는 무시하고 200으로 전체를 보낸다
+	fsys := fstest.MapFS{"many.txt": {Data: []byte(data), ModTime: modTime}}
+	req, err := request.RequestFromReader(strings.NewReader("GET /many.txt HTTP/1.1\r\nHost: localhost\r\nRange: " + header + "\r\n\r\n"))
+	require.NoError(t, err)
+	w := &response.Writer{}
+	New(fsys)(w, req)
+	head, body := splitResponse(t, string(w.Data))
+	assert.True(t, strings.HasPrefix(head, "HTTP/1.1 200 OK\r\n"), head)
+	assert.Equal(t, data, body)
+}
+
+func TestUnsatisfiableRange(t *testing.T) {
+	head, _ := splitResponse(t, doWithHeaders(t, "/digits.txt", "Range: bytes=50-60"))
+
+	assert.True(t, strings.HasPrefix(head, "HTTP/1.1 416 Range Not Satisfiable\r\n"), head)
+	assert.Contains(t, head, "Content-Range: bytes */10")
+}
+
+func TestMultipleRanges(t *testing.T) {
+	head, body := splitResponse(t, doWithHeaders(t, "/digits.txt", "Range: bytes=0-1, -2"))
+	assert.True(t, strings.HasPrefix(head, "HTTP/1.1 206 Partial Content\r\n"))
+
+	contentType := ""
+	contentLength := ""
+	for _, line := range strings.Split(head, "\r\n")[1:] {
+		name, value, _ := strings.Cut(line, ": ")
+		switch name {
+		case "Content-Type":
+			contentType = value
+		case "Content-Length":
+			contentLength = value
+		}
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/byteranges", mediaType)
+
+	// Test: Content-Length가 실제 body 길이와 같다
+	assert.Equal(t, strconv.Itoa(len(body)), contentLength)
+
+	reader := multipart.NewReader(strings.NewReader(body), params["boundary"])
+	expected := []struct{ contentRange, data string }{
+		{"bytes 0-1/10", "01"},
+		{"bytes 8-9/10", "89"},
+	}
+	for _, e := range expected {
+		part, err := reader.NextPart()
+		require.NoError(t, err)
+		assert.Equal(t, e.contentRange, part.Header.Get("Content-Range"))
+		assert.Equal(t, "text/plain; charset=utf-8", part.Header.Get("Content-Type"))
+		data, err := io.ReadAll(part)
+		require.NoError(t, err)
+		assert.Equal(t, e.data, string(data))
+	}
+	_, err = reader.NextPart()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestIfRange(t *testing.T) {
+	// Test: 날짜가 같으면 일부만
+	head, body := splitResponse(t, doWithHeaders(t, "/digits.txt", "Range: bytes=0-1", "If-Range: "+modTime.Format(http.TimeFormat)))
+	assert.True(t, strings.HasPrefix(head, "HTTP/1.1 206"))
+	assert.Equal(t, "01", body)
+
+	// Test: 파일이 바뀌었으면(날짜가 다르면) 전체
+	_, body = splitResponse(t, doWithHeaders(t, "/digits.txt", "Range: bytes=0-1", "If-Range: "+modTime.Add(-time.Hour).Format(http.TimeFormat)))
+	assert.Equal(t, digits, body)
+
+	// Test: weak ETag는 If-Range에 쓸 수 없으므로 전체
+	_, body = splitResponse(t, doWithHeaders(t, "/digits.txt", "Range: bytes=0-1", `If-Range: W/"abc"`))
+	assert.Equal(t, digits, body)
+}
+
+func TestConditionalFile(t *testing.T) {
+	head, body := splitResponse(t, doWithHeaders(t, "/digits.txt"))
+	assert.Contains(t, head, "Last-Modified: Thu, 02 Jan 2025 03:04:05 GMT")
+	assert.Equal(t, digits, body)
+
+	etag := ""
+	for _, line := range strings.Split(head, "\r\n") {
+		if value, ok := strings.CutPrefix(line, "ETag: "); ok {
+			etag = value
+		}
+	}
+	require.NotEmpty(t, etag)
+
+	// Test: 받은 ETag로 재요청하면 304
+	head, body = splitResponse(t, doWithHeaders(t, "/digits.txt", "If-None-Match: "+etag))
+	assert.True(t, strings.HasPrefix(head, "HTTP/1.1 304 Not Modified\r\n"), head)
+	assert.Empty(t, body)
+
+	// Test: Last-Modified로 재요청하면 304
+	head, _ = splitResponse(t, doWithHeaders(t, "/digits.txt", "If-Modified-Since: "+modTime.Format(http.TimeFormat)))
+	assert.True(t, strings.HasPrefix(head, "HTTP/1.1 304 Not Modified\r\n"), head)
+
+	// Test: If-Match가 다르면 412
+	head, _ = splitResponse(t, doWithHeaders(t, "/digits.txt", `If-Match: "other"`))
+	assert.True(t, strings.HasPrefix(head, "HTTP/1.1 412 Precondition Failed\r\n"), head)
+
+	// Test: If-Range에 ETag
+	head, body = splitResponse(t, doWithHeaders(t, "/digits.txt", "Range: bytes=0-1", "If-Range: "+etag))
+	assert.True(t, strings.HasPrefix(head, "HTTP/1.1 206"), head)
+	assert.Equal(t, "01", body)
+}
@@ -0,0 +1,49 @@
+package headers
+
+import (
+	"testing"
+)
+
+const chromeHeaders = "Host: localhost:42069\r\n" +
+	"Connection: keep-alive\r\n" +
+	"sec-ch-ua: \"Chromium\";v=\"124\", \"Google Chrome\";v=\"124\", \"Not-A.Brand\";v=\"99\"\r\n" +
+	"sec-ch-ua-mobile: ?0\r\n" +
+	"sec-ch-ua-platform: \"Linux\"\r\n" +
+	"Upgrade-Insecure-Requests: 1\r\n" +
+	"User-Agent: Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36\r\n" +
+	"Accept: text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8\r\n" +
+	"Sec-Fetch-Site: none\r\n" +
+	"Sec-Fetch-Mode: navigate\r\n" +
+	"Sec-Fetch-User: ?1\r\n" +
+	"Sec-Fetch-Dest: document\r\n" +
+	"Accept-Encoding: gzip, deflate, br, zstd\r\n" +
+	"Accept-Language: en-US,en;q=0.9,ko;q=0.8\r\n" +
+	"Cookie: session=4f2a9c; theme=dark\r\n\r\n"
+
+func BenchmarkHeadersParse(b *testing.B) {
+	data := []byte(chromeHeaders)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for b.Loop() {
+		h := NewHeaders()
+		for rest := data; ; {
+			n, done, err := h.Parse(rest)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if done {
+				break
+			}
+			rest = rest[n:]
+		}
+	}
+}
+
+func BenchmarkContainsInvalidChar(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		if ContainsInvalidChar("Sec-Fetch-Mode") {
+			b.Fatal("valid token")
+		}
+	}
+}
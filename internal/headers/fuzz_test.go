@@ -0,0 +1,79 @@
+package headers
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// data를 한 번에 readSize 바이트씩 받는 것처럼 Parse를 반복 호출하는 함수 (request 파서가 호출하는 방식)
+// 헤더 끝(빈 줄)까지 파싱하면 done이 true
+func parseAll(data []byte, readSize int) (h Headers, done bool, err error) {
+	h = NewHeaders()
+	parsed, received := 0, 0
+	for received < len(data) {
+		received = min(received+readSize, len(data))
+		for {
+			n, done, err := h.Parse(data[parsed:received])
+			if err != nil {
+				return nil, false, err
+			}
+			parsed += n
+			if done {
+				return h, true, nil
+			}
+			if n == 0 {
+				break
+			}
+		}
+	}
+	return h, false, nil
+}
+
+// seed corpus는 testdata/fuzz/FuzzHeadersParse (curl, 브라우저가 보낸 request의 헤더 부분)
+func FuzzHeadersParse(f *testing.F) {
+	f.Add([]byte("Host: localhost:42069\r\nUser-Agent: curl/8.5.0\r\nAccept: */*\r\n\r\n"), uint8(3))
+	f.Add([]byte("Accept: text/html\r\naccept: */*\r\nX-Empty:\r\n\r\n"), uint8(1))
+
+	f.Fuzz(func(t *testing.T, data []byte, readSize uint8) {
+		// Property: Parse가 반환하는 n은 주어진 데이터를 넘지 않고, 끝이면 빈 줄(2바이트)만 소비
+		h := NewHeaders()
+		n, done, err := h.Parse(data)
+		if n < 0 || n > len(data) || (done && n != 2) || (err != nil && n != 0) {
+			t.Fatalf("Parse(%q) = %d, %v, %v", data, n, done, err)
+		}
+
+		// Property: 한 번에 받는 크기와 상관없이 같은 결과
+		want, wantDone, wantErr := parseAll(data, len(data)+1)
+		got, gotDone, err := parseAll(data, int(readSize)%16+1)
+		if (err == nil) != (wantErr == nil) || gotDone != wantDone || !maps.Equal(got, want) {
+			t.Fatalf("read size %d: %v, %v, %v\nwhole: %v, %v, %v", int(readSize)%16+1, got, gotDone, err, want, wantDone, wantErr)
+		}
+		if wantErr != nil || !wantDone {
+			return
+		}
+
+		// Property: 파싱된 key는 소문자 token, 값은 앞뒤 공백과 CR, LF, NUL이 없다
+		for key, value := range want {
+			if ContainsInvalidChar(key) || key != strings.ToLower(key) {
+				t.Fatalf("invalid key %q", key)
+			}
+			if value != strings.TrimSpace(value) || strings.ContainsAny(value, "\r\n\x00") {
+				t.Fatalf("invalid value %q for %q", value, key)
+			}
+		}
+
+		// Property: 파싱 결과를 다시 헤더 줄로 써서 파싱하면 같은 결과
+		var b strings.Builder
+		for _, key := range slices.Sorted(maps.Keys(want)) {
+			b.WriteString(key + ": " + want[key] + crlf)
+		}
+		b.WriteString(crlf)
+
+		reparsed, done, err := parseAll([]byte(b.String()), len(b.String()))
+		if err != nil || !done || !maps.Equal(reparsed, want) {
+			t.Fatalf("round trip changed headers: %v, %v\n%v\n%v", err, done, want, reparsed)
+		}
+	})
+}
@@ -1,8 +1,8 @@
 package headers
 
 import (
+	"bytes"
 	"errors"
-	"regexp"
 	"strings"
 	"unicode"
 )
@@ -18,6 +18,7 @@ var ErrMissingColon = errors.New("header line must contain colon")
 var ErrMissingName = errors.New("header line must contain header name")
 var ErrInvalidName = errors.New("header name contain invalid character")
 var ErrInvalidWSBetweenNameAndColon = errors.New("there must be no spaces betweern colon and header name")
+var ErrInvalidValue = errors.New("header value must not contain CR, LF or NUL")
 
 // var ErrMultipleColon = errors.New("there must be one and only one colon")
 // @@@ Host: localhost:42069\r\n 와 같이 값에 :가 또 들어갈 수도 있다
@@ -39,25 +40,26 @@ func (h Headers) Parse(data []byte) (n int, done bool, err error) {
 	// @@@ 고에서 map은 실제 데이터가 위치한 메모리 주소를 저장하는 참조타입
 	// // @@@ ==> h를 통해 맵 내부의 데이터를 변경하면 원본 맵이 가리키는 데이터들도 변함
 	// // // @@@ slice, map, channel, function, interface : 참조 타입 (referece type)
-	strData := string(data)
 
-	// CRLF를 포함하고 있는지 확인
-	if !strings.Contains(strData, crlf) {
+	// @@@ 구조 변경: data 전체를 string으로 바꾸고 strings.Split 하는 대신 바이트 그대로 CRLF 위치만 찾는다
+	// @@@ (헤더가 여러 줄이면 호출할 때마다 남은 data 전체를 복사하고 나눠서 헤더 크기의 제곱에 비례해 느려졌다)
+	lineEnd := bytes.Index(data, []byte(crlf))
+	if lineEnd == -1 {
 		// 없으면 데이터를 더 읽은 후 다시 이 함수를 호출하도록 알리는 내용을 담아 반환 (0 바이트 파싱됨, 파싱 미완효, 에러 nil)
 		return 0, false, nil
 	}
 
 	// CRLF로 시작하는지 확인 (헤더 라인들이 끝날 때 \r\n 두번 반복)
-	if strData[:2] == crlf {
+	if lineEnd == 0 {
 		// 헤더 라인 파싱이 끝났다고 알림
 		return 2, true, nil
 		// @@@ \r\n 2바이트
 	}
 
 	// 헤더 라인 분리
-	line := strings.Split(strData, crlf)[0]
+	line := data[:lineEnd]
 	// :의 인덱스 찾기
-	colonIdx := strings.Index(line, ":")
+	colonIdx := bytes.IndexByte(line, ':')
 	// :이 없으면 에러
 	if colonIdx == -1 {
 		return 0, false, ErrMissingColon
@@ -67,85 +69,133 @@ func (h Headers) Parse(data []byte) (n int, done bool, err error) {
 		return 0, false, ErrMissingName
 	}
 	// 헤더 이름과 : 사이에는 공백이 있으면 안된다
-	// if line[colonIdx-1] == " " { @@@ 스트링을 인덱스로 접근해서 반환받은 값은 byte 타입이므로 " "(string)과 ==으로 비교하면 에러 발생
-	// if line[colonIdx-1] == ' ' { @@@ ' '로 바이트 타입은 일치하지만 공백에 해당하는 \t와 같은 케이스도 커버하도록 변경
+	// @@@ \t와 같이 공백에 해당하는 다른 문자도 커버하도록 unicode.IsSpace 사용
 	if unicode.IsSpace(rune(line[colonIdx-1])) {
-		// unicode.IsSpace(r)는 유니코드에서 공백으로 정의된 모든 문자를 판별
 		return 0, false, ErrInvalidWSBetweenNameAndColon
 	}
-	// // @@@ 공백 제거 방식 변경
-	// // // @@@ line에 복수 value 존재시 value 사이에
-	// // // @@@ ,와 공백 한칸으로 구분되어 있으므로
-	// // // @@@ 그 공백은 지우면 안됨
-	// 공백 제거
-	// trimmed := strings.ReplaceAll(line, " ", "")
-	// @@@ 공백이 \t와 같이 다른 것일 경우 어떻게 처리할지?
-
-	// @@@ colon : 처리 방식 변경
-	// @@@ Host: localhost:42069\r\n 와 같이 값에 :가 또 들어갈 수도 있다
-	// 헤더 이름과 값으로 분리
-	// nameAndValue := strings.Split(trimmed, ":")
-
-	// // :이 복수개 들어간 경우 에러
-	// if len(nameAndValue) != 2 {
-	// 	return 0, false, ErrMultipleColon
-	// }
-
-	// 헤더 맵에 입력
-	// h[nameAndValue[0]] = nameAndValue[1]
-
-	// 헤더 이름과 값을 구분하는 첫번쨰 : 인덱스 찾기
-	// colonIdx = strings.Index(trimmed, ":")
-
-	// headerName := trimmed[:colonIdx]
-	// headerValue := trimmed[colonIdx+1:]
-	// // @@@ 공백 제거 방식 변경
-	headerName := strings.TrimSpace(line[:colonIdx])
-	headerValue := strings.TrimSpace(line[colonIdx+1:])
-	// strings.TrimSpace는 문자열의 앞과 뒤에 붙어있는 공백 제거 (공백 아닌 문자열 사이의 공백은 유지)
+
+	// 헤더 이름과 값의 앞뒤 공백 제거 (값 사이의 공백은 유지)
+	// @@@ Host: localhost:42069\r\n 와 같이 값에 :가 또 들어갈 수도 있으므로 첫번째 :로만 나눈다
+	name := bytes.TrimSpace(line[:colonIdx])
+	value := bytes.TrimSpace(line[colonIdx+1:])
 
 	// @@@ field name(헤더 네임)이 RFC 9110에서 정의한 가능한 문자 범위를 벗어나는 경우 예외 처리
-	// // @@@ 알파벳 대,소문자, 0-9, !, #, $, %, &, ', *, +, -, ., ^, _, `, |, ~ 만 가능
-	if ContainsInvalidChar(headerName) {
+	if !isToken(name) {
 		return 0, false, ErrInvalidName
 	}
 
+	// @@@ RFC 9110 5.5: 값 안의 CR, LF, NUL은 다른 헤더를 끼워넣는 데 쓰일 수 있으므로 거절
+	if bytes.ContainsAny(value, "\r\n\x00") {
+		return 0, false, ErrInvalidValue
+	}
+
 	// @@@ 맵에 들어가는 key는 대문자를 소문자로 변경
-	headerName = strings.ToLower(headerName)
+	headerName := lowerName(name)
 
 	// header name이 맵에 이미 존재하는지 확인
 	curValue, ok := h[headerName]
-	if ok {
+	// @@@ 빈 값은 목록에 아무것도 더하지 않는다 (RFC 9110 5.6.1, "A:\r\nA:\r\n"이 ", "가 되지 않도록)
+	if ok && len(value) == 0 {
+		return lineEnd + 2, false, nil
+	}
+	if ok && curValue != "" {
 		// 기존에 존재하는 이름이면 ,
-		h[headerName] = curValue + ", " + headerValue
+		h[headerName] = curValue + ", " + string(value)
 		// @@@ RFC 9110에 따르면 값 사이 구분은 ",OWS" 즉 , 한개와 optional white space 한개(optional이지만 표준 권장 사항)
 	} else {
 		// 헤더 맵에 입력
-		h[headerName] = headerValue
+		h[headerName] = string(value)
 		// // @@@ value는 그대로
 	}
 
 	// 파싱 완료 후 처리된 바이트 길이 반환
-	return len(line) + 2, false, nil
+	return lineEnd + 2, false, nil
 	// @@@ 헤더 부분 + CRLF 2바이트
 }
 
-// @@@ Perp AI 답변 수정(raw string literal 에러)
+// RFC 9110 5.6.2에서 token(헤더 이름 등)에 쓸 수 있는 문자표
+// 알파벳 대,소문자, 0-9, !, #, $, %, &, ', *, +, -, ., ^, _, `, |, ~
+// @@@ 구조 변경: 매번 regexp.MatchString으로 패턴을 컴파일하는 대신 미리 만든 표에서 바이트마다 확인
+var tokenTable = func() (table [256]bool) {
+	for c := '0'; c <= '9'; c++ {
+		table[c] = true
+	}
+	for c := 'a'; c <= 'z'; c++ {
+		table[c] = true
+		table[c-'a'+'A'] = true
+	}
+	for _, c := range "!#$%&'*+-.^_`|~" {
+		table[c] = true
+	}
+	return table
+}()
+
+// b가 비어있지 않고 token 문자로만 이루어져 있는지 확인하는 함수
+func isToken(b []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+	for _, c := range b {
+		if !tokenTable[c] {
+			return false
+		}
+	}
+	return true
+}
+
+// 자주 쓰는 헤더 이름 (소문자)
+// @@@ lowerName이 이 이름들은 새로 할당하지 않고 여기 있는 string을 그대로 쓴다
+var commonNames = func() map[string]string {
+	names := map[string]string{}
+	for _, name := range []string{
+		"accept", "accept-encoding", "accept-language", "authorization", "cache-control",
+		"connection", "content-encoding", "content-length", "content-type", "cookie",
+		"expect", "forwarded", "host", "if-match", "if-modified-since", "if-none-match",
+		"if-range", "if-unmodified-since", "origin", "pragma", "range", "referer",
+		"te", "trailer", "transfer-encoding", "upgrade", "upgrade-insecure-requests", "user-agent",
+		"x-forwarded-for", "x-forwarded-host", "x-forwarded-proto", "x-request-id",
+		"sec-fetch-dest", "sec-fetch-mode", "sec-fetch-site", "sec-fetch-user",
+		"sec-websocket-extensions", "sec-websocket-key", "sec-websocket-protocol", "sec-websocket-version",
+	} {
+		names[name] = name
+	}
+	return names
+}()
+
+// token인 헤더 이름을 소문자 string으로 바꾸는 함수
+func lowerName(name []byte) string {
+	var buf [64]byte
+	if len(name) > len(buf) {
+		return strings.ToLower(string(name))
+	}
+
+	lower := buf[:len(name)]
+	for i, c := range name {
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		lower[i] = c
+	}
+
+	// @@@ map[string(b)] 조회는 컴파일러가 할당 없이 처리한다
+	if common, ok := commonNames[string(lower)]; ok {
+		return common
+	}
+	return string(lower)
+}
+
 // 알파벳 대,소문자, 0-9, !, #, $, %, &, ', *, +, -, ., ^, _, `, |, ~ 를 벗어나는 문자가 있으면
-// true 반환
+// true 반환 (빈 문자열도 true)
 func ContainsInvalidChar(s string) bool {
-	// 허용 문자 집합: A-Z, a-z, 0-9, !#$%&'*+-.^_`|~
-	// pattern := `^[A-Za-z0-9!#$%&'*+\-.\^_`\|~]+$`
-	// @@@ golang의 raw string literal(`~~`)안에는 `포함 불가
-	pattern := "^[A-Za-z0-9!#$%&'*+\\-.^_`|~]+$"
-	// @@@ [] 브래킷 안에서 대부분의 정규 표현식 예약어는 단순 문자 취급되지만
-	// @@@ - (A-Z 와 같이 [] 안에서0 범위 지정에 사용),
-	// @@@ ] (브래킷 닫기 대신 문자 ]를 쓰려면 이스케이프 필요),
-	// @@@ ^ ([^abc] 같이 [] 안의 문자열 맨앞에 오면 그 문자열의 부정이 된다, 현재는 ^가 맨 앞이 아니므로 이스케이프 불필요),
-	// @@@ \ (이스케이프 기능을 수행, 이스케이프 대신 \ 자체를 포함하려면 \\, golang 문자열에 들어가려면 \\\\)
-	// // @@@ 현재는 이스케이프 기능으로 사용하므로 \ 한개지만 golang 문자열에 들어가려면 \\ 두개 필요
-	matched, _ := regexp.MatchString(pattern, s)
-	return !matched
+	if s == "" {
+		return true
+	}
+	for i := 0; i < len(s); i++ {
+		if !tokenTable[s[i]] {
+			return true
+		}
+	}
+	return false
 }
 
 // key 값을 받으면 value를 반환하는 Headers의 메소드
@@ -174,3 +224,34 @@ func (h Headers) SetOverride(key, value string) {
 
 	h[key] = value
 }
+
+// 대소문자 구분 없이 key에 해당하는 value를 찾는 메소드
+// @@@ 파싱된 헤더는 소문자 key지만 response 헤더는 SetOverride("Content-Length", ...)처럼 쓰이므로 Get으로는 찾을 수 없다
+func (h Headers) Lookup(key string) string {
+	for k, v := range h {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
+// 대소문자 구분 없이 key에 해당하는 헤더를 모두 지우는 메소드
+func (h Headers) Delete(key string) {
+	for k := range h {
+		if strings.EqualFold(k, key) {
+			delete(h, k)
+		}
+	}
+}
+
+// 콤마로 구분된 헤더 값(ex: Connection, Upgrade)에 token이 있는지 대소문자 구분 없이 확인하는 함수
+// @@@ "keep-alive, close"처럼 여러 token이 올 수 있으므로 값 전체를 비교하면 안된다
+func HasToken(value, token string) bool {
+	for _, part := range strings.Split(value, ",") {
+		if strings.EqualFold(strings.TrimSpace(part), token) {
+			return true
+		}
+	}
+	return false
+}
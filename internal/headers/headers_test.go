@@ -130,4 +130,36 @@ func TestHeaderLineParse(t *testing.T) {
 	require.Error(t, err)
 	assert.Equal(t, 0, n)
 	assert.False(t, done)
+	// Test: 값에 CR만 따로 들어있는 경우 (에러 발생)
+	headers = NewHeaders()
+	data = []byte("X-User: bob\rX-Admin: true\r\n\r\n")
+	n, _, err = headers.Parse(data)
+	require.ErrorIs(t, err, ErrInvalidValue)
+	assert.Equal(t, 0, n)
+
+	// Test: 빈 값은 같은 이름의 다른 값과 합치지 않는다
+	headers = NewHeaders()
+	data = []byte("Accept:\r\nAccept: text/html\r\nAccept:\r\n\r\n")
+	for _, want := range []int{9, 19, 9} {
+		n, _, err = headers.Parse(data)
+		require.NoError(t, err)
+		assert.Equal(t, want, n)
+		data = data[n:]
+	}
+	assert.Equal(t, "text/html", headers["accept"])
+}
+
+func TestLookupAndDelete(t *testing.T) {
+	// Test: 대소문자가 다른 key로도 찾을 수 있다
+	h := NewHeaders()
+	h.SetOverride("Content-Length", "13")
+	h.SetOverride("content-type", "text/plain")
+	assert.Equal(t, "13", h.Lookup("content-length"))
+	assert.Equal(t, "text/plain", h.Lookup("Content-Type"))
+	assert.Equal(t, "", h.Lookup("Content-Encoding"))
+
+	// Test: 대소문자가 달라도 지운다
+	h.Delete("CONTENT-LENGTH")
+	assert.Equal(t, "", h.Lookup("Content-Length"))
+	assert.Len(t, h, 1)
 }
@@ -0,0 +1,103 @@
+package headers
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// 시작 줄(request line, status line)과 헤더 필드들을 합친 헤더 블록 하나의 최대 크기 (trailer 블록도 같은 제한)
+const MaxHeaderBytes = 1 << 20
+
+// 헤더 블록 하나에 들어갈 수 있는 최대 필드 수
+const MaxFields = 500
+
+var ErrTooLarge = errors.New("header section exceeds the size or field count limit")
+
+// LineReader가 읽은 헤더 블록의 형식이 틀렸을 때의 에러 (I/O 에러와 구분할 때 errors.Is로 확인)
+// @@@ ReadHeaders는 Parse의 에러(ErrInvalidName 등)를 이 에러로 감싸서 반환하므로 둘 다 errors.Is로 찾을 수 있다
+var ErrMalformed = errors.New("malformed header section")
+var ErrBareLF = fmt.Errorf("%w: line must end with CRLF, not a bare LF", ErrMalformed)
+
+// bufio.Reader에서 CRLF로 끝나는 줄을 읽는 구조체 (request, response 파서와 chunked body가 같이 쓴다)
+// Reset 이후로 읽은 바이트가 제한을 넘으면 ErrTooLarge를 반환한다
+// @@@ 제한이 없으면 CRLF 없이 계속 들어오는 줄 하나로 메모리를 다 쓰게 만들 수 있다
+type LineReader struct {
+	src       *bufio.Reader
+	remaining int
+	fields    int
+	line      []byte // bufio.Reader 버퍼에 다 들어가지 않는 줄을 이어붙이는 버퍼 (필요할 때만 할당)
+}
+
+// src에서 새 헤더 블록을 limit 바이트까지 읽도록 초기화하는 메소드
+func (l *LineReader) Reset(src *bufio.Reader, limit int) {
+	l.src = src
+	l.remaining = limit
+	l.fields = 0
+	l.line = l.line[:0]
+}
+
+// CRLF로 끝나는 줄 하나를 읽어 CRLF까지 포함해서 반환하는 메소드
+// @@@ 줄 전체가 bufio.Reader의 버퍼 안에 있으면 복사 없이 버퍼를 가리키는 slice를 반환하므로 다음 읽기 전까지만 쓸 수 있다
+// 줄을 읽기 전에 src가 끝나면 io.EOF, 줄 중간에 끝나면 io.ErrUnexpectedEOF
+// CR 없이 LF로 끝나는 줄은 ErrBareLF (RFC 9112 2.2, 받는 쪽마다 줄 경계를 다르게 보지 않도록 거절)
+func (l *LineReader) ReadLine() ([]byte, error) {
+	l.line = l.line[:0]
+
+	for {
+		part, err := l.src.ReadSlice('\n')
+		l.remaining -= len(part)
+		if l.remaining < 0 {
+			return nil, ErrTooLarge
+		}
+
+		if err == nil && len(l.line) == 0 {
+			if !bytes.HasSuffix(part, []byte(crlf)) {
+				return nil, ErrBareLF
+			}
+			return part, nil
+		}
+
+		l.line = append(l.line, part...)
+		if err == nil {
+			if !bytes.HasSuffix(l.line, []byte(crlf)) {
+				return nil, ErrBareLF
+			}
+			return l.line, nil
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if errors.Is(err, io.EOF) && len(l.line) > 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		return nil, err
+	}
+}
+
+// 빈 줄이 나올 때까지 헤더 줄들을 읽어서 h에 넣는 메소드 (trailer 블록에도 사용)
+// 필드 수가 MaxFields를 넘으면 ErrTooLarge
+func (l *LineReader) ReadHeaders(h Headers) error {
+	for {
+		line, err := l.ReadLine()
+		if err != nil {
+			return err
+		}
+
+		_, done, err := h.Parse(line)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrMalformed, err)
+		}
+		if done {
+			return nil
+		}
+
+		l.fields++
+		if l.fields > MaxFields {
+			return ErrTooLarge
+		}
+	}
+}
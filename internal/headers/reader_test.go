@@ -0,0 +1,63 @@
+package headers
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLineReader(t *testing.T) {
+	// Test: bufio.Reader 버퍼보다 긴 줄도 CRLF까지 포함해서 읽는다
+	long := strings.Repeat("a", 100)
+	var l LineReader
+	l.Reset(bufio.NewReaderSize(strings.NewReader(long+"\r\nnext\r\n"), 16), MaxHeaderBytes)
+	line, err := l.ReadLine()
+	require.NoError(t, err)
+	assert.Equal(t, long+"\r\n", string(line))
+	line, err = l.ReadLine()
+	require.NoError(t, err)
+	assert.Equal(t, "next\r\n", string(line))
+	_, err = l.ReadLine()
+	assert.ErrorIs(t, err, io.EOF)
+
+	// Test: 줄 중간에 끝나면 io.ErrUnexpectedEOF
+	l.Reset(bufio.NewReader(strings.NewReader("partial")), MaxHeaderBytes)
+	_, err = l.ReadLine()
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	// Test: CR 없이 LF로 끝나는 줄은 거절
+	l.Reset(bufio.NewReader(strings.NewReader("Host: x\n")), MaxHeaderBytes)
+	_, err = l.ReadLine()
+	assert.ErrorIs(t, err, ErrBareLF)
+	assert.ErrorIs(t, err, ErrMalformed)
+
+	// Test: 제한을 넘는 줄은 CRLF가 오기 전에 ErrTooLarge
+	l.Reset(bufio.NewReaderSize(strings.NewReader(strings.Repeat("a", 1000)), 16), 64)
+	_, err = l.ReadLine()
+	assert.ErrorIs(t, err, ErrTooLarge)
+}
+
+func TestLineReaderReadHeaders(t *testing.T) {
+	var l LineReader
+
+	h := NewHeaders()
+	l.Reset(bufio.NewReader(strings.NewReader("Host: localhost\r\nAccept: */*\r\n\r\nbody")), MaxHeaderBytes)
+	require.NoError(t, l.ReadHeaders(h))
+	assert.Equal(t, "localhost", h.Get("host"))
+	assert.Equal(t, "*/*", h.Get("accept"))
+
+	// Test: Parse 에러는 ErrMalformed로 감싼다
+	l.Reset(bufio.NewReader(strings.NewReader("Bad Name: x\r\n\r\n")), MaxHeaderBytes)
+	err := l.ReadHeaders(NewHeaders())
+	assert.ErrorIs(t, err, ErrMalformed)
+	assert.ErrorIs(t, err, ErrInvalidName)
+
+	// Test: 필드 수가 MaxFields를 넘으면 ErrTooLarge
+	many := strings.Repeat("X-A: b\r\n", MaxFields+1) + "\r\n"
+	l.Reset(bufio.NewReader(strings.NewReader(many)), MaxHeaderBytes)
+	assert.ErrorIs(t, l.ReadHeaders(NewHeaders()), ErrTooLarge)
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"bytes"
+	"log"
+	"strconv"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+)
+
+// Prometheus text exposition format의 Content-Type
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// 레지스트리의 메트릭들을 response로 보내는 handler를 반환하는 함수
+// 반환값은 server.Handler로 그대로 쓸 수 있다
+// @@@ server 패키지가 metrics를 import하므로 순환 import를 피하려고 함수 타입으로 반환
+func Handler(r *Registry) func(w *response.Writer, req *request.Request) {
+	return func(w *response.Writer, req *request.Request) {
+		body := &bytes.Buffer{}
+		err := r.WriteText(body)
+		if err != nil {
+			log.Printf("error writing metrics: %v", err)
+			return
+		}
+
+		err = w.WriteStatusLine(response.StatusOK)
+		if err != nil {
+			log.Printf("error writing status line: %v", err)
+			return
+		}
+
+		h := headers.NewHeaders()
+		h.SetOverride("Content-Length", strconv.Itoa(body.Len()))
+		h.SetOverride("Content-Type", ContentType)
+
+		err = w.WriteHeaders(h)
+		if err != nil {
+			log.Printf("error writing headers: %v", err)
+			return
+		}
+
+		_, err = w.WriteBody(body.Bytes())
+		if err != nil {
+			log.Printf("error writing body: %v", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,377 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// handler 처리 시간 등에 쓰는 기본 histogram 버킷 (초 단위)
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// 메트릭 종류 (Prometheus text exposition format의 # TYPE 값)
+type metricType string
+
+const (
+	typeCounter   metricType = "counter"
+	typeGauge     metricType = "gauge"
+	typeHistogram metricType = "histogram"
+)
+
+// 레지스트리에 등록되어 텍스트로 출력될 수 있는 메트릭 묶음 (이름 하나에 label 조합별 series 여러개)
+type family struct {
+	name       string
+	help       string
+	typ        metricType
+	labelNames []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	series map[string]*series // key: label 값들을 \xff로 이은 문자열
+}
+
+// label 값 조합 하나에 해당하는 시계열
+type series struct {
+	labelValues []string
+	counter     *Counter
+	gauge       *Gauge
+	histogram   *Histogram
+}
+
+// 메트릭들을 모아두고 Prometheus 텍스트 형식으로 출력하는 레지스트리
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+// 빈 Registry 인스턴스 생성하는 함수
+func NewRegistry() *Registry {
+	return &Registry{
+		families: make(map[string]*family),
+	}
+}
+
+// 새 메트릭 묶음을 등록하는 메소드
+// 같은 이름이 이미 등록되어 있으면 프로그래밍 실수이므로 panic
+func (r *Registry) register(name, help string, typ metricType, buckets []float64, labelNames []string) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.families[name]; ok {
+		panic(fmt.Sprintf("metrics: %q is already registered", name))
+	}
+
+	f := &family{
+		name:       name,
+		help:       help,
+		typ:        typ,
+		labelNames: labelNames,
+		buckets:    buckets,
+		series:     make(map[string]*series),
+	}
+	r.families[name] = f
+
+	return f
+}
+
+// label 값 조합에 해당하는 series를 찾고, 없으면 만들어서 반환하는 메소드
+func (f *family) with(labelValues []string) *series {
+	if len(labelValues) != len(f.labelNames) {
+		panic(fmt.Sprintf("metrics: %q expects %d label values, got %d", f.name, len(f.labelNames), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, ok := f.series[key]
+	if ok {
+		return s
+	}
+
+	s = &series{labelValues: append([]string{}, labelValues...)}
+	switch f.typ {
+	case typeCounter:
+		s.counter = &Counter{}
+	case typeGauge:
+		s.gauge = &Gauge{}
+	case typeHistogram:
+		s.histogram = newHistogram(f.buckets)
+	}
+	f.series[key] = s
+
+	return s
+}
+
+// label 없는 counter를 등록하고 반환하는 메소드
+func (r *Registry) NewCounter(name, help string) *Counter {
+	return r.register(name, help, typeCounter, nil, nil).with(nil).counter
+}
+
+// label이 있는 counter 묶음을 등록하고 반환하는 메소드
+func (r *Registry) NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	return &CounterVec{family: r.register(name, help, typeCounter, nil, labelNames)}
+}
+
+// label 없는 gauge를 등록하고 반환하는 메소드
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	return r.register(name, help, typeGauge, nil, nil).with(nil).gauge
+}
+
+// label 없는 histogram을 등록하고 반환하는 메소드 (buckets가 nil이면 DefBuckets 사용)
+func (r *Registry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	return r.register(name, help, typeHistogram, normalizeBuckets(buckets), nil).with(nil).histogram
+}
+
+// label이 있는 histogram 묶음을 등록하고 반환하는 메소드 (buckets가 nil이면 DefBuckets 사용)
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	return &HistogramVec{family: r.register(name, help, typeHistogram, normalizeBuckets(buckets), labelNames)}
+}
+
+// 버킷 경계값을 정렬된 복사본으로 만드는 함수
+func normalizeBuckets(buckets []float64) []float64 {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+
+	sorted := append([]float64{}, buckets...)
+	sort.Float64s(sorted)
+
+	return sorted
+}
+
+// 증가만 하는 값
+type Counter struct {
+	bits atomic.Uint64 // float64 값을 비트로 저장
+}
+
+// 1 증가시키는 메소드
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// v만큼 증가시키는 메소드 (counter는 감소할 수 없으므로 음수는 무시)
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		return
+	}
+	addFloat(&c.bits, v)
+}
+
+// 현재 값을 반환하는 메소드
+func (c *Counter) Value() float64 {
+	return math.Float64frombits(c.bits.Load())
+}
+
+// 오르내릴 수 있는 값
+type Gauge struct {
+	bits atomic.Uint64
+}
+
+// 값을 v로 지정하는 메소드
+func (g *Gauge) Set(v float64) {
+	g.bits.Store(math.Float64bits(v))
+}
+
+// 1 증가시키는 메소드
+func (g *Gauge) Inc() {
+	addFloat(&g.bits, 1)
+}
+
+// 1 감소시키는 메소드
+func (g *Gauge) Dec() {
+	addFloat(&g.bits, -1)
+}
+
+// v만큼 더하는 메소드
+func (g *Gauge) Add(v float64) {
+	addFloat(&g.bits, v)
+}
+
+// 현재 값을 반환하는 메소드
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(g.bits.Load())
+}
+
+// 관측값들을 버킷별로 세는 histogram
+type Histogram struct {
+	mu     sync.Mutex
+	bounds []float64 // 각 버킷의 상한 (le), 오름차순
+	counts []uint64  // 각 버킷에 들어간 관측 수 (누적 아님)
+	sum    float64
+	count  uint64
+}
+
+func newHistogram(bounds []float64) *Histogram {
+	return &Histogram{
+		bounds: bounds,
+		counts: make([]uint64, len(bounds)),
+	}
+}
+
+// 관측값 하나를 기록하는 메소드
+func (h *Histogram) Observe(v float64) {
+	// v <= 상한 인 첫번째 버킷 (없으면 +Inf 버킷에만 포함)
+	i := sort.SearchFloat64s(h.bounds, v)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.sum += v
+	h.count++
+}
+
+// 누적 버킷 값, 합, 전체 개수를 반환하는 메소드
+func (h *Histogram) snapshot() ([]uint64, float64, uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	cumulative := make([]uint64, len(h.counts))
+	var total uint64
+	for i, c := range h.counts {
+		total += c
+		cumulative[i] = total
+	}
+
+	return cumulative, h.sum, h.count
+}
+
+// label 값에 따라 나뉘는 counter 묶음
+type CounterVec struct {
+	family *family
+}
+
+// 주어진 label 값들(등록할 때의 label 이름 순서)에 해당하는 counter를 반환하는 메소드
+func (v *CounterVec) WithLabelValues(values ...string) *Counter {
+	return v.family.with(values).counter
+}
+
+// label 값에 따라 나뉘는 histogram 묶음
+type HistogramVec struct {
+	family *family
+}
+
+// 주어진 label 값들에 해당하는 histogram을 반환하는 메소드
+func (v *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	return v.family.with(values).histogram
+}
+
+// float64 비트로 저장된 값에 v를 원자적으로 더하는 함수
+func addFloat(bits *atomic.Uint64, v float64) {
+	for {
+		old := bits.Load()
+		next := math.Float64bits(math.Float64frombits(old) + v)
+		if bits.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+// 등록된 모든 메트릭을 Prometheus text exposition format(0.0.4)으로 w에 쓰는 메소드
+// 메트릭 이름, label 값 순으로 정렬해서 출력
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].name < families[j].name
+	})
+
+	sb := &strings.Builder{}
+	for _, f := range families {
+		f.writeText(sb)
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// 메트릭 묶음 하나를 텍스트 형식으로 sb에 쓰는 메소드
+func (f *family) writeText(sb *strings.Builder) {
+	f.mu.Lock()
+	all := make([]*series, 0, len(f.series))
+	for _, s := range f.series {
+		all = append(all, s)
+	}
+	f.mu.Unlock()
+
+	sort.Slice(all, func(i, j int) bool {
+		return strings.Join(all[i].labelValues, "\xff") < strings.Join(all[j].labelValues, "\xff")
+	})
+
+	fmt.Fprintf(sb, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(sb, "# TYPE %s %s\n", f.name, f.typ)
+
+	for _, s := range all {
+		labels := formatLabels(f.labelNames, s.labelValues)
+
+		switch f.typ {
+		case typeCounter:
+			fmt.Fprintf(sb, "%s%s %s\n", f.name, labels, formatFloat(s.counter.Value()))
+		case typeGauge:
+			fmt.Fprintf(sb, "%s%s %s\n", f.name, labels, formatFloat(s.gauge.Value()))
+		case typeHistogram:
+			cumulative, sum, count := s.histogram.snapshot()
+			for i, bound := range f.buckets {
+				le := formatLabels(slices.Concat(f.labelNames, []string{"le"}), slices.Concat(s.labelValues, []string{formatFloat(bound)}))
+				fmt.Fprintf(sb, "%s_bucket%s %d\n", f.name, le, cumulative[i])
+			}
+			le := formatLabels(slices.Concat(f.labelNames, []string{"le"}), slices.Concat(s.labelValues, []string{"+Inf"}))
+			fmt.Fprintf(sb, "%s_bucket%s %d\n", f.name, le, count)
+			fmt.Fprintf(sb, "%s_sum%s %s\n", f.name, labels, formatFloat(sum))
+			fmt.Fprintf(sb, "%s_count%s %d\n", f.name, labels, count)
+		}
+	}
+}
+
+// {name="value",...} 형태의 label 문자열을 만드는 함수 (label이 없으면 "")
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + `="` + escapeLabelValue(values[i]) + `"`
+	}
+
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// label 값 안의 \, ", 줄바꿈 escape
+func escapeLabelValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+// HELP 문자열 안의 \, 줄바꿈 escape
+func escapeHelp(v string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(v)
+}
+
+// 메트릭 값을 텍스트 형식에 맞게 변환하는 함수
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteText(t *testing.T) {
+	reg := NewRegistry()
+
+	requests := reg.NewCounterVec("requests_total", "Handled requests.", "method", "status")
+	requests.WithLabelValues("GET", "200").Inc()
+	requests.WithLabelValues("GET", "200").Inc()
+	requests.WithLabelValues("POST", "400").Add(3)
+
+	active := reg.NewGauge("active", "Active \\ connections\nnow.")
+	active.Inc()
+	active.Inc()
+	active.Dec()
+
+	latency := reg.NewHistogram("latency_seconds", "Latency.", []float64{1, 0.1})
+	latency.Observe(0.05)
+	latency.Observe(0.5)
+	latency.Observe(3)
+
+	out := &bytes.Buffer{}
+	require.NoError(t, reg.WriteText(out))
+
+	// Test: 이름 순으로 정렬, HELP escape, histogram 누적 버킷
+	expected := `# HELP active Active \\ connections\nnow.
+# TYPE active gauge
+active 1
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 1
+latency_seconds_bucket{le="1"} 2
+latency_seconds_bucket{le="+Inf"} 3
+latency_seconds_sum 3.55
+latency_seconds_count 3
+# HELP requests_total Handled requests.
+# TYPE requests_total counter
+requests_total{method="GET",status="200"} 2
+requests_total{method="POST",status="400"} 3
+`
+	assert.Equal(t, expected, out.String())
+}
+
+func TestLabelEscapingAndPanics(t *testing.T) {
+	reg := NewRegistry()
+	c := reg.NewCounterVec("escaped_total", "Escaping.", "path")
+	c.WithLabelValues("a\"b\\c\nd").Inc()
+
+	out := &bytes.Buffer{}
+	require.NoError(t, reg.WriteText(out))
+	assert.Contains(t, out.String(), `escaped_total{path="a\"b\\c\nd"} 1`)
+
+	// Test: 중복 등록, label 개수 불일치는 panic
+	assert.Panics(t, func() { reg.NewCounter("escaped_total", "dup") })
+	assert.Panics(t, func() { c.WithLabelValues("a", "b") })
+
+	// Test: counter는 감소하지 않는다
+	counter := reg.NewCounter("monotonic_total", "Monotonic.")
+	counter.Add(-5)
+	assert.Equal(t, float64(0), counter.Value())
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	reg := NewRegistry()
+	c := reg.NewCounter("hits_total", "Hits.")
+	h := reg.NewHistogramVec("sizes", "Sizes.", nil, "kind")
+
+	wg := sync.WaitGroup{}
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 1000 {
+				c.Inc()
+				h.WithLabelValues("a").Observe(0.01)
+				_ = reg.WriteText(&bytes.Buffer{})
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, float64(8000), c.Value())
+}
+
+func TestHandler(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewCounter("hits_total", "Hits.").Inc()
+
+	req, err := request.RequestFromReader(strings.NewReader("GET /metrics HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+
+	w := &response.Writer{}
+	Handler(reg)(w, req)
+
+	data := string(w.Data)
+	assert.True(t, strings.HasPrefix(data, "HTTP/1.1 200 OK\r\n"))
+	assert.Contains(t, data, "Content-Type: "+ContentType+"\r\n")
+	assert.True(t, strings.HasSuffix(data, "hits_total 1\n"))
+}
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/paokimsiwoong/httpfromtcp/internal/server"
+)
+
+// 접근 로그 출력 형식
+type LogFormat int
+
+const (
+	// Apache Common Log Format
+	// ex) 127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET / HTTP/1.1" 200 2326
+	LogFormatCommon LogFormat = iota
+	// Common Log Format 뒤에 "Referer" "User-Agent"가 붙는 Combined Log Format
+	LogFormatCombined
+	// slog.JSONHandler의 한 줄 JSON
+	LogFormatJSON
+)
+
+// 접근 로그 레코드에 쓰이는 attribute key들
+const (
+	KeyMethod     = "method"
+	KeyTarget     = "target"
+	KeyProto      = "proto"
+	KeyStatus     = "status"
+	KeyBytes      = "bytes"
+	KeyDuration   = "duration"
+	KeyRemoteAddr = "remote_addr"
+	KeyUserAgent  = "user_agent"
+	KeyReferer    = "referer"
+)
+
+// CLF의 시간 표기 형식
+const clfTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+// 주어진 형식으로 out에 접근 로그를 쓰는 slog.Logger를 만드는 함수
+func NewAccessLogger(out io.Writer, format LogFormat) *slog.Logger {
+	switch format {
+	case LogFormatJSON:
+		return slog.New(slog.NewJSONHandler(out, nil))
+	case LogFormatCombined:
+		return slog.New(&clfHandler{out: out, mu: &sync.Mutex{}, combined: true})
+	default:
+		return slog.New(&clfHandler{out: out, mu: &sync.Mutex{}})
+	}
+}
+
+// request 하나가 처리될 때마다 logger로 접근 로그를 남기는 middleware
+// method, target, status, 보낸 body 바이트 수, 처리 시간, client 주소, User-Agent를 기록
+func AccessLog(logger *slog.Logger) Middleware {
+	return func(next server.Handler) server.Handler {
+		return func(w *response.Writer, req *request.Request) {
+			start := time.Now()
+
+			next(w, req)
+
+			logger.LogAttrs(context.Background(), slog.LevelInfo, "request",
+				slog.String(KeyMethod, req.RequestLine.Method),
+				slog.String(KeyTarget, req.RequestLine.RequestTarget),
+				slog.String(KeyProto, "HTTP/"+req.RequestLine.HttpVersion),
+				slog.Int(KeyStatus, int(w.StatusCode())),
+				slog.Int(KeyBytes, w.BytesWritten()),
+				slog.Duration(KeyDuration, time.Since(start)),
+				slog.String(KeyRemoteAddr, req.RemoteAddr),
+				slog.String(KeyUserAgent, req.Headers.Get("User-Agent")),
+				slog.String(KeyReferer, req.Headers.Get("Referer")),
+			)
+		}
+	}
+}
+
+// slog 레코드를 Common/Combined Log Format 한 줄로 출력하는 slog.Handler
+type clfHandler struct {
+	out      io.Writer
+	mu       *sync.Mutex // WithAttrs로 복사된 handler들도 같은 out에 쓰므로 포인터로 공유
+	combined bool
+	attrs    []slog.Attr
+}
+
+func (h *clfHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= slog.LevelInfo
+}
+
+func (h *clfHandler) Handle(_ context.Context, r slog.Record) error {
+	fields := map[string]slog.Value{}
+	for _, a := range h.attrs {
+		fields[a.Key] = a.Value
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		fields[a.Key] = a.Value
+		return true
+	})
+
+	// 값이 없으면 CLF 관례대로 "-"
+	get := func(key string) string {
+		v, ok := fields[key]
+		if !ok || v.String() == "" {
+			return "-"
+		}
+		return v.String()
+	}
+
+	host := get(KeyRemoteAddr)
+	if ip, _, err := net.SplitHostPort(host); err == nil {
+		host = ip
+	}
+
+	bytes := get(KeyBytes)
+	if bytes == "0" {
+		bytes = "-"
+	}
+
+	line := fmt.Sprintf("%s - - [%s] \"%s %s %s\" %s %s",
+		host,
+		r.Time.Format(clfTimeLayout),
+		get(KeyMethod), get(KeyTarget), get(KeyProto),
+		get(KeyStatus),
+		bytes,
+	)
+
+	if h.combined {
+		line += fmt.Sprintf(" %q %q", get(KeyReferer), get(KeyUserAgent))
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	_, err := io.WriteString(h.out, line+"\n")
+	return err
+}
+
+func (h *clfHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+	return &clone
+}
+
+// CLF는 한 줄 형식이 고정되어 있어서 group은 무시하고 key 이름만 사용
+func (h *clfHandler) WithGroup(_ string) slog.Handler {
+	return h
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/paokimsiwoong/httpfromtcp/internal/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 테스트용 request 생성 함수
+func newRequest(t *testing.T, raw string) *request.Request {
+	t.Helper()
+
+	req, err := request.RequestFromReader(strings.NewReader(raw))
+	require.NoError(t, err)
+	req.RemoteAddr = "192.0.2.7:51234"
+
+	return req
+}
+
+// 주어진 status code와 body를 보내는 handler
+func staticHandler(statusCode response.StatusCode, body string) server.Handler {
+	return func(w *response.Writer, req *request.Request) {
+		_ = w.WriteStatusLine(statusCode)
+		h := headers.NewHeaders()
+		h.SetOverride("Content-Length", strconv.Itoa(len(body)))
+		_ = w.WriteHeaders(h)
+		_, _ = w.WriteBody([]byte(body))
+	}
+}
+
+const testRequest = "GET /coffee HTTP/1.1\r\nHost: localhost\r\nUser-Agent: curl/8.5.0\r\nReferer: http://example.com/\r\n\r\n"
+
+func TestAccessLogCommon(t *testing.T) {
+	out := &bytes.Buffer{}
+	handler := Chain(staticHandler(response.StatusOK, "hello"), AccessLog(NewAccessLogger(out, LogFormatCommon)))
+
+	handler(&response.Writer{}, newRequest(t, testRequest))
+
+	line := out.String()
+	assert.True(t, strings.HasPrefix(line, "192.0.2.7 - - ["), line)
+	assert.True(t, strings.HasSuffix(line, "] \"GET /coffee HTTP/1.1\" 200 5\n"), line)
+}
+
+func TestAccessLogCombined(t *testing.T) {
+	out := &bytes.Buffer{}
+	handler := Chain(staticHandler(response.StatusBadRequest, ""), AccessLog(NewAccessLogger(out, LogFormatCombined)))
+
+	handler(&response.Writer{}, newRequest(t, testRequest))
+
+	// Test: body가 없으면 바이트 수는 "-"
+	assert.True(t, strings.HasSuffix(out.String(), "\"GET /coffee HTTP/1.1\" 400 - \"http://example.com/\" \"curl/8.5.0\"\n"), out.String())
+}
+
+func TestAccessLogJSON(t *testing.T) {
+	out := &bytes.Buffer{}
+	handler := Chain(staticHandler(response.StatusOK, "hello"), AccessLog(NewAccessLogger(out, LogFormatJSON)))
+
+	handler(&response.Writer{}, newRequest(t, testRequest))
+
+	record := map[string]any{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &record))
+	assert.Equal(t, "GET", record[KeyMethod])
+	assert.Equal(t, "/coffee", record[KeyTarget])
+	assert.Equal(t, float64(200), record[KeyStatus])
+	assert.Equal(t, float64(5), record[KeyBytes])
+	assert.Equal(t, "192.0.2.7:51234", record[KeyRemoteAddr])
+	assert.Equal(t, "curl/8.5.0", record[KeyUserAgent])
+	assert.Contains(t, record, KeyDuration)
+}
+
+func TestChainOrder(t *testing.T) {
+	order := []string{}
+	mark := func(name string) Middleware {
+		return func(next server.Handler) server.Handler {
+			return func(w *response.Writer, req *request.Request) {
+				order = append(order, name)
+				next(w, req)
+			}
+		}
+	}
+
+	handler := Chain(func(w *response.Writer, req *request.Request) {
+		order = append(order, "handler")
+	}, mark("A"), mark("B"))
+	handler(&response.Writer{}, newRequest(t, testRequest))
+
+	assert.Equal(t, []string{"A", "B", "handler"}, order)
+}
@@ -0,0 +1,280 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/paokimsiwoong/httpfromtcp/internal/server"
+)
+
+// 압축 middleware 설정
+type compressConfig struct {
+	level   int
+	minSize int
+}
+
+// Compress middleware의 설정을 바꾸는 함수 타입
+type CompressOption func(*compressConfig)
+
+// 압축 레벨을 정하는 옵션 (gzip.BestSpeed ~ gzip.BestCompression, 기본값 gzip.DefaultCompression)
+func WithCompressionLevel(level int) CompressOption {
+	return func(c *compressConfig) {
+		c.level = level
+	}
+}
+
+// Content-Length가 n 바이트보다 작은 body는 압축하지 않도록 하는 옵션 (기본값 1024)
+// @@@ 작은 body는 압축해도 gzip 헤더 등 때문에 오히려 커지거나 줄어드는 양이 적다
+func WithMinSize(n int) CompressOption {
+	return func(c *compressConfig) {
+		c.minSize = n
+	}
+}
+
+// 이미 압축되어 있어서 다시 압축해도 줄지 않는 media type들
+var incompressibleTypes = []string{
+	"image/", "video/", "audio/", "font/woff",
+	"application/zip", "application/gzip", "application/x-gzip", "application/zstd",
+	"application/x-bzip2", "application/x-xz", "application/x-7z-compressed", "application/x-rar-compressed",
+	"application/pdf", "application/wasm",
+}
+
+// 압축해도 되는 media type인지 확인하는 함수 (SVG는 텍스트이므로 image/여도 압축)
+func compressible(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+
+	if mediaType == "image/svg+xml" {
+		return true
+	}
+
+	for _, t := range incompressibleTypes {
+		if strings.HasPrefix(mediaType, t) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Accept-Encoding에 따라 response body를 gzip 또는 deflate로 압축하는 middleware
+// 압축할 때는 Content-Encoding을 붙이고, 작은 body는 Content-Length와 함께, 길이를 알 수 없는 body는 chunked로 보낸다
+// 다음 response들은 압축하지 않는다
+// - HEAD, CONNECT request, body가 없는 status code, 206 Partial Content
+// - 이미 Content-Encoding이 있거나 Cache-Control: no-transform인 response
+// - 이미 압축된 media type (이미지, 영상, zip 등), minSize보다 작은 body
+// 압축할 수 있는 response에는 client가 압축을 받지 않더라도 Vary: Accept-Encoding을 붙인다 (캐시용)
+func Compress(opts ...CompressOption) Middleware {
+	cfg := &compressConfig{
+		level:   gzip.DefaultCompression,
+		minSize: 1024,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	pools := newEncoderPools(cfg.level)
+
+	return func(next server.Handler) server.Handler {
+		return func(w *response.Writer, req *request.Request) {
+			// @@@ HEAD는 body가 없어서 압축 결과 길이를 알 수 없으므로 그대로 보낸다
+			// @@@ CONNECT의 2xx 뒤에는 body 대신 터널 데이터가 오가므로 건드리면 안 된다
+			if req.RequestLine.Method == "HEAD" || req.RequestLine.Method == "CONNECT" {
+				next(w, req)
+				return
+			}
+
+			coding := negotiateEncoding(req.Headers.Get("Accept-Encoding"))
+
+			err := w.SetEncoder(func(code response.StatusCode, h headers.Headers, dst io.Writer) response.Encoder {
+				if !shouldCompress(code, h, cfg.minSize) {
+					return nil
+				}
+
+				addVary(h, "Accept-Encoding")
+
+				if coding == "" {
+					return nil
+				}
+
+				h.SetOverride("Content-Encoding", coding)
+				weakenETag(h)
+
+				return pools.get(coding, dst)
+			})
+			if err != nil {
+				next(w, req)
+				return
+			}
+
+			next(w, req)
+
+			_ = w.FinishEncoding()
+		}
+	}
+}
+
+// status code와 헤더를 보고 압축 대상인지 확인하는 함수
+func shouldCompress(code response.StatusCode, h headers.Headers, minSize int) bool {
+	if code < 200 || code == 204 || code == response.StatusNotModified || code == response.StatusPartialContent {
+		return false
+	}
+
+	if h.Lookup("Content-Encoding") != "" || h.Lookup("Content-Range") != "" {
+		return false
+	}
+
+	if strings.Contains(strings.ToLower(h.Lookup("Cache-Control")), "no-transform") {
+		return false
+	}
+
+	if !compressible(h.Lookup("Content-Type")) {
+		return false
+	}
+
+	if length, err := strconv.Atoi(h.Lookup("Content-Length")); err == nil && length < minSize {
+		return false
+	}
+
+	return true
+}
+
+// Accept-Encoding 헤더에서 gzip, deflate 중 client가 가장 선호하는 것을 고르는 함수
+// ex) "gzip;q=0.8, deflate" => "deflate", "br" => "", "*" => "gzip"
+// q 값이 같으면 gzip 우선, q=0은 받지 않겠다는 뜻
+func negotiateEncoding(acceptEncoding string) string {
+	q := map[string]float64{}
+	wildcard := -1.0
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+
+		weight := 1.0
+		for _, param := range strings.Split(params, ";") {
+			value, ok := strings.CutPrefix(strings.ToLower(strings.TrimSpace(param)), "q=")
+			if !ok {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				parsed = 0
+			}
+			weight = parsed
+		}
+
+		if coding == "*" {
+			wildcard = weight
+			continue
+		}
+		// @@@ x-gzip은 gzip과 같은 것으로 취급 (RFC 9110 8.4.1.3)
+		if coding == "x-gzip" {
+			coding = "gzip"
+		}
+		q[coding] = weight
+	}
+
+	best, bestQ := "", 0.0
+	for _, coding := range []string{"gzip", "deflate"} {
+		weight, ok := q[coding]
+		if !ok {
+			weight = wildcard
+		}
+		if weight > bestQ {
+			best, bestQ = coding, weight
+		}
+	}
+
+	return best
+}
+
+// Vary 헤더에 값을 추가하는 함수 (이미 있거나 Vary: *이면 그대로)
+func addVary(h headers.Headers, value string) {
+	for key, vary := range h {
+		if !strings.EqualFold(key, "Vary") {
+			continue
+		}
+		for _, v := range strings.Split(vary, ",") {
+			v = strings.TrimSpace(v)
+			if v == "*" || strings.EqualFold(v, value) {
+				return
+			}
+		}
+		h[key] = vary + ", " + value
+		return
+	}
+
+	h.SetOverride("Vary", value)
+}
+
+// strong ETag를 weak ETag로 바꾸는 함수
+// @@@ 압축된 body는 원래 body와 바이트가 다르므로 같은 strong ETag를 쓰면 안 된다
+// @@@ weak로 바꾸면 If-None-Match(weak 비교)로 304는 계속 받을 수 있다
+func weakenETag(h headers.Headers) {
+	for key, etag := range h {
+		if strings.EqualFold(key, "ETag") && strings.HasPrefix(etag, `"`) {
+			h[key] = "W/" + etag
+		}
+	}
+}
+
+// gzip.Writer, zlib.Writer는 만들 때 메모리를 많이 쓰므로 재사용한다
+type encoderPools struct {
+	gzip    sync.Pool
+	deflate sync.Pool
+}
+
+func newEncoderPools(level int) *encoderPools {
+	p := &encoderPools{}
+	p.gzip.New = func() any {
+		zw, err := gzip.NewWriterLevel(io.Discard, level)
+		if err != nil {
+			zw = gzip.NewWriter(io.Discard)
+		}
+		return zw
+	}
+	// @@@ HTTP의 deflate는 raw DEFLATE(compress/flate)가 아니라 zlib 형식(RFC 1950)으로 감싼 DEFLATE (RFC 9110 8.4.1.2)
+	p.deflate.New = func() any {
+		zw, err := zlib.NewWriterLevel(io.Discard, level)
+		if err != nil {
+			zw = zlib.NewWriter(io.Discard)
+		}
+		return zw
+	}
+	return p
+}
+
+// coding에 맞는 Encoder를 pool에서 꺼내 dst로 쓰도록 하는 메소드
+func (p *encoderPools) get(coding string, dst io.Writer) response.Encoder {
+	if coding == "deflate" {
+		zw := p.deflate.Get().(*zlib.Writer)
+		zw.Reset(dst)
+		return &pooledEncoder{Encoder: zw, pool: &p.deflate}
+	}
+
+	zw := p.gzip.Get().(*gzip.Writer)
+	zw.Reset(dst)
+	return &pooledEncoder{Encoder: zw, pool: &p.gzip}
+}
+
+// Close 때 pool로 돌아가는 Encoder
+type pooledEncoder struct {
+	response.Encoder
+	pool *sync.Pool
+}
+
+func (e *pooledEncoder) Close() error {
+	err := e.Encoder.Close()
+	e.pool.Put(e.Encoder)
+	return err
+}
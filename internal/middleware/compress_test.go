@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/paokimsiwoong/httpfromtcp/internal/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var longBody = strings.Repeat("an absolute banger of a response body. ", 200)
+
+// Content-Type과 Content-Length를 붙여서 body를 보내는 handler
+func typedHandler(contentType, body string) server.Handler {
+	return func(w *response.Writer, req *request.Request) {
+		_ = w.WriteStatusLine(response.StatusOK)
+		h := headers.NewHeaders()
+		h.SetOverride("Content-Length", strconv.Itoa(len(body)))
+		h.SetOverride("Content-Type", contentType)
+		h.SetOverride("ETag", `"abc"`)
+		_ = w.WriteHeaders(h)
+		_, _ = w.WriteBody([]byte(body))
+	}
+}
+
+// 길이를 모르는 body를 Write로 나눠 보내는 handler
+func streamHandler(body string, parts int) server.Handler {
+	return func(w *response.Writer, req *request.Request) {
+		_ = w.WriteStatusLine(response.StatusOK)
+		h := headers.NewHeaders()
+		h.SetOverride("Content-Type", "text/plain")
+		h.SetOverride("Connection", "close")
+		_ = w.WriteHeaders(h)
+		size := len(body) / parts
+		for i := 0; i < parts; i++ {
+			end := (i + 1) * size
+			if i == parts-1 {
+				end = len(body)
+			}
+			_, _ = w.Write([]byte(body[i*size : end]))
+		}
+	}
+}
+
+// middleware를 거친 response를 net/http로 파싱하는 함수 (압축 해제는 하지 않는다)
+func doCompress(t *testing.T, handler server.Handler, acceptEncoding string, opts ...CompressOption) (*http.Response, []byte) {
+	t.Helper()
+
+	raw := "GET / HTTP/1.1\r\nHost: localhost\r\n"
+	if acceptEncoding != "" {
+		raw += "Accept-Encoding: " + acceptEncoding + "\r\n"
+	}
+	raw += "\r\n"
+
+	out := &bytes.Buffer{}
+	w := response.NewWriter(out)
+	Chain(handler, Compress(opts...))(w, newRequest(t, raw))
+	require.NoError(t, w.Flush())
+
+	resp, err := http.ReadResponse(bufio.NewReader(out), nil)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp, body
+}
+
+func gunzip(t *testing.T, data []byte) string {
+	t.Helper()
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	plain, err := io.ReadAll(zr)
+	require.NoError(t, err)
+
+	return string(plain)
+}
+
+func TestCompressGzip(t *testing.T) {
+	resp, body := doCompress(t, typedHandler("text/html", longBody), "gzip, deflate")
+
+	assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", resp.Header.Get("Vary"))
+	assert.Equal(t, `W/"abc"`, resp.Header.Get("ETag"))
+	// Test: 작은 body는 모아서 Content-Length로 보낸다
+	assert.Equal(t, int64(len(body)), resp.ContentLength)
+	assert.Empty(t, resp.TransferEncoding)
+	assert.Less(t, len(body), len(longBody))
+	assert.Equal(t, longBody, gunzip(t, body))
+}
+
+func TestCompressDeflate(t *testing.T) {
+	resp, body := doCompress(t, typedHandler("application/json", longBody), "gzip;q=0.5, deflate")
+
+	assert.Equal(t, "deflate", resp.Header.Get("Content-Encoding"))
+
+	zr, err := zlib.NewReader(bytes.NewReader(body))
+	require.NoError(t, err)
+	plain, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, longBody, string(plain))
+}
+
+func TestCompressChunked(t *testing.T) {
+	big := strings.Repeat(longBody, 20)
+	resp, body := doCompress(t, streamHandler(big, 7), "gzip")
+
+	assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+	assert.Equal(t, []string{"chunked"}, resp.TransferEncoding)
+	assert.Equal(t, big, gunzip(t, body))
+
+	// Test: Content-Length가 커도 chunked로 바뀐다
+	resp, body = doCompress(t, typedHandler("text/plain", big), "gzip")
+	assert.Equal(t, []string{"chunked"}, resp.TransferEncoding)
+	assert.Equal(t, int64(-1), resp.ContentLength)
+	assert.Equal(t, big, gunzip(t, body))
+}
+
+func TestCompressSkipped(t *testing.T) {
+	// Test: Accept-Encoding이 없으면 압축하지 않지만 Vary는 붙는다
+	resp, body := doCompress(t, typedHandler("text/html", longBody), "")
+	assert.Empty(t, resp.Header.Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", resp.Header.Get("Vary"))
+	assert.Equal(t, `"abc"`, resp.Header.Get("ETag"))
+	assert.Equal(t, longBody, string(body))
+
+	// Test: 이미 압축된 media type
+	resp, body = doCompress(t, typedHandler("video/mp4", longBody), "gzip")
+	assert.Empty(t, resp.Header.Get("Content-Encoding"))
+	assert.Empty(t, resp.Header.Get("Vary"))
+	assert.Equal(t, longBody, string(body))
+
+	// Test: 작은 body
+	resp, body = doCompress(t, typedHandler("text/html", "tiny"), "gzip")
+	assert.Empty(t, resp.Header.Get("Content-Encoding"))
+	assert.Equal(t, "tiny", string(body))
+
+	// Test: WithMinSize로 작은 body도 압축
+	resp, body = doCompress(t, typedHandler("text/html", "tiny"), "gzip", WithMinSize(0))
+	assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+	assert.Equal(t, "tiny", gunzip(t, body))
+
+	// Test: 지원하지 않는 encoding만 받는 client
+	resp, _ = doCompress(t, typedHandler("text/html", longBody), "br, gzip;q=0")
+	assert.Empty(t, resp.Header.Get("Content-Encoding"))
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                       "",
+		"gzip":                   "gzip",
+		"deflate, gzip":          "gzip",
+		"gzip;q=0.8, deflate":    "deflate",
+		"GZIP;Q=0.5":             "gzip",
+		"x-gzip":                 "gzip",
+		"*":                      "gzip",
+		"*;q=0.3, gzip;q=0":      "deflate",
+		"br, identity":           "",
+		"gzip;q=0, deflate;q=0":  "",
+		"deflate;q=0.9, *;q=0.1": "deflate",
+	}
+
+	for header, expected := range tests {
+		assert.Equal(t, expected, negotiateEncoding(header), header)
+	}
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/paokimsiwoong/httpfromtcp/internal/server"
+)
+
+// DecompressRequest의 기본 최대 body 크기 (10MiB)
+const DefaultMaxDecodedBody = 10 << 20
+
+// Content-Encoding으로 압축된 request body를 풀어서 handler에 넘기는 middleware
+// 풀린 body가 maxSize 바이트를 넘으면 413, 지원하지 않는 encoding이면 415, 깨진 데이터면 400을 보낸다
+func DecompressRequest(maxSize int64) Middleware {
+	return func(next server.Handler) server.Handler {
+		return func(w *response.Writer, req *request.Request) {
+			err := req.DecodeBody(maxSize)
+
+			switch {
+			case err == nil:
+				next(w, req)
+			case errors.Is(err, request.ErrUnsupportedEncoding):
+				// @@@ 415에 Accept-Encoding을 붙여서 받을 수 있는 encoding을 알려준다 (RFC 9110 15.5.16)
+				h := headers.NewHeaders()
+				h.SetOverride("Accept-Encoding", "gzip, deflate")
+				writePlainError(w, response.StatusUnsupportedMediaType, h)
+			case errors.Is(err, request.ErrBodyTooLarge):
+				writePlainError(w, response.StatusContentTooLarge, nil)
+			default:
+				writePlainError(w, response.StatusBadRequest, nil)
+			}
+		}
+	}
+}
+
+// "<code> <reason>\n" body로 에러 response를 쓰는 함수
+// h는 함께 보낼 헤더 (nil 가능)
+func writePlainError(w *response.Writer, code response.StatusCode, h headers.Headers) {
+	body := strconv.Itoa(int(code)) + " " + response.StatusText(code) + "\n"
+
+	err := w.WriteStatusLine(code)
+	if err != nil {
+		log.Printf("error writing status line: %v", err)
+		return
+	}
+
+	if h == nil {
+		h = headers.NewHeaders()
+	}
+	h.SetOverride("Content-Length", strconv.Itoa(len(body)))
+	h.SetOverride("Content-Type", "text/plain; charset=utf-8")
+
+	err = w.WriteHeaders(h)
+	if err != nil {
+		log.Printf("error writing headers: %v", err)
+		return
+	}
+
+	_, err = w.WriteBody([]byte(body))
+	if err != nil {
+		log.Printf("error writing body: %v", err)
+		return
+	}
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 받은 body를 그대로 돌려보내는 handler
+func echoHandler(w *response.Writer, req *request.Request) {
+	staticHandler(response.StatusOK, string(req.Body))(w, req)
+}
+
+func uploadRequest(t *testing.T, contentEncoding string, body []byte) *request.Request {
+	t.Helper()
+
+	return newRequest(t, "POST /upload HTTP/1.1\r\nHost: localhost\r\n"+
+		"Content-Encoding: "+contentEncoding+"\r\n"+
+		"Content-Length: "+strconv.Itoa(len(body))+"\r\n\r\n"+string(body))
+}
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	_, err := zw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	return buf.Bytes()
+}
+
+func TestDecompressRequest(t *testing.T) {
+	handler := Chain(echoHandler, DecompressRequest(1024))
+
+	// Test: gzip body가 풀려서 handler에 전달
+	w := &response.Writer{}
+	handler(w, uploadRequest(t, "gzip", gzipBytes(t, []byte("hello, upload"))))
+	assert.True(t, strings.HasSuffix(string(w.Data), "\r\n\r\nhello, upload"), string(w.Data))
+
+	// Test: 제한을 넘으면 413
+	w = &response.Writer{}
+	handler(w, uploadRequest(t, "gzip", gzipBytes(t, make([]byte, 4096))))
+	assert.Equal(t, response.StatusContentTooLarge, w.StatusCode())
+
+	// Test: 지원하지 않는 encoding이면 415와 Accept-Encoding
+	w = &response.Writer{}
+	handler(w, uploadRequest(t, "br", []byte("brotli?")))
+	assert.Equal(t, response.StatusUnsupportedMediaType, w.StatusCode())
+	assert.Contains(t, string(w.Data), "Accept-Encoding: gzip, deflate\r\n")
+
+	// Test: 깨진 데이터면 400
+	w = &response.Writer{}
+	handler(w, uploadRequest(t, "gzip", []byte("nope")))
+	assert.Equal(t, response.StatusBadRequest, w.StatusCode())
+}
+
+func TestDecompressRequestUnless(t *testing.T) {
+	proxied := func(req *request.Request) bool { return strings.HasPrefix(req.RequestLine.RequestTarget, "/proxy") }
+	handler := Chain(echoHandler, Unless(proxied, DecompressRequest(1024)))
+	compressed := gzipBytes(t, []byte("hello, upload"))
+
+	// Test: skip이 true인 request는 body를 풀지 않고 그대로 넘긴다
+	req := uploadRequest(t, "gzip", compressed)
+	req.RequestLine.RequestTarget = "/proxy/upload"
+	w := &response.Writer{}
+	handler(w, req)
+	assert.True(t, strings.HasSuffix(string(w.Data), "\r\n\r\n"+string(compressed)), string(w.Data))
+	assert.Equal(t, "gzip", req.Headers.Get("Content-Encoding"))
+
+	// Test: 나머지 request는 middleware를 거친다
+	w = &response.Writer{}
+	handler(w, uploadRequest(t, "gzip", compressed))
+	assert.True(t, strings.HasSuffix(string(w.Data), "\r\n\r\nhello, upload"), string(w.Data))
+}
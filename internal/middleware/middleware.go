@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/paokimsiwoong/httpfromtcp/internal/server"
+)
+
+// handler를 감싸서 앞뒤로 공통 처리를 추가하는 함수 타입
+type Middleware func(server.Handler) server.Handler
+
+// handler에 middleware들을 적용하는 함수
+// 앞에 있는 middleware가 가장 바깥쪽에서 먼저 실행된다
+// ex) Chain(h, A, B) => A(B(h))
+func Chain(handler server.Handler, middlewares ...Middleware) server.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	return handler
+}
+
+// skip이 true를 반환하는 request는 m을 거치지 않고 바로 handler로 보내는 middleware를 반환하는 함수
+// ex) Unless(isProxyRoute, DecompressRequest(DefaultMaxDecodedBody)) => proxy로 가는 request body는 풀지 않는다
+func Unless(skip func(req *request.Request) bool, m Middleware) Middleware {
+	return func(next server.Handler) server.Handler {
+		wrapped := m(next)
+		return func(w *response.Writer, req *request.Request) {
+			if skip(req) {
+				next(w, req)
+				return
+			}
+			wrapped(w, req)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package proxy
+
+import (
+	"context"
+	"hash/fnv"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/client"
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+)
+
+// 요청마다 backend를 고르는 방식
+type Strategy int
+
+const (
+	// backend들을 돌아가며 고른다
+	RoundRobin Strategy = iota
+	// 처리 중인 request가 가장 적은 backend를 고른다
+	LeastConnections
+	// 같은 key(기본값: client IP)의 request는 항상 같은 backend로 보낸다
+	// backend가 빠지거나 돌아와도 다른 key들의 배정은 거의 바뀌지 않는다
+	ConsistentHash
+)
+
+// consistent hash ring에 backend 하나당 올리는 가상 노드 수
+const virtualNodes = 100
+
+// upstream backend 하나의 상태
+type backend struct {
+	url *url.URL
+
+	active  atomic.Int64 // 처리 중인 request 수
+	healthy atomic.Bool  // 마지막 health check 결과 (health check를 안 하면 항상 true)
+
+	mu           sync.Mutex
+	failures     int       // 연속 실패 횟수
+	ejectedUntil time.Time // passive ejection이 풀리는 시각
+}
+
+// request를 받을 수 있는 backend인지 확인하는 메소드
+func (b *backend) available(now time.Time) bool {
+	if !b.healthy.Load() {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return !now.Before(b.ejectedUntil)
+}
+
+// consistent hash ring 위의 점 하나
+type ringPoint struct {
+	hash    uint32
+	backend *backend
+}
+
+// 여러 upstream backend를 묶어서 request마다 하나를 골라주는 pool
+// NewBalanced로 reverse proxy handler를 만들 때 사용한다
+type Pool struct {
+	backends []*backend
+	strategy Strategy
+	next     atomic.Uint64 // round robin 위치
+	ring     []ringPoint   // hash 순으로 정렬된 consistent hash ring
+	hashKey  func(req *request.Request) string
+
+	// 연속으로 maxFails번 실패한 backend는 ejectFor 동안 빼둔다 (passive ejection)
+	maxFails int
+	ejectFor time.Duration
+
+	// 멱등(idempotent) request가 실패하면 다른 backend로 다시 보내는 최대 횟수
+	retries int
+
+	// active health check 설정 (healthPath가 ""이면 하지 않는다)
+	healthPath     string
+	healthInterval time.Duration
+	healthTimeout  time.Duration
+	healthClient   *client.Client
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewPool에 넘겨 pool 설정을 바꾸는 옵션 함수 타입
+type PoolOption func(*Pool)
+
+// backend를 고르는 방식을 정하는 옵션 (기본값 RoundRobin)
+func WithStrategy(strategy Strategy) PoolOption {
+	return func(p *Pool) {
+		p.strategy = strategy
+	}
+}
+
+// ConsistentHash에서 request의 key를 만드는 함수를 정하는 옵션 (기본값: client IP)
+// ex) 세션 쿠키나 사용자 ID 헤더로 같은 사용자를 같은 backend로 보내기
+func WithHashKey(hashKey func(req *request.Request) string) PoolOption {
+	return func(p *Pool) {
+		p.hashKey = hashKey
+	}
+}
+
+// 주기적으로 각 backend의 path로 GET을 보내서 2xx/3xx가 아니면 빼두는 옵션
+// 실패했던 backend도 계속 확인해서 다시 응답하면 돌려놓는다
+func WithHealthCheck(path string, interval, timeout time.Duration) PoolOption {
+	return func(p *Pool) {
+		p.healthPath = path
+		p.healthInterval = interval
+		p.healthTimeout = timeout
+	}
+}
+
+// 연속으로 maxFails번 연결 실패나 502/503/504를 돌려준 backend를 ejectFor 동안 빼두는 옵션
+// (기본값 3번, 30초, maxFails가 0 이하면 빼지 않는다)
+func WithPassiveEjection(maxFails int, ejectFor time.Duration) PoolOption {
+	return func(p *Pool) {
+		p.maxFails = maxFails
+		p.ejectFor = ejectFor
+	}
+}
+
+// GET, PUT 등 멱등 request가 실패했을 때 다른 backend로 다시 보내는 최대 횟수를 정하는 옵션 (기본값 1)
+// @@@ POST, PATCH는 upstream에서 이미 처리됐을 수도 있으므로 다시 보내지 않는다
+func WithRetries(n int) PoolOption {
+	return func(p *Pool) {
+		p.retries = n
+	}
+}
+
+// targets를 backend로 하는 pool을 만드는 함수
+// health check 옵션이 있으면 바로 health check를 시작하므로 다 쓴 뒤 Close를 호출해야 한다
+func NewPool(targets []*url.URL, opts ...PoolOption) *Pool {
+	p := &Pool{
+		strategy: RoundRobin,
+		hashKey:  clientIP,
+		maxFails: 3,
+		ejectFor: 30 * time.Second,
+		retries:  1,
+		stop:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	for _, target := range targets {
+		b := &backend{url: target}
+		b.healthy.Store(true)
+		p.backends = append(p.backends, b)
+	}
+
+	if p.strategy == ConsistentHash {
+		p.buildRing()
+	}
+
+	if p.healthPath != "" && p.healthInterval > 0 {
+		// @@@ client는 redirect를 따라가지 않으므로 3xx 자체를 정상으로 본다
+		p.healthClient = client.New(client.WithMaxIdleConnsPerHost(1))
+		p.wg.Add(1)
+		go p.healthLoop()
+	}
+
+	return p
+}
+
+// health check를 멈추는 메소드
+func (p *Pool) Close() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+	p.wg.Wait()
+}
+
+// 사용 가능한 backend 하나를 고르는 메소드 (tried에 있는 backend는 제외, 없으면 nil)
+func (p *Pool) pick(req *request.Request, tried []*backend) *backend {
+	now := time.Now()
+	usable := func(b *backend) bool {
+		return b.available(now) && !slices.Contains(tried, b)
+	}
+
+	switch p.strategy {
+	case LeastConnections:
+		// @@@ 처리 중인 수가 같으면 round robin 순서로 골라서 한 backend로 몰리지 않게 한다
+		start := int(p.next.Add(1) - 1)
+		var best *backend
+		for i := range p.backends {
+			b := p.backends[(start+i)%len(p.backends)]
+			if usable(b) && (best == nil || b.active.Load() < best.active.Load()) {
+				best = b
+			}
+		}
+		return best
+
+	case ConsistentHash:
+		if len(p.ring) == 0 {
+			return nil
+		}
+		h := hash32(p.hashKey(req))
+		i, _ := slices.BinarySearchFunc(p.ring, h, func(pt ringPoint, h uint32) int {
+			switch {
+			case pt.hash < h:
+				return -1
+			case pt.hash > h:
+				return 1
+			default:
+				return 0
+			}
+		})
+		// ring을 따라 시계 방향으로 사용 가능한 첫 backend
+		for j := range p.ring {
+			b := p.ring[(i+j)%len(p.ring)].backend
+			if usable(b) {
+				return b
+			}
+		}
+		return nil
+
+	default:
+		for range p.backends {
+			b := p.backends[int((p.next.Add(1)-1)%uint64(len(p.backends)))]
+			if usable(b) {
+				return b
+			}
+		}
+		return nil
+	}
+}
+
+// backend가 request를 제대로 처리했는지 기록하는 메소드
+// 연속 실패가 maxFails에 닿으면 ejectFor 동안 빼둔다
+func (p *Pool) report(b *backend, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ok {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if p.maxFails > 0 && b.failures >= p.maxFails {
+		log.Printf("ejecting backend %s for %v after %d failures", b.url, p.ejectFor, b.failures)
+		b.ejectedUntil = time.Now().Add(p.ejectFor)
+		b.failures = 0
+	}
+}
+
+// consistent hash ring을 만드는 메소드
+func (p *Pool) buildRing() {
+	p.ring = make([]ringPoint, 0, len(p.backends)*virtualNodes)
+	for _, b := range p.backends {
+		for i := 0; i < virtualNodes; i++ {
+			p.ring = append(p.ring, ringPoint{hash: hash32(b.url.String() + "#" + strconv.Itoa(i)), backend: b})
+		}
+	}
+	slices.SortFunc(p.ring, func(a, b ringPoint) int {
+		switch {
+		case a.hash < b.hash:
+			return -1
+		case a.hash > b.hash:
+			return 1
+		default:
+			return 0
+		}
+	})
+}
+
+// healthInterval마다 모든 backend를 확인하는 루프
+func (p *Pool) healthLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.healthInterval)
+	defer ticker.Stop()
+
+	p.checkAll()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.checkAll()
+		}
+	}
+}
+
+// 모든 backend에 동시에 health check request를 보내는 메소드
+func (p *Pool) checkAll() {
+	wg := sync.WaitGroup{}
+	for _, b := range p.backends {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			healthy := p.check(b)
+			if b.healthy.Swap(healthy) != healthy {
+				log.Printf("backend %s healthy: %v", b.url, healthy)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// backend 하나의 health check 메소드
+func (p *Pool) check(b *backend) bool {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if p.healthTimeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, p.healthTimeout)
+		defer cancelTimeout()
+	}
+	go func() {
+		select {
+		case <-p.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	u := *b.url
+	u.Path = joinPath(b.url.Path, p.healthPath)
+	u.RawQuery = ""
+
+	resp, err := p.healthClient.Get(ctx, u.String())
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	return resp.StatusCode >= 200 && resp.StatusCode < 400
+}
+
+// request를 보낸 client의 IP (ConsistentHash의 기본 key)
+func clientIP(req *request.Request) string {
+	if ip, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		return ip
+	}
+	return req.RemoteAddr
+}
+
+func hash32(s string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(s))
+	return h.Sum32()
+}
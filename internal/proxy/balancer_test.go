@@ -0,0 +1,228 @@
+package proxy
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/paokimsiwoong/httpfromtcp/internal/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 테스트용 backend server
+type testBackend struct {
+	name   string
+	url    *url.URL
+	hits   atomic.Int64
+	status atomic.Int64 // 보낼 status code (0이면 200)
+}
+
+// 이름을 body로 보내는 server.Server를 backend로 띄우는 함수
+func startBackend(t *testing.T, name string) *testBackend {
+	t.Helper()
+
+	tb := &testBackend{name: name}
+	s, err := server.ServeAddr("tcp", "127.0.0.1:0", func(w *response.Writer, req *request.Request) {
+		code := response.StatusCode(tb.status.Load())
+		if code == 0 {
+			code = response.StatusOK
+		}
+		if req.RequestLine.RequestTarget != "/healthz" {
+			tb.hits.Add(1)
+		}
+
+		_ = w.WriteStatusLine(code)
+		h := headers.NewHeaders()
+		h.SetOverride("Content-Length", strconv.Itoa(len(name)))
+		_ = w.WriteHeaders(h)
+		_, _ = w.WriteBody([]byte(name))
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+
+	tb.url = &url.URL{Scheme: "http", Host: s.Addr().String()}
+
+	return tb
+}
+
+func startBackends(t *testing.T, names ...string) ([]*testBackend, []*url.URL) {
+	t.Helper()
+
+	backends := []*testBackend{}
+	urls := []*url.URL{}
+	for _, name := range names {
+		tb := startBackend(t, name)
+		backends = append(backends, tb)
+		urls = append(urls, tb.url)
+	}
+
+	return backends, urls
+}
+
+// 주어진 client 주소로 GET request를 보내고 body(backend 이름)를 반환하는 함수
+func getFrom(t *testing.T, handler server.Handler, method, remoteAddr string) (int, string) {
+	t.Helper()
+
+	req := &request.Request{
+		RequestLine: request.RequestLine{Method: method, RequestTarget: "/", HttpVersion: "1.1"},
+		Headers:     headers.Headers{"host": "localhost"},
+		RemoteAddr:  remoteAddr,
+	}
+
+	resp, body := doRequest(t, handler, req)
+	return resp.StatusCode, body
+}
+
+func TestRoundRobin(t *testing.T) {
+	_, urls := startBackends(t, "a", "b", "c")
+	pool := NewPool(urls)
+	defer pool.Close()
+	handler := NewBalanced(pool)
+
+	got := []string{}
+	for i := 0; i < 6; i++ {
+		_, body := getFrom(t, handler, "GET", "192.0.2.1:1000")
+		got = append(got, body)
+	}
+
+	assert.Equal(t, []string{"a", "b", "c", "a", "b", "c"}, got)
+}
+
+func TestLeastConnections(t *testing.T) {
+	_, urls := startBackends(t, "a", "b")
+	pool := NewPool(urls, WithStrategy(LeastConnections))
+	defer pool.Close()
+
+	// Test: a가 처리 중인 request가 많으면 b를 고른다
+	pool.backends[0].active.Add(3)
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, pool.backends[1], pool.pick(&request.Request{}, nil))
+	}
+
+	pool.backends[1].active.Add(5)
+	assert.Equal(t, pool.backends[0], pool.pick(&request.Request{}, nil))
+}
+
+func TestConsistentHash(t *testing.T) {
+	backends, urls := startBackends(t, "a", "b", "c")
+	pool := NewPool(urls, WithStrategy(ConsistentHash))
+	defer pool.Close()
+	handler := NewBalanced(pool)
+
+	// Test: 같은 client는 항상 같은 backend
+	assignment := map[string]string{}
+	for i := 0; i < 30; i++ {
+		addr := "198.51.100." + strconv.Itoa(i) + ":4000"
+		_, first := getFrom(t, handler, "GET", addr)
+		_, second := getFrom(t, handler, "GET", addr)
+		assert.Equal(t, first, second)
+		assignment[addr] = first
+	}
+
+	// Test: backend 하나가 빠져도 그 backend에 배정됐던 client만 옮겨간다
+	pool.backends[1].healthy.Store(false)
+	for addr, before := range assignment {
+		_, after := getFrom(t, handler, "GET", addr)
+		if before != backends[1].name {
+			assert.Equal(t, before, after, addr)
+		} else {
+			assert.NotEqual(t, backends[1].name, after, addr)
+		}
+	}
+}
+
+func TestRetryAndPassiveEjection(t *testing.T) {
+	backends, urls := startBackends(t, "a", "b")
+	backends[0].status.Store(int64(response.StatusServiceUnavailable))
+
+	pool := NewPool(urls, WithPassiveEjection(2, time.Minute))
+	defer pool.Close()
+	handler := NewBalanced(pool)
+
+	// Test: 멱등 request는 503을 받으면 다른 backend로 다시 보낸다
+	for i := 0; i < 4; i++ {
+		code, body := getFrom(t, handler, "GET", "192.0.2.1:1000")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "b", body)
+	}
+
+	// Test: 두 번 실패한 a는 빠져서 더 이상 request를 받지 않는다
+	assert.Equal(t, int64(2), backends[0].hits.Load())
+
+	// Test: POST는 다시 보내지 않고 upstream 결과를 그대로 전달
+	pool2 := NewPool(urls, WithPassiveEjection(0, 0))
+	defer pool2.Close()
+	code, body := getFrom(t, NewBalanced(pool2), "POST", "192.0.2.1:1000")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "a", body)
+}
+
+func TestNoAvailableBackend(t *testing.T) {
+	// @@@ 띄웠다가 바로 닫은 server 주소로 연결 실패 만들기
+	s, err := server.ServeAddr("tcp", "127.0.0.1:0", nil)
+	require.NoError(t, err)
+	dead := &url.URL{Scheme: "http", Host: s.Addr().String()}
+	require.NoError(t, s.Close())
+
+	pool := NewPool([]*url.URL{dead}, WithPassiveEjection(1, time.Minute))
+	defer pool.Close()
+	handler := NewBalanced(pool)
+
+	code, _ := getFrom(t, handler, "GET", "192.0.2.1:1000")
+	assert.Equal(t, http.StatusBadGateway, code)
+
+	// Test: 빠진 뒤에는 보낼 곳이 없으므로 503
+	code, _ = getFrom(t, handler, "GET", "192.0.2.1:1000")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+}
+
+func TestActiveHealthCheck(t *testing.T) {
+	backends, urls := startBackends(t, "a", "b")
+	pool := NewPool(urls, WithHealthCheck("/healthz", 20*time.Millisecond, time.Second))
+	defer pool.Close()
+	handler := NewBalanced(pool)
+
+	backends[0].status.Store(int64(response.StatusInternalServerError))
+	assert.Eventually(t, func() bool { return !pool.backends[0].healthy.Load() }, 2*time.Second, 10*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		_, body := getFrom(t, handler, "GET", "192.0.2.1:1000")
+		assert.Equal(t, "b", body)
+	}
+
+	// Test: 다시 정상 응답하면 돌아온다
+	backends[0].status.Store(0)
+	assert.Eventually(t, func() bool { return pool.backends[0].healthy.Load() }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestBalancedConcurrent(t *testing.T) {
+	backends, urls := startBackends(t, "a", "b", "c")
+	pool := NewPool(urls, WithStrategy(LeastConnections))
+	defer pool.Close()
+	handler := NewBalanced(pool)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			code, _ := getFrom(t, handler, "GET", "192.0.2.1:1000")
+			assert.Equal(t, http.StatusOK, code)
+		}()
+	}
+	wg.Wait()
+
+	total := int64(0)
+	for _, b := range backends {
+		total += b.hits.Load()
+	}
+	assert.Equal(t, int64(30), total)
+}
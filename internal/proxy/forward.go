@@ -0,0 +1,450 @@
+package proxy
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/client"
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/paokimsiwoong/httpfromtcp/internal/server"
+)
+
+// CONNECT 대상에 연결할 때 기다리는 최대 시간
+const defaultDialTimeout = 10 * time.Second
+
+type forwardProxy struct {
+	client      *client.Client
+	dialTimeout time.Duration
+
+	// Proxy-Authorization basic auth (check가 nil이면 인증하지 않는다)
+	realm string
+	check func(user, password string) bool
+
+	// 접속할 수 있는 host 패턴들 (allow, allowNets가 비어있으면 deny에 걸리지 않는 모든 host)
+	allow []string
+	deny  []string
+	// IP, CIDR 패턴들 (host를 resolve한 주소에 맞춰본다)
+	allowNets []netip.Prefix
+	denyNets  []netip.Prefix
+}
+
+// deny 목록에 걸렸거나 allow 목록에 없는 host에 접속하려 할 때의 에러 (403 Forbidden)
+var errHostDenied = errors.New("proxy: host is not allowed")
+
+// NewForward에 넘겨 forward proxy 설정을 바꾸는 옵션 함수 타입
+type ForwardOption func(*forwardProxy)
+
+// Proxy-Authorization 헤더로 basic auth를 요구하는 옵션
+// check가 false를 반환하면 407 Proxy Authentication Required
+func WithProxyAuth(realm string, check func(user, password string) bool) ForwardOption {
+	return func(f *forwardProxy) {
+		f.realm = realm
+		f.check = check
+	}
+}
+
+// 사용자 이름과 비밀번호 하나로 WithProxyAuth를 만드는 함수
+// @@@ 비교 시간으로 값을 추측할 수 없도록 subtle.ConstantTimeCompare 사용
+func WithProxyUser(realm, user, password string) ForwardOption {
+	return WithProxyAuth(realm, func(u, p string) bool {
+		userOK := subtle.ConstantTimeCompare([]byte(u), []byte(user)) == 1
+		passwordOK := subtle.ConstantTimeCompare([]byte(p), []byte(password)) == 1
+		return userOK && passwordOK
+	})
+}
+
+// 이 host들로만 접속할 수 있도록 하는 옵션 (ex: "example.com", "*.example.com", "10.0.0.0/8", "192.0.2.1")
+// "*."로 시작하는 패턴은 그 아래 subdomain들과 맞는다
+// IP, CIDR 패턴은 host를 resolve한 주소가 모두 그 안에 있을 때 맞는다
+func WithAllowedHosts(patterns ...string) ForwardOption {
+	return func(f *forwardProxy) {
+		f.allow, f.allowNets = appendPatterns(f.allow, f.allowNets, patterns)
+	}
+}
+
+// 이 host들로는 접속할 수 없도록 하는 옵션 (WithAllowedHosts보다 우선)
+// IP, CIDR 패턴은 host를 resolve한 주소 중 하나라도 그 안에 있으면 맞는다
+// @@@ 이름 패턴은 이름만 비교하므로 "localhost"를 막아도 127.0.0.1, 127.1, [::1]로는 접속할 수 있다
+// @@@ loopback을 막으려면 "127.0.0.0/8", "::1"처럼 주소로 막는다
+func WithDeniedHosts(patterns ...string) ForwardOption {
+	return func(f *forwardProxy) {
+		f.deny, f.denyNets = appendPatterns(f.deny, f.denyNets, patterns)
+	}
+}
+
+// 패턴들을 이름 패턴과 IP, CIDR 패턴으로 나눠서 덧붙이는 함수 (IP 하나는 그 주소만 들어있는 prefix로)
+func appendPatterns(names []string, nets []netip.Prefix, patterns []string) ([]string, []netip.Prefix) {
+	for _, pattern := range patterns {
+		if prefix, err := netip.ParsePrefix(pattern); err == nil {
+			nets = append(nets, prefix.Masked())
+			continue
+		}
+		if addr, err := netip.ParseAddr(strings.Trim(pattern, "[]")); err == nil {
+			addr = addr.Unmap()
+			nets = append(nets, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		names = append(names, pattern)
+	}
+
+	return names, nets
+}
+
+// absolute-form request를 보낼 때 쓸 client를 정하는 옵션 (기본값 client.DefaultClient)
+func WithForwardClient(c *client.Client) ForwardOption {
+	return func(f *forwardProxy) {
+		f.client = c
+	}
+}
+
+// request가 forward proxy로 보낸 것인지 확인하는 함수
+// CONNECT request이거나 request target이 absolute-form(ex: "http://example.com/")이면 true
+func IsForwardRequest(req *request.Request) bool {
+	if req.RequestLine.Method == "CONNECT" {
+		return true
+	}
+
+	target := strings.ToLower(req.RequestLine.RequestTarget)
+	return strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://")
+}
+
+// HTTP forward proxy handler를 반환하는 함수
+// - absolute-form request (ex: GET http://example.com/a HTTP/1.1): 그 주소로 전달하고 response를 돌려준다
+// - CONNECT host:port: 대상에 TCP 연결을 맺고 200을 보낸 뒤 연결을 Hijack해서 양방향으로 바이트를 그대로 이어준다
+// - 그 외 request: 400 Bad Request
+func NewForward(opts ...ForwardOption) server.Handler {
+	f := &forwardProxy{
+		client:      client.DefaultClient,
+		dialTimeout: defaultDialTimeout,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	return f.serve
+}
+
+func (f *forwardProxy) serve(w *response.Writer, req *request.Request) {
+	if !f.authorized(req) {
+		h := headers.NewHeaders()
+		h.SetOverride("Proxy-Authenticate", "Basic realm="+strconv.Quote(f.realm))
+		writeStatus(w, response.StatusProxyAuthRequired, h)
+		return
+	}
+
+	if req.RequestLine.Method == "CONNECT" {
+		f.tunnel(w, req)
+		return
+	}
+
+	target, err := url.Parse(req.RequestLine.RequestTarget)
+	if err != nil || target.Host == "" || !IsForwardRequest(req) {
+		writeError(w, response.StatusBadRequest)
+		return
+	}
+
+	addrs, err := f.checkHost(context.Background(), target.Hostname())
+	if err != nil {
+		writeCheckError(w, target.Hostname(), err)
+		return
+	}
+
+	// @@@ reverse proxy와 같은 방식으로 전달하되 대상은 request에 적힌 주소
+	// @@@ Request를 복사하면 body 읽기 상태가 원래 request에 남지 않으므로 request target만 잠시 바꿨다가 되돌린다
+	origin := &url.URL{Scheme: target.Scheme, Host: target.Host}
+	requestTarget := req.RequestLine.RequestTarget
+	req.RequestLine.RequestTarget = target.RequestURI()
+	defer func() { req.RequestLine.RequestTarget = requestTarget }()
+
+	rp := &reverseProxy{target: origin, client: f.client}
+	// @@@ 확인한 주소로 접속해야 DNS 응답이 그 사이에 바뀌어도(DNS rebinding) 막힌 주소로 가지 않는다
+	// @@@ https는 인증서를 host 이름으로 확인해야 하므로 client가 다시 resolve한다
+	if len(addrs) > 0 && target.Scheme == "http" {
+		port := target.Port()
+		if port == "" {
+			port = "80"
+		}
+		origin.Host = net.JoinHostPort(addrs[0].String(), port)
+		rp.rewrite = func(out *client.Request, in *request.Request) {
+			out.Headers.SetOverride("host", target.Host)
+		}
+	}
+	rp.serve(w, req)
+}
+
+// Proxy-Authorization 헤더를 확인하는 메소드
+func (f *forwardProxy) authorized(req *request.Request) bool {
+	if f.check == nil {
+		return true
+	}
+
+	encoded, ok := strings.CutPrefix(req.Headers.Get("Proxy-Authorization"), "Basic ")
+	if !ok {
+		return false
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return false
+	}
+
+	user, password, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return false
+	}
+
+	return f.check(user, password)
+}
+
+// host에 접속해도 되는지 allow/deny 목록으로 확인하는 메소드
+// IP, CIDR 패턴을 확인하느라 host를 resolve했으면 그 주소들을 반환한다 (접속할 때 이 주소를 쓴다)
+// 막힌 host면 errHostDenied, resolve에 실패하면 그 에러
+func (f *forwardProxy) checkHost(ctx context.Context, host string) ([]netip.Addr, error) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	for _, pattern := range f.deny {
+		if matchHost(pattern, host) {
+			return nil, errHostDenied
+		}
+	}
+
+	var addrs []netip.Addr
+	if len(f.denyNets) > 0 {
+		var err error
+		addrs, err = lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			if containsAddr(f.denyNets, addr) {
+				return nil, errHostDenied
+			}
+		}
+	}
+
+	if len(f.allow) == 0 && len(f.allowNets) == 0 {
+		return addrs, nil
+	}
+
+	for _, pattern := range f.allow {
+		if matchHost(pattern, host) {
+			return addrs, nil
+		}
+	}
+
+	if len(f.allowNets) == 0 {
+		return nil, errHostDenied
+	}
+	if addrs == nil {
+		var err error
+		addrs, err = lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, addr := range addrs {
+		if !containsAddr(f.allowNets, addr) {
+			return nil, errHostDenied
+		}
+	}
+
+	return addrs, nil
+}
+
+// host의 IP 주소들을 반환하는 함수 (IP 주소 형태면 resolve하지 않고 그대로)
+// @@@ IPv4-mapped IPv6 주소(::ffff:127.0.0.1)는 IPv4 주소로 바꿔서 IPv4 CIDR 패턴과 맞춰볼 수 있게 한다
+func lookup(ctx context.Context, host string) ([]netip.Addr, error) {
+	if addr, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		return []netip.Addr{addr.Unmap()}, nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+	for i := range addrs {
+		addrs[i] = addrs[i].Unmap()
+	}
+
+	return addrs, nil
+}
+
+// addr이 prefix들 중 하나에 들어있는지 확인하는 함수
+func containsAddr(nets []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range nets {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHost 에러에 맞는 response를 쓰는 함수 (막힌 host면 403, resolve 실패면 502)
+func writeCheckError(w *response.Writer, host string, err error) {
+	if errors.Is(err, errHostDenied) {
+		writeError(w, response.StatusForbidden)
+		return
+	}
+	log.Printf("error resolving %s: %v", host, err)
+	writeError(w, response.StatusBadGateway)
+}
+
+// host가 패턴과 맞는지 확인하는 함수 ("*.example.com"은 a.example.com, a.b.example.com과 맞는다)
+func matchHost(pattern, host string) bool {
+	pattern = strings.ToLower(pattern)
+
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+		return strings.HasSuffix(host, suffix)
+	}
+
+	return pattern == host
+}
+
+// CONNECT 터널을 여는 메소드
+func (f *forwardProxy) tunnel(w *response.Writer, req *request.Request) {
+	// CONNECT의 request target은 authority-form (host:port)
+	address := req.RequestLine.RequestTarget
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || host == "" || port == "" {
+		writeError(w, response.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), f.dialTimeout)
+	defer cancel()
+
+	addrs, err := f.checkHost(ctx, host)
+	if err != nil {
+		writeCheckError(w, host, err)
+		return
+	}
+
+	upstream, err := dial(ctx, address, addrs, port)
+	if err != nil {
+		log.Printf("error dialing CONNECT target %s: %v", address, err)
+		writeError(w, statusForError(err))
+		return
+	}
+
+	// @@@ 200 이후에는 body가 없고 바로 터널 데이터가 오가므로 Content-Length 등 framing 헤더를 쓰지 않는다 (RFC 9110 9.3.6)
+	err = w.WriteStatusLine(response.StatusOK)
+	if err == nil {
+		err = w.WriteHeaders(headers.NewHeaders())
+	}
+	if err != nil {
+		log.Printf("error writing CONNECT response: %v", err)
+		upstream.Close()
+		return
+	}
+
+	client, buffered, err := w.Hijack()
+	if err != nil {
+		log.Printf("error hijacking connection for CONNECT: %v", err)
+		upstream.Close()
+		return
+	}
+
+	splice(client, buffered, upstream)
+}
+
+// CONNECT 대상에 TCP 연결을 맺는 함수
+// checkHost에서 확인한 주소들이 있으면 다시 resolve하지 않고 그 주소들로 차례대로 접속해본다
+func dial(ctx context.Context, address string, addrs []netip.Addr, port string) (net.Conn, error) {
+	dialer := &net.Dialer{}
+	if len(addrs) == 0 {
+		return dialer.DialContext(ctx, "tcp", address)
+	}
+
+	var err error
+	for _, addr := range addrs {
+		var conn net.Conn
+		conn, err = dialer.DialContext(ctx, "tcp", net.JoinHostPort(addr.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+	}
+
+	return nil, err
+}
+
+// 두 연결 사이에서 양방향으로 바이트를 복사하는 함수 (양쪽이 다 끝나면 두 연결을 닫는다)
+// clientReader에는 server가 client 연결에서 미리 읽어둔 바이트가 있을 수 있으므로 client 대신 여기서 읽는다
+// 한쪽 방향이 끝나면 반대편에 쓰기 종료(FIN)를 알려서 half-close를 전달한다
+func splice(client net.Conn, clientReader io.Reader, upstream net.Conn) {
+	defer client.Close()
+	defer upstream.Close()
+
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		_, err := io.Copy(upstream, clientReader)
+		logCopyError(err)
+		closeWrite(upstream)
+	}()
+
+	go func() {
+		defer wg.Done()
+		_, err := io.Copy(client, upstream)
+		logCopyError(err)
+		closeWrite(client)
+	}()
+
+	wg.Wait()
+}
+
+// 연결의 쓰기 방향만 닫는 함수 (지원하지 않는 연결이면 전체를 닫는다)
+func closeWrite(conn net.Conn) {
+	if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+		_ = cw.CloseWrite()
+		return
+	}
+	_ = conn.Close()
+}
+
+// 터널이 닫히면서 생기는 흔한 에러는 빼고 로그를 남기는 함수
+func logCopyError(err error) {
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		log.Printf("tunnel copy error: %v", err)
+	}
+}
+
+// 주어진 헤더에 짧은 text/plain body를 붙여 response를 보내는 함수
+func writeStatus(w *response.Writer, statusCode response.StatusCode, h headers.Headers) {
+	body := strconv.Itoa(int(statusCode)) + " " + response.StatusText(statusCode) + "\n"
+
+	err := w.WriteStatusLine(statusCode)
+	if err != nil {
+		log.Printf("error writing status line: %v", err)
+		return
+	}
+
+	h.SetOverride("Content-Length", strconv.Itoa(len(body)))
+	h.SetOverride("Content-Type", "text/plain; charset=utf-8")
+
+	err = w.WriteHeaders(h)
+	if err != nil {
+		log.Printf("error writing headers: %v", err)
+		return
+	}
+
+	_, err = w.WriteBody([]byte(body))
+	if err != nil {
+		log.Printf("error writing body: %v", err)
+		return
+	}
+}
@@ -0,0 +1,219 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// forward proxy server를 띄우고 그 주소를 반환하는 함수
+func startForward(t *testing.T, opts ...ForwardOption) *url.URL {
+	t.Helper()
+
+	s, err := server.ServeAddr("tcp", "127.0.0.1:0", NewForward(opts...))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+
+	return &url.URL{Scheme: "http", Host: s.Addr().String()}
+}
+
+// proxyURL을 거쳐 request를 보내는 http.Client
+func proxyClient(proxyURL *url.URL) *http.Client {
+	return &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyURL(proxyURL),
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+}
+
+func TestForwardAbsoluteForm(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Proxy-Authorization"))
+		_, _ = io.WriteString(w, r.Method+" "+r.URL.RequestURI()+" "+r.Host)
+	}))
+	defer upstream.Close()
+
+	client := proxyClient(startForward(t))
+
+	resp, err := client.Get(upstream.URL + "/path?q=1")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "GET /path?q=1 "+strings.TrimPrefix(upstream.URL, "http://"), string(body))
+}
+
+func TestForwardConnect(t *testing.T) {
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "secret over tls")
+	}))
+	defer upstream.Close()
+
+	client := proxyClient(startForward(t))
+
+	// Test: https request는 CONNECT 터널을 거친다
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(upstream.URL)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+		assert.Equal(t, "secret over tls", string(body))
+	}
+}
+
+func TestForwardConnectRaw(t *testing.T) {
+	// 받은 줄을 그대로 돌려주는 TCP echo server
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer echo.Close()
+	go func() {
+		conn, err := echo.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.Copy(conn, conn)
+	}()
+
+	proxyURL := startForward(t)
+	conn, err := net.Dial("tcp", proxyURL.Host)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	target := echo.Addr().String()
+	_, err = io.WriteString(conn, "CONNECT "+target+" HTTP/1.1\r\nHost: "+target+"\r\n\r\n")
+	require.NoError(t, err)
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, &http.Request{Method: "CONNECT"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Content-Length"))
+
+	// Test: 200 이후로는 바이트가 그대로 오간다
+	_, err = io.WriteString(conn, "ping\n")
+	require.NoError(t, err)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "ping\n", line)
+
+	_, err = io.WriteString(conn, "pong\n")
+	require.NoError(t, err)
+	line, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "pong\n", line)
+}
+
+func TestForwardAuth(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer upstream.Close()
+
+	proxyURL := startForward(t, WithProxyUser("httpfromtcp", "kim", "hunter2"))
+
+	// Test: 인증 정보가 없으면 407
+	resp, err := proxyClient(proxyURL).Get(upstream.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusProxyAuthRequired, resp.StatusCode)
+	assert.Equal(t, `Basic realm="httpfromtcp"`, resp.Header.Get("Proxy-Authenticate"))
+
+	// Test: 틀린 비밀번호
+	wrong := *proxyURL
+	wrong.User = url.UserPassword("kim", "wrong")
+	resp, err = proxyClient(&wrong).Get(upstream.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusProxyAuthRequired, resp.StatusCode)
+
+	// Test: 맞는 인증 정보
+	right := *proxyURL
+	right.User = url.UserPassword("kim", "hunter2")
+	resp, err = proxyClient(&right).Get(upstream.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestForwardHostLists(t *testing.T) {
+	allowed := func(f *forwardProxy, host string) bool {
+		_, err := f.checkHost(context.Background(), host)
+		return err == nil
+	}
+
+	f := &forwardProxy{}
+	WithAllowedHosts("*.example.com", "127.0.0.1")(f)
+	WithDeniedHosts("secret.example.com")(f)
+
+	assert.True(t, allowed(f, "api.example.com"))
+	assert.True(t, allowed(f, "A.B.Example.COM"))
+	assert.True(t, allowed(f, "127.0.0.1"))
+	assert.False(t, allowed(f, "127.0.0.2"))
+	assert.False(t, allowed(f, "secret.example.com"))
+	assert.False(t, allowed(f, "example.org"))
+
+	// Test: IP, CIDR 패턴은 이름이 아니라 주소로 막으므로 다른 표기로 우회할 수 없다
+	f = &forwardProxy{}
+	WithDeniedHosts("127.0.0.0/8", "::1")(f)
+	for _, host := range []string{"127.0.0.1", "127.0.0.2", "::1", "[::1]", "::ffff:127.0.0.1", "localhost"} {
+		_, err := f.checkHost(context.Background(), host)
+		assert.ErrorIs(t, err, errHostDenied, host)
+	}
+	assert.True(t, allowed(f, "192.0.2.1"))
+
+	// Test: allow CIDR은 resolve한 주소가 모두 들어있어야 한다
+	f = &forwardProxy{}
+	WithAllowedHosts("10.0.0.0/8")(f)
+	addrs, err := f.checkHost(context.Background(), "10.1.2.3")
+	require.NoError(t, err)
+	assert.Equal(t, []netip.Addr{netip.MustParseAddr("10.1.2.3")}, addrs)
+	assert.False(t, allowed(f, "192.0.2.1"))
+
+	// Test: 목록에 없는 host는 403 (CONNECT, absolute-form 둘 다)
+	proxyURL := startForward(t, WithDeniedHosts("127.0.0.1"))
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	defer upstream.Close()
+
+	resp, err := proxyClient(proxyURL).Get(upstream.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Test: 주소가 확인된 upstream에는 그 주소로 접속하고 Host는 원래 값을 보낸다
+	var gotHost string
+	allowedUpstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost = r.Host
+	}))
+	defer allowedUpstream.Close()
+	resp, err = proxyClient(startForward(t, WithAllowedHosts("127.0.0.0/8"))).Get(allowedUpstream.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, strings.TrimPrefix(allowedUpstream.URL, "http://"), gotHost)
+
+	tlsUpstream := httptest.NewTLSServer(http.NotFoundHandler())
+	defer tlsUpstream.Close()
+	_, err = proxyClient(proxyURL).Get(tlsUpstream.URL)
+	assert.ErrorContains(t, err, "Forbidden")
+}
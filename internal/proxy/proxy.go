@@ -0,0 +1,464 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/textproto"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/client"
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/paokimsiwoong/httpfromtcp/internal/server"
+)
+
+// request target이 upstream URL로 바꿀 수 없는 형식일 때의 에러 (400 Bad Request)
+var errInvalidTarget = errors.New("request target cannot be forwarded")
+
+// upstream response body를 읽어서 chunk 하나로 보내는 크기
+const copyBufferSize = 32 * 1024
+
+// 연결 하나에서만 의미가 있어서 proxy가 전달하지 않는 hop-by-hop 헤더들 (RFC 9110 7.6.1)
+// @@@ Expect는 hop-by-hop은 아니지만 100 Continue는 이 server가 client에게 보내므로 upstream에 다시 보내지 않는다
+var hopByHopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"TE",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+	"Expect",
+}
+
+type reverseProxy struct {
+	target  *url.URL
+	pool    *Pool                                          // nil이 아니면 target 대신 pool에서 request마다 backend를 고른다
+	prefix  string                                         // request target에서 떼어낼 경로 앞부분
+	rewrite func(out *client.Request, in *request.Request) // upstream으로 보내기 직전에 request를 고치는 함수
+	client  *client.Client
+	timeout time.Duration // request를 보내고 upstream response 헤더까지 받는 최대 시간 (0이면 무제한)
+}
+
+// New에 넘겨 reverse proxy 설정을 바꾸는 옵션 함수 타입
+type Option func(*reverseProxy)
+
+// request target 앞의 prefix를 떼고 나머지 경로를 target 경로 뒤에 붙이도록 하는 옵션
+// ex) WithStripPrefix("/httpbin") + target https://httpbin.org => /httpbin/get 요청은 https://httpbin.org/get
+func WithStripPrefix(prefix string) Option {
+	return func(p *reverseProxy) {
+		p.prefix = strings.TrimSuffix(prefix, "/")
+	}
+}
+
+// upstream으로 보낼 request를 마지막으로 고치는 함수를 정하는 옵션
+// URL, 헤더, body가 다 채워진 뒤에 호출된다 (ex: 경로 바꾸기, 인증 헤더 붙이기)
+// @@@ out.Headers에 host가 없으면 client가 URL의 host를 보낸다
+func WithRewrite(rewrite func(out *client.Request, in *request.Request)) Option {
+	return func(p *reverseProxy) {
+		p.rewrite = rewrite
+	}
+}
+
+// upstream에 request를 보낼 때 쓸 client를 정하는 옵션 (기본값 client.DefaultClient)
+// @@@ client는 redirect를 따라가지 않으므로 upstream의 redirect는 그대로 전달된다
+func WithClient(c *client.Client) Option {
+	return func(p *reverseProxy) {
+		p.client = c
+	}
+}
+
+// request를 보내고 upstream response 헤더를 받을 때까지 기다리는 최대 시간을 정하는 옵션 (넘으면 504 Gateway Timeout)
+// @@@ response body를 복사하는 동안은 제한하지 않으므로 오래 이어지는 스트리밍 response도 끊기지 않는다
+func WithTimeout(timeout time.Duration) Option {
+	return func(p *reverseProxy) {
+		p.timeout = timeout
+	}
+}
+
+// 받은 request를 target으로 전달하고 upstream response를 그대로 돌려보내는 handler를 반환하는 함수
+// method, 헤더, body, status code를 그대로 전달하며 hop-by-hop 헤더는 빼고
+// 아직 읽지 않은 request body는 메모리에 모으지 않고 읽는 대로 upstream으로 보낸다 (server.WithStreamingBody 참고)
+// X-Forwarded-For/-Host/-Proto와 Forwarded 헤더를 붙인다
+// upstream에 연결할 수 없으면 502 Bad Gateway, 시간 안에 응답이 없으면 504 Gateway Timeout
+func New(target *url.URL, opts ...Option) server.Handler {
+	p := &reverseProxy{
+		target: target,
+		client: client.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p.serve
+}
+
+// backend pool에서 request마다 upstream을 골라 전달하는 handler를 반환하는 함수
+// 연결 실패나 502/503/504가 오면 그 backend를 실패로 기록하고,
+// 멱등 request면 아직 시도하지 않은 다른 backend로 다시 보낸다
+// @@@ 읽는 대로 보낸 body는 다시 보낼 수 없으므로 body를 이미 다 받아둔 request만 다시 보낸다
+// 사용 가능한 backend가 없으면 503 Service Unavailable
+func NewBalanced(pool *Pool, opts ...Option) server.Handler {
+	p := &reverseProxy{
+		pool:   pool,
+		client: client.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p.serveBalanced
+}
+
+func (p *reverseProxy) serve(w *response.Writer, req *request.Request) {
+	resp, cancel, err := p.roundTrip(req, p.target)
+	if err != nil {
+		writeError(w, statusForError(err))
+		return
+	}
+	defer cancel()
+	defer resp.Body.Close()
+
+	copyResponse(w, req, resp)
+}
+
+func (p *reverseProxy) serveBalanced(w *response.Writer, req *request.Request) {
+	attempts := 1
+	if request.Idempotent(req.RequestLine.Method) && req.BodyComplete() {
+		attempts += max(p.pool.retries, 0)
+	}
+
+	tried := []*backend{}
+	statusCode := response.StatusServiceUnavailable
+
+	for range attempts {
+		b := p.pool.pick(req, tried)
+		if b == nil {
+			break
+		}
+		tried = append(tried, b)
+
+		done, code := p.tryBackend(w, req, b, len(tried) < attempts)
+		if done {
+			return
+		}
+		statusCode = code
+	}
+
+	writeError(w, statusCode)
+}
+
+// backend 하나로 request를 보내보는 메소드
+// response를 썼으면 true, 다시 시도해야 하면 false와 마지막으로 보낼 에러 status code를 반환
+// canRetry가 false면 502/503/504도 그대로 전달한다
+func (p *reverseProxy) tryBackend(w *response.Writer, req *request.Request, b *backend, canRetry bool) (bool, response.StatusCode) {
+	b.active.Add(1)
+	defer b.active.Add(-1)
+
+	resp, cancel, err := p.roundTrip(req, b.url)
+	if errors.Is(err, errInvalidTarget) || errors.Is(err, client.ErrRequestBody) {
+		// backend 잘못이 아니므로 실패로 기록하지 않는다
+		writeError(w, statusForError(err))
+		return true, 0
+	}
+	if err != nil {
+		p.pool.report(b, false)
+		return false, statusForError(err)
+	}
+	defer cancel()
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case response.StatusBadGateway, response.StatusServiceUnavailable, response.StatusGatewayTimeout:
+		p.pool.report(b, false)
+		if canRetry {
+			return false, resp.StatusCode
+		}
+	default:
+		p.pool.report(b, true)
+	}
+
+	copyResponse(w, req, resp)
+
+	return true, 0
+}
+
+// request를 target으로 보내고 upstream response 헤더까지 받는 메소드
+// WithTimeout이 있으면 response 헤더를 받을 때까지만 시간을 재고, 그 뒤 body를 복사하는 동안은 제한하지 않는다
+// 성공하면 response body를 다 쓴 뒤 반환된 cancel을 호출해야 한다
+func (p *reverseProxy) roundTrip(req *request.Request, target *url.URL) (*client.Response, context.CancelFunc, error) {
+	out, err := p.outgoingRequest(req, target)
+	if err != nil {
+		log.Printf("error building upstream request: %v", err)
+		return nil, nil, err
+	}
+
+	// @@@ context.WithTimeout은 body를 읽는 동안에도 끊으므로 헤더를 받으면 멈출 수 있는 timer로 취소한다
+	ctx, cancel := context.WithCancelCause(context.Background())
+	stop := func() bool { return true }
+	if p.timeout > 0 {
+		timer := time.AfterFunc(p.timeout, func() { cancel(context.DeadlineExceeded) })
+		stop = timer.Stop
+	}
+
+	resp, err := p.client.Do(ctx, out)
+	if !stop() && err == nil {
+		// 헤더를 받은 직후에 시간이 다 되어 이미 취소됐으면 body를 읽을 수 없다
+		resp.Body.Close()
+		err = context.Cause(ctx)
+	}
+	if err != nil {
+		log.Printf("error making upstream request to %s: %v", target, err)
+		if ctx.Err() != nil {
+			err = context.Cause(ctx)
+		}
+		cancel(nil)
+		return nil, nil, err
+	}
+
+	return resp, func() { cancel(nil) }, nil
+}
+
+// request를 upstream으로 보낼 client.Request로 바꾸는 메소드
+func (p *reverseProxy) outgoingRequest(req *request.Request, target *url.URL) (*client.Request, error) {
+	in, err := url.ParseRequestURI(req.RequestLine.RequestTarget)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidTarget, err)
+	}
+
+	u := *target
+	u.Path = joinPath(target.Path, strings.TrimPrefix(in.Path, p.prefix))
+	u.RawPath = ""
+	switch {
+	case target.RawQuery == "":
+		u.RawQuery = in.RawQuery
+	case in.RawQuery != "":
+		u.RawQuery = target.RawQuery + "&" + in.RawQuery
+	}
+
+	out := &client.Request{
+		Method:  req.RequestLine.Method,
+		URL:     &u,
+		Headers: headers.NewHeaders(),
+	}
+	// @@@ 아직 받지 않은 body는 BodyReader로 client에게서 받는 대로 upstream으로 보낸다 (Expect: 100-continue면 이때 100 Continue가 나간다)
+	if length := req.ContentLength(); length != 0 {
+		out.Body = req.BodyReader()
+		out.ContentLength = length
+	}
+
+	for key, value := range req.Headers {
+		switch key {
+		case "host", "content-length":
+			// Host는 target 주소로, Content-Length는 ContentLength로 client가 채운다
+			continue
+		}
+		out.Headers.SetOverride(key, value)
+	}
+	removeHopByHop(out.Headers)
+
+	setForwarded(out.Headers, req)
+
+	if p.rewrite != nil {
+		p.rewrite(out, req)
+	}
+
+	return out, nil
+}
+
+// 두 경로를 / 하나로 이어붙이는 함수
+func joinPath(base, rest string) string {
+	if rest == "" {
+		if base == "" {
+			return "/"
+		}
+		return base
+	}
+
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(rest, "/")
+}
+
+// Connection 헤더에 적힌 헤더들과 hop-by-hop 헤더들을 지우는 함수 (h의 key는 소문자)
+func removeHopByHop(h headers.Headers) {
+	for _, name := range strings.Split(h.Get("Connection"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			delete(h, strings.ToLower(name))
+		}
+	}
+
+	for _, name := range hopByHopHeaders {
+		delete(h, strings.ToLower(name))
+	}
+}
+
+// X-Forwarded-For/-Host/-Proto와 Forwarded(RFC 7239) 헤더를 붙이는 함수
+// For는 앞선 proxy들의 값 뒤에 덧붙이고, Host와 Proto는 이 server가 받은 값으로 덮어쓴다
+// (h의 key는 소문자)
+func setForwarded(h headers.Headers, req *request.Request) {
+	proto := "http"
+	if req.TLS != nil {
+		proto = "https"
+	}
+	host := req.Headers.Get("Host")
+
+	clientIP := ""
+	if ip, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		clientIP = ip
+	}
+
+	if clientIP != "" {
+		// @@@ Set은 이미 있는 값 뒤에 ", "로 덧붙인다
+		h.Set("x-forwarded-for", clientIP)
+	}
+	if host != "" {
+		h.SetOverride("x-forwarded-host", host)
+	}
+	h.SetOverride("x-forwarded-proto", proto)
+
+	// @@@ Forwarded는 IPv6 주소를 "[...]"로 감싸고, 주소를 모르면(unix socket 등) unknown
+	forwardedFor := "unknown"
+	if clientIP != "" {
+		forwardedFor = clientIP
+		if strings.Contains(clientIP, ":") {
+			forwardedFor = `"[` + clientIP + `]"`
+		}
+	}
+	forwarded := "for=" + forwardedFor
+	if host != "" {
+		forwarded += ";host=" + strconv.Quote(host)
+	}
+	forwarded += ";proto=" + proto
+
+	h.Set("forwarded", forwarded)
+}
+
+// upstream response를 status code, 헤더, body 그대로 w에 쓰는 함수
+// body 길이를 알면 Content-Length로, 모르면 chunked로 받는 대로 내보낸다
+func copyResponse(w *response.Writer, req *request.Request, resp *client.Response) {
+	removeHopByHop(resp.Headers)
+
+	h := headers.NewHeaders()
+	for key, value := range resp.Headers {
+		if key == "content-length" {
+			continue
+		}
+		// @@@ headers.Headers는 key 하나에 값 하나라서 여러 값은 ", "로 합쳐져 있다
+		// @@@ (Set-Cookie처럼 합치면 의미가 바뀌는 헤더는 정확히 전달되지 않는다)
+		h.SetOverride(textproto.CanonicalMIMEHeaderKey(key), value)
+	}
+
+	statusCode := resp.StatusCode
+	noBody := statusCode < 200 || statusCode == response.StatusNoContent || statusCode == response.StatusNotModified
+	chunked := false
+
+	switch {
+	case req.RequestLine.Method == "HEAD":
+		// HEAD는 body가 없지만 GET이었다면 보냈을 길이를 그대로 알려준다
+		if length := resp.Headers.Get("Content-Length"); length != "" {
+			h.SetOverride("Content-Length", length)
+		}
+		noBody = true
+	case noBody:
+	case resp.ContentLength >= 0:
+		h.SetOverride("Content-Length", strconv.FormatInt(resp.ContentLength, 10))
+	default:
+		h.SetOverride("Transfer-Encoding", "chunked")
+		chunked = true
+	}
+
+	err := w.WriteStatusLine(statusCode)
+	if err != nil {
+		log.Printf("error writing status line: %v", err)
+		return
+	}
+
+	err = w.WriteHeaders(h)
+	if err != nil {
+		log.Printf("error writing headers: %v", err)
+		return
+	}
+
+	if noBody {
+		return
+	}
+
+	if !chunked {
+		n, err := io.Copy(w, resp.Body)
+		if err != nil || n != resp.ContentLength {
+			// @@@ 약속한 길이만큼 보내지 못했으므로 같은 연결로 다음 request를 받으면 안 된다
+			log.Printf("error copying upstream body (%d/%d bytes): %v", n, resp.ContentLength, err)
+			w.CloseConnection()
+		}
+		return
+	}
+
+	buf := make([]byte, copyBufferSize)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			_, writeErr := w.WriteChunkedBody(buf[:n])
+			if writeErr == nil {
+				// @@@ 스트리밍 response(ex: /stream/{n})는 받는 대로 client에 보이도록 바로 내보내기
+				writeErr = w.Flush()
+			}
+			if writeErr != nil {
+				log.Printf("error writing a chunk: %v", writeErr)
+				w.CloseConnection()
+				return
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// @@@ 마지막 chunk를 보내지 않고 연결을 닫아서 client가 body가 잘렸다는 걸 알 수 있게 한다
+			log.Printf("error reading upstream body: %v", err)
+			w.CloseConnection()
+			return
+		}
+	}
+
+	_, err = w.WriteChunkedBodyDone()
+	if err != nil {
+		log.Printf("error writing a chunk end: %v", err)
+	}
+}
+
+// upstream 요청 에러에 맞는 status code를 고르는 함수
+func statusForError(err error) response.StatusCode {
+	// @@@ client가 보낸 body를 읽다가 실패한 것은 upstream 잘못이 아니다
+	if errors.Is(err, request.ErrBodyTooLarge) {
+		return response.StatusContentTooLarge
+	}
+	if errors.Is(err, errInvalidTarget) || errors.Is(err, client.ErrRequestBody) {
+		return response.StatusBadRequest
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return response.StatusGatewayTimeout
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return response.StatusGatewayTimeout
+	}
+
+	return response.StatusBadGateway
+}
+
+// 짧은 text/plain body로 에러 response를 보내는 함수
+func writeError(w *response.Writer, statusCode response.StatusCode) {
+	writeStatus(w, statusCode, headers.NewHeaders())
+}
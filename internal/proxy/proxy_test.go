@@ -0,0 +1,282 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/client"
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/paokimsiwoong/httpfromtcp/internal/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// raw request를 handler로 처리하고 결과를 net/http로 파싱하는 함수
+func do(t *testing.T, handler server.Handler, raw string) (*http.Response, string) {
+	t.Helper()
+
+	req, err := request.RequestFromReader(strings.NewReader(raw))
+	require.NoError(t, err)
+	req.RemoteAddr = "192.0.2.7:51234"
+
+	return doRequest(t, handler, req)
+}
+
+// request를 handler로 처리하고 결과를 net/http로 파싱하는 함수
+func doRequest(t *testing.T, handler server.Handler, req *request.Request) (*http.Response, string) {
+	t.Helper()
+
+	out := &bytes.Buffer{}
+	w := response.NewWriter(out)
+	handler(w, req)
+	require.NoError(t, w.Flush())
+
+	resp, err := http.ReadResponse(bufio.NewReader(out), &http.Request{Method: req.RequestLine.Method})
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp, string(body)
+}
+
+func mustParse(t *testing.T, rawURL string) *url.URL {
+	t.Helper()
+
+	u, err := url.Parse(rawURL)
+	require.NoError(t, err)
+
+	return u
+}
+
+func TestProxyForwardsRequest(t *testing.T) {
+	var got *http.Request
+	var gotBody []byte
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		gotBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("X-Upstream", "yes")
+		w.Header().Set("Connection", "X-Secret")
+		w.Header().Set("X-Secret", "hop")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, "created!")
+	}))
+	defer upstream.Close()
+
+	handler := New(mustParse(t, upstream.URL+"/api?key=1"), WithStripPrefix("/proxy"))
+
+	body := `{"name":"coffee"}`
+	resp, respBody := do(t, handler, "PUT /proxy/items/7?x=y HTTP/1.1\r\n"+
+		"Host: localhost:42069\r\n"+
+		"Content-Type: application/json\r\n"+
+		"Connection: keep-alive, X-Drop-Me\r\n"+
+		"X-Drop-Me: please\r\n"+
+		"X-Forwarded-For: 203.0.113.9\r\n"+
+		"Content-Length: "+strconv.Itoa(len(body))+"\r\n\r\n"+body)
+
+	// Test: method, 경로, query, body, 헤더 전달
+	require.NotNil(t, got)
+	assert.Equal(t, "PUT", got.Method)
+	assert.Equal(t, "/api/items/7", got.URL.Path)
+	assert.Equal(t, "key=1&x=y", got.URL.RawQuery)
+	assert.Equal(t, body, string(gotBody))
+	assert.Equal(t, "application/json", got.Header.Get("Content-Type"))
+	assert.Empty(t, got.Header.Get("X-Drop-Me"))
+
+	// Test: X-Forwarded-*, Forwarded
+	assert.Equal(t, "203.0.113.9, 192.0.2.7", got.Header.Get("X-Forwarded-For"))
+	assert.Equal(t, "localhost:42069", got.Header.Get("X-Forwarded-Host"))
+	assert.Equal(t, "http", got.Header.Get("X-Forwarded-Proto"))
+	assert.Equal(t, `for=192.0.2.7;host="localhost:42069";proto=http`, got.Header.Get("Forwarded"))
+
+	// Test: upstream status code, 헤더, body 그대로 (hop-by-hop 제외)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "yes", resp.Header.Get("X-Upstream"))
+	assert.Empty(t, resp.Header.Get("X-Secret"))
+	assert.Equal(t, "created!", respBody)
+}
+
+func TestProxyStatusVerbatim(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		if code == http.StatusFound {
+			http.Redirect(w, r, "/elsewhere", code)
+			return
+		}
+		w.WriteHeader(code)
+	}))
+	defer upstream.Close()
+
+	handler := New(mustParse(t, upstream.URL))
+
+	for _, code := range []int{http.StatusFound, http.StatusNotFound, http.StatusTeapot, http.StatusServiceUnavailable} {
+		resp, _ := do(t, handler, "GET /"+strconv.Itoa(code)+" HTTP/1.1\r\nHost: localhost\r\n\r\n")
+		assert.Equal(t, code, resp.StatusCode)
+		if code == http.StatusFound {
+			// Test: redirect는 따라가지 않고 그대로 전달
+			assert.Equal(t, "/elsewhere", resp.Header.Get("Location"))
+		}
+	}
+}
+
+func TestProxyStreamsUnknownLength(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 5; i++ {
+			_, _ = io.WriteString(w, strings.Repeat(strconv.Itoa(i), 10000))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer upstream.Close()
+
+	resp, body := do(t, New(mustParse(t, upstream.URL)), "GET /stream HTTP/1.1\r\nHost: localhost\r\n\r\n")
+
+	assert.Equal(t, []string{"chunked"}, resp.TransferEncoding)
+	assert.Len(t, body, 50000)
+	assert.True(t, strings.HasPrefix(body, "0000") && strings.HasSuffix(body, "4444"))
+}
+
+func TestProxyHead(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1234")
+	}))
+	defer upstream.Close()
+
+	resp, body := do(t, New(mustParse(t, upstream.URL)), "HEAD / HTTP/1.1\r\nHost: localhost\r\n\r\n")
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int64(1234), resp.ContentLength)
+	assert.Empty(t, body)
+}
+
+func TestProxyUpstreamErrors(t *testing.T) {
+	// Test: 연결할 수 없는 upstream이면 502
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	target := mustParse(t, upstream.URL)
+	upstream.Close()
+
+	resp, _ := do(t, New(target), "GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+
+	// Test: 시간 안에 응답하지 않으면 504
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	resp, _ = do(t, New(mustParse(t, slow.URL), WithTimeout(50*time.Millisecond)), "GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+}
+
+func TestProxyTimeoutOnlyUntilHeaders(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		for i := 0; i < 3; i++ {
+			time.Sleep(40 * time.Millisecond)
+			_, _ = io.WriteString(w, strconv.Itoa(i))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer upstream.Close()
+
+	// Test: 헤더를 시간 안에 받았으면 body가 timeout보다 오래 걸려도 끝까지 전달한다
+	resp, body := do(t, New(mustParse(t, upstream.URL), WithTimeout(50*time.Millisecond)), "GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "012", body)
+}
+
+func TestProxyStreamsRequestBody(t *testing.T) {
+	received := make(chan string, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 첫 부분은 client가 나머지를 보내기 전에 도착해야 한다
+		first := make([]byte, 5)
+		_, err := io.ReadFull(r.Body, first)
+		if err == nil {
+			received <- string(first)
+		}
+		rest, _ := io.ReadAll(r.Body)
+		_, _ = io.WriteString(w, strings.Join(r.TransferEncoding, ",")+" "+string(first)+string(rest))
+	}))
+	defer upstream.Close()
+
+	s, err := server.ServeAddr("tcp", "127.0.0.1:0", New(mustParse(t, upstream.URL)),
+		server.WithStreamingBody(func(*request.Request) bool { return true }))
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Test: body를 다 받기 전에 받은 만큼 upstream으로 보낸다 (길이를 모르는 chunked body는 chunked로)
+	_, err = io.WriteString(conn, "POST /upload HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n")
+	require.NoError(t, err)
+	select {
+	case first := <-received:
+		assert.Equal(t, "hello", first)
+	case <-time.After(2 * time.Second):
+		t.Fatal("upstream did not receive the first chunk before the body ended")
+	}
+	_, err = io.WriteString(conn, "7\r\n, world\r\n0\r\n\r\n")
+	require.NoError(t, err)
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "chunked hello, world", string(body))
+}
+
+func TestProxyRewrite(t *testing.T) {
+	var gotPath, gotAuth string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+	}))
+	defer upstream.Close()
+
+	handler := New(mustParse(t, upstream.URL), WithRewrite(func(out *client.Request, in *request.Request) {
+		out.URL.Path = "/v2" + out.URL.Path
+		out.Headers.SetOverride("authorization", "Bearer upstream-token")
+	}))
+
+	resp, _ := do(t, handler, "GET /users HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "/v2/users", gotPath)
+	assert.Equal(t, "Bearer upstream-token", gotAuth)
+}
+
+func TestProxyReusesUpstreamConnections(t *testing.T) {
+	conns := 0
+	upstream := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.URL.Path)
+	}))
+	upstream.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns++
+		}
+	}
+	upstream.Start()
+	defer upstream.Close()
+
+	handler := New(mustParse(t, upstream.URL), WithClient(client.New()))
+
+	// Test: upstream response body를 다 전달하면 다음 request에 같은 연결을 다시 쓴다
+	for _, path := range []string{"/a", "/b", "/c"} {
+		_, body := do(t, handler, "GET "+path+" HTTP/1.1\r\nHost: localhost\r\n\r\n")
+		assert.Equal(t, path, body)
+	}
+	assert.Equal(t, 1, conns)
+}
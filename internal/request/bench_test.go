@@ -0,0 +1,86 @@
+package request
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const chromeRequest = "GET /httpbin/html?lang=en HTTP/1.1\r\n" +
+	"Host: localhost:42069\r\n" +
+	"Connection: keep-alive\r\n" +
+	"sec-ch-ua: \"Chromium\";v=\"124\", \"Google Chrome\";v=\"124\", \"Not-A.Brand\";v=\"99\"\r\n" +
+	"sec-ch-ua-mobile: ?0\r\n" +
+	"sec-ch-ua-platform: \"Linux\"\r\n" +
+	"Upgrade-Insecure-Requests: 1\r\n" +
+	"User-Agent: Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36\r\n" +
+	"Accept: text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8\r\n" +
+	"Sec-Fetch-Site: none\r\n" +
+	"Sec-Fetch-Mode: navigate\r\n" +
+	"Sec-Fetch-User: ?1\r\n" +
+	"Sec-Fetch-Dest: document\r\n" +
+	"Accept-Encoding: gzip, deflate, br, zstd\r\n" +
+	"Accept-Language: en-US,en;q=0.9,ko;q=0.8\r\n" +
+	"Cookie: session=4f2a9c; theme=dark\r\n\r\n"
+
+// 벤치마크에 쓰는 request들
+func benchRequests() []struct{ name, raw string } {
+	body := strings.Repeat("x", 4096)
+	var many strings.Builder
+	many.WriteString("GET / HTTP/1.1\r\nHost: localhost\r\n")
+	for i := range 100 {
+		many.WriteString("X-Header-" + strconv.Itoa(i) + ": " + strings.Repeat("v", 40) + "\r\n")
+	}
+	many.WriteString("\r\n")
+
+	return []struct{ name, raw string }{
+		{"curl", "GET / HTTP/1.1\r\nHost: localhost:42069\r\nUser-Agent: curl/8.5.0\r\nAccept: */*\r\n\r\n"},
+		{"chrome", chromeRequest},
+		{"body_4k", "POST /upload HTTP/1.1\r\nHost: localhost\r\nContent-Length: 4096\r\n\r\n" + body},
+		{"headers_100", many.String()},
+	}
+}
+
+func BenchmarkRequestFromReader(b *testing.B) {
+	for _, tc := range benchRequests() {
+		b.Run(tc.name, func(b *testing.B) {
+			reader := strings.NewReader(tc.raw)
+			b.SetBytes(int64(len(tc.raw)))
+			b.ReportAllocs()
+			for b.Loop() {
+				reader.Reset(tc.raw)
+				_, err := RequestFromReader(reader)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// server처럼 연결의 bufio.Reader에서 request를 계속 읽는 경우
+func BenchmarkRequestHeadersFromBufio(b *testing.B) {
+	for _, tc := range benchRequests() {
+		b.Run(tc.name, func(b *testing.B) {
+			data := []byte(tc.raw)
+			src := bytes.NewReader(data)
+			reader := bufio.NewReader(src)
+			b.SetBytes(int64(len(tc.raw)))
+			b.ReportAllocs()
+			for b.Loop() {
+				src.Reset(data)
+				reader.Reset(src)
+				req, err := RequestHeadersFromReader(reader)
+				if err != nil {
+					b.Fatal(err)
+				}
+				err = req.ReadBody()
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package request
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/transfer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestHeadersFromReader(t *testing.T) {
+	// Test: 헤더까지만 읽고 body는 ReadBody 때 읽는다
+	reader := &chunkReader{
+		data: "POST /submit HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Content-Length: 13\r\n" +
+			"\r\n" +
+			"hello world!\n",
+		numBytesPerRead: 3,
+	}
+	r, err := RequestHeadersFromReader(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "POST", r.RequestLine.Method)
+	assert.Equal(t, "13", r.Headers.Get("content-length"))
+	assert.Empty(t, r.Body)
+	assert.False(t, r.BodyComplete())
+	assert.False(t, r.ExpectsContinue())
+
+	require.NoError(t, r.ReadBody())
+	assert.Equal(t, "hello world!\n", string(r.Body))
+	assert.True(t, r.BodyComplete())
+	// 두 번째 호출은 아무것도 하지 않는다
+	require.NoError(t, r.ReadBody())
+	assert.Equal(t, "hello world!\n", string(r.Body))
+
+	// Test: body가 없으면 더 읽지 않고 끝난다
+	reader = &chunkReader{
+		data:            "GET / HTTP/1.1\r\nHost: localhost\r\n\r\n",
+		numBytesPerRead: 5,
+	}
+	r, err = RequestHeadersFromReader(reader)
+	require.NoError(t, err)
+	require.NoError(t, r.ReadBody())
+	assert.True(t, r.BodyComplete())
+
+	// Test: 읽기 에러는 계속 같은 에러로 반환한다
+	reader = &chunkReader{
+		data:            "POST / HTTP/1.1\r\nContent-Length: 10\r\n\r\nshort",
+		numBytesPerRead: 4,
+	}
+	r, err = RequestHeadersFromReader(reader)
+	require.NoError(t, err)
+	require.ErrorIs(t, r.ReadBody(), ErrIncorrectContentLength)
+	require.ErrorIs(t, r.ReadBody(), ErrIncorrectContentLength)
+
+	// Test: reader 없이 만든 Request는 Body를 그대로 둔다
+	manual := &Request{Body: []byte("as is")}
+	require.NoError(t, manual.ReadBody())
+	assert.Equal(t, "as is", string(manual.Body))
+}
+
+func TestExpectContinue(t *testing.T) {
+	data := "PUT /upload HTTP/1.1\r\n" +
+		"Host: localhost\r\n" +
+		"Expect: 100-Continue\r\n" +
+		"Content-Length: 5\r\n" +
+		"\r\n" +
+		"abcde"
+
+	r, err := RequestHeadersFromReader(&chunkReader{data: data, numBytesPerRead: 7})
+	require.NoError(t, err)
+	assert.True(t, r.ExpectsContinue())
+
+	// Test: 설정된 함수는 body를 처음 읽기 전에 한번만 호출된다
+	calls := 0
+	r.SetExpectContinue(func() error {
+		calls++
+		assert.Empty(t, r.Body)
+		return nil
+	})
+	require.NoError(t, r.ReadBody())
+	require.NoError(t, r.ReadBody())
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, "abcde", string(r.Body))
+	assert.False(t, r.ExpectsContinue())
+
+	// Test: 함수가 실패하면 body를 읽지 않는다
+	r, err = RequestHeadersFromReader(&chunkReader{data: data, numBytesPerRead: 7})
+	require.NoError(t, err)
+	sendErr := errors.New("connection closed")
+	r.SetExpectContinue(func() error { return sendErr })
+	require.ErrorIs(t, r.ReadBody(), sendErr)
+	assert.False(t, r.BodyComplete())
+
+	// Test: body가 없으면 100 Continue가 필요 없다
+	r, err = RequestHeadersFromReader(&chunkReader{
+		data:            "POST / HTTP/1.1\r\nExpect: 100-continue\r\nContent-Length: 0\r\n\r\n",
+		numBytesPerRead: 7,
+	})
+	require.NoError(t, err)
+	assert.False(t, r.ExpectsContinue())
+}
+
+func TestChunkedBody(t *testing.T) {
+	data := "POST /upload HTTP/1.1\r\n" +
+		"Host: localhost\r\n" +
+		"Transfer-Encoding: chunked\r\n" +
+		"\r\n" +
+		"5\r\nhello\r\n7;ext=1\r\n, world\r\n0\r\nX-Checksum: 42\r\n\r\n" +
+		"GET /next HTTP/1.1\r\n"
+
+	// Test: chunk들을 이어붙여 Body에, trailer는 Trailers에 넣고, 다음 request는 남겨둔다
+	for _, n := range []int{1, 3, 1024} {
+		reader := bufio.NewReader(&chunkReader{data: data, numBytesPerRead: n})
+		r, err := RequestHeadersFromReader(reader)
+		require.NoError(t, err)
+		require.NoError(t, r.ReadBody())
+		assert.Equal(t, "hello, world", string(r.Body))
+		assert.Equal(t, "42", r.Trailers.Get("x-checksum"))
+		rest, _ := io.ReadAll(reader)
+		assert.Equal(t, "GET /next HTTP/1.1\r\n", string(rest))
+	}
+
+	// Test: chunked body도 Expect: 100-continue를 기다린다
+	r, err := RequestHeadersFromReader(&chunkReader{
+		data:            "POST / HTTP/1.1\r\nExpect: 100-continue\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\n",
+		numBytesPerRead: 7,
+	})
+	require.NoError(t, err)
+	assert.True(t, r.ExpectsContinue())
+}
+
+func TestBodyReader(t *testing.T) {
+	data := "POST /upload HTTP/1.1\r\n" +
+		"Host: localhost\r\n" +
+		"Expect: 100-continue\r\n" +
+		"Content-Length: 12\r\n" +
+		"\r\n" +
+		"hello, world" +
+		"GET /next HTTP/1.1\r\n"
+
+	// Test: body를 Body에 모으지 않고 읽으며, 끝까지 읽으면 BodyComplete
+	reader := bufio.NewReader(&chunkReader{data: data, numBytesPerRead: 3})
+	r, err := RequestHeadersFromReader(reader)
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), r.ContentLength())
+	calls := 0
+	r.SetExpectContinue(func() error {
+		calls++
+		return nil
+	})
+
+	body, err := io.ReadAll(r.BodyReader())
+	require.NoError(t, err)
+	assert.Equal(t, "hello, world", string(body))
+	assert.Equal(t, 1, calls)
+	assert.Empty(t, r.Body)
+	assert.True(t, r.BodyComplete())
+	rest, _ := io.ReadAll(reader)
+	assert.Equal(t, "GET /next HTTP/1.1\r\n", string(rest))
+
+	// Test: 이미 읽은 body는 Body를 읽는다
+	r, err = RequestFromReader(&chunkReader{data: data, numBytesPerRead: 3})
+	require.NoError(t, err)
+	body, err = io.ReadAll(r.BodyReader())
+	require.NoError(t, err)
+	assert.Equal(t, "hello, world", string(body))
+	assert.Equal(t, int64(12), r.ContentLength())
+
+	// Test: chunked body는 길이를 모른다
+	r, err = RequestHeadersFromReader(&chunkReader{
+		data:            "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n0\r\n\r\n",
+		numBytesPerRead: 4,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), r.ContentLength())
+	body, err = io.ReadAll(r.BodyReader())
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(body))
+
+	// Test: 읽기 에러는 계속 같은 에러로 반환하고 ReadBody도 같은 에러
+	r, err = RequestHeadersFromReader(&chunkReader{
+		data:            "POST / HTTP/1.1\r\nContent-Length: 10\r\n\r\nshort",
+		numBytesPerRead: 4,
+	})
+	require.NoError(t, err)
+	_, err = io.ReadAll(r.BodyReader())
+	require.ErrorIs(t, err, ErrIncorrectContentLength)
+	require.ErrorIs(t, r.ReadBody(), ErrIncorrectContentLength)
+}
+
+func TestMaxBodySize(t *testing.T) {
+	chunked := "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n6\r\n world\r\n0\r\n\r\n"
+
+	// Test: chunked body가 제한을 넘으면 ReadBody가 ErrBodyTooLarge를 반환한다
+	r, err := RequestHeadersFromReader(&chunkReader{data: chunked, numBytesPerRead: 4})
+	require.NoError(t, err)
+	r.SetMaxBodySize(8)
+	assert.False(t, r.BodyTooLarge())
+	require.ErrorIs(t, r.ReadBody(), ErrBodyTooLarge)
+	require.ErrorIs(t, r.ReadBody(), ErrBodyTooLarge)
+
+	// Test: BodyReader도 제한까지만 읽는다
+	r, err = RequestHeadersFromReader(&chunkReader{data: chunked, numBytesPerRead: 4})
+	require.NoError(t, err)
+	r.SetMaxBodySize(8)
+	_, err = io.ReadAll(r.BodyReader())
+	require.ErrorIs(t, err, ErrBodyTooLarge)
+
+	// Test: 제한과 같은 길이는 읽는다
+	r, err = RequestHeadersFromReader(&chunkReader{data: chunked, numBytesPerRead: 4})
+	require.NoError(t, err)
+	r.SetMaxBodySize(11)
+	require.NoError(t, r.ReadBody())
+	assert.Equal(t, "hello world", string(r.Body))
+
+	// Test: Content-Length가 제한보다 크면 읽기 전에 알 수 있다
+	r, err = RequestHeadersFromReader(&chunkReader{
+		data:            "POST / HTTP/1.1\r\nContent-Length: 12\r\n\r\nhello, world",
+		numBytesPerRead: 4,
+	})
+	require.NoError(t, err)
+	r.SetMaxBodySize(11)
+	assert.True(t, r.BodyTooLarge())
+	require.ErrorIs(t, r.ReadBody(), ErrBodyTooLarge)
+	assert.Empty(t, r.Body)
+}
+
+func TestBodyFramingErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		err  error
+	}{
+		{"transfer encoding with content length", "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\nContent-Length: 5\r\n\r\n0\r\n\r\n", ErrConflictingFraming},
+		{"unsupported transfer coding", "POST / HTTP/1.1\r\nTransfer-Encoding: gzip\r\n\r\n", ErrUnsupportedTransferEncoding},
+		{"chunked is not the only coding", "POST / HTTP/1.1\r\nTransfer-Encoding: gzip, chunked\r\n\r\n", ErrUnsupportedTransferEncoding},
+		{"signed content length", "POST / HTTP/1.1\r\nContent-Length: +5\r\n\r\nhello", ErrInvalidContentLength},
+		{"conflicting content lengths", "POST / HTTP/1.1\r\nContent-Length: 5\r\nContent-Length: 6\r\n\r\nhello!", ErrInvalidContentLength},
+		{"invalid chunk size", "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\nzz\r\n", transfer.ErrInvalidChunk},
+		{"missing last chunk", "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n", ErrIncompleteRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := RequestFromReader(&chunkReader{data: tc.raw, numBytesPerRead: 3})
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
+}
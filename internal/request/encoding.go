@@ -0,0 +1,128 @@
+package request
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// body나 압축을 푼 body가 크기 제한을 넘을 때의 에러 (SetMaxBodySize, DecodeBody)
+var ErrBodyTooLarge = errors.New("body exceeds the size limit")
+var ErrInvalidEncodedBody = errors.New("body is not valid for its content encoding")
+
+// Content-Encoding으로 압축된 body를 풀어서 Body를 바꾸는 메소드 (gzip, x-gzip, deflate, identity 지원)
+// 압축된 body나 풀린 body가 maxSize 바이트를 넘으면 ErrBodyTooLarge를 반환한다 (압축 폭탄 방어)
+// 성공하면 Content-Encoding, Transfer-Encoding 헤더를 지우고 Content-Length를 풀린 길이로 바꾼다
+// @@@ chunked body도 다 읽어서 Body에 넣었으므로 Transfer-Encoding을 남기면 Content-Length와 같이 있게 된다 (ErrConflictingFraming)
+// @@@ 실패하면 Body와 헤더는 그대로 둔다
+func (r *Request) DecodeBody(maxSize int64) error {
+	contentEncoding := r.Headers.Get("Content-Encoding")
+	if contentEncoding == "" {
+		return nil
+	}
+
+	err := r.readEncodedBody(maxSize)
+	if err != nil {
+		return err
+	}
+
+	// 여러 개면 적용된 순서대로 나열되므로 뒤에서부터 푼다 (ex: "deflate, gzip" => gzip 먼저)
+	codings := strings.Split(contentEncoding, ",")
+
+	body := r.Body
+	for i := len(codings) - 1; i >= 0; i-- {
+		coding := strings.ToLower(strings.TrimSpace(codings[i]))
+
+		var err error
+		body, err = decode(coding, body, maxSize)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.Body = body
+	r.Headers.Delete("content-encoding")
+	r.Headers.Delete("transfer-encoding")
+	r.Headers.SetOverride("content-length", strconv.Itoa(len(body)))
+
+	return nil
+}
+
+// 아직 읽지 않은 압축된 body를 maxSize 바이트까지만 읽어서 Body에 저장하는 메소드
+// @@@ 압축된 body를 먼저 다 읽으면 크기 제한이 풀 때만 적용되므로 큰 body 하나로 메모리를 다 쓰게 만들 수 있다
+// @@@ Content-Length가 maxSize보다 크면 읽지 않고, 길이를 모르는 chunked body는 maxSize+1까지만 읽는다
+func (r *Request) readEncodedBody(maxSize int64) error {
+	if r.bodyErr != nil {
+		return r.bodyErr
+	}
+
+	length := r.ContentLength()
+	if length > maxSize {
+		return ErrBodyTooLarge
+	}
+	if length >= 0 {
+		return r.ReadBody()
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.BodyReader(), maxSize+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(body)) > maxSize {
+		return ErrBodyTooLarge
+	}
+	r.Body = body
+
+	return nil
+}
+
+// coding 하나를 푸는 함수
+func decode(coding string, data []byte, maxSize int64) ([]byte, error) {
+	var reader io.ReadCloser
+	var err error
+
+	switch coding {
+	case "identity", "":
+		if int64(len(data)) > maxSize {
+			return nil, ErrBodyTooLarge
+		}
+		return data, nil
+	case "gzip", "x-gzip":
+		reader, err = gzip.NewReader(bytes.NewReader(data))
+	case "deflate":
+		// @@@ HTTP의 deflate는 zlib 형식이지만 raw DEFLATE를 보내는 client도 있어서 zlib 헤더가 없으면 raw로 읽는다
+		br := bufio.NewReader(bytes.NewReader(data))
+		header, _ := br.Peek(2)
+		if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			reader, err = zlib.NewReader(br)
+		} else {
+			reader = flate.NewReader(br)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedEncoding, coding)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEncodedBody, err)
+	}
+	defer reader.Close()
+
+	// @@@ maxSize+1까지만 읽어서 넘는지 확인 (끝까지 다 풀지 않는다)
+	decoded, err := io.ReadAll(io.LimitReader(reader, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEncodedBody, err)
+	}
+	if int64(len(decoded)) > maxSize {
+		return nil, ErrBodyTooLarge
+	}
+
+	return decoded, nil
+}
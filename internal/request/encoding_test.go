@@ -0,0 +1,130 @@
+package request
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// body를 주어진 Content-Encoding으로 보내는 request를 파싱하는 함수
+func encodedRequest(t *testing.T, contentEncoding string, body []byte) *Request {
+	t.Helper()
+
+	raw := "POST /upload HTTP/1.1\r\nHost: localhost\r\n" +
+		"Content-Encoding: " + contentEncoding + "\r\n" +
+		"Content-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + string(body)
+
+	req, err := RequestFromReader(strings.NewReader(raw))
+	require.NoError(t, err)
+
+	return req
+}
+
+func compress(t *testing.T, newWriter func(io.Writer) io.WriteCloser, data []byte) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	zw := newWriter(buf)
+	_, err := zw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	return buf.Bytes()
+}
+
+func gzipWriter(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }
+func zlibWriter(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }
+func flateWriter(w io.Writer) io.WriteCloser {
+	fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+	return fw
+}
+
+func TestDecodeBody(t *testing.T) {
+	plain := []byte(strings.Repeat("partial credit is still credit\n", 50))
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+	}{
+		{"gzip", "gzip", compress(t, gzipWriter, plain)},
+		{"x-gzip", "X-GZIP", compress(t, gzipWriter, plain)},
+		{"deflate (zlib)", "deflate", compress(t, zlibWriter, plain)},
+		{"deflate (raw)", "deflate", compress(t, flateWriter, plain)},
+		{"layered", "deflate, gzip", compress(t, gzipWriter, compress(t, zlibWriter, plain))},
+		{"identity", "identity", plain},
+	}
+
+	for _, tc := range tests {
+		req := encodedRequest(t, tc.encoding, tc.body)
+		require.NoError(t, req.DecodeBody(1<<20), tc.name)
+		assert.Equal(t, plain, req.Body, tc.name)
+		assert.Empty(t, req.Headers.Get("Content-Encoding"), tc.name)
+		assert.Equal(t, strconv.Itoa(len(plain)), req.Headers.Get("Content-Length"), tc.name)
+	}
+
+	// Test: chunked body를 풀면 Transfer-Encoding은 지우고 Content-Length만 남긴다
+	// @@@ 둘 다 남으면 handler가 받은 request를 다시 보낼 때 ErrConflictingFraming
+	body := compress(t, gzipWriter, plain)
+	raw := "POST /upload HTTP/1.1\r\nHost: localhost\r\nContent-Encoding: gzip\r\nTransfer-Encoding: chunked\r\n\r\n" +
+		strconv.FormatInt(int64(len(body)), 16) + "\r\n" + string(body) + "\r\n0\r\n\r\n"
+	req, err := RequestHeadersFromReader(strings.NewReader(raw))
+	require.NoError(t, err)
+	require.NoError(t, req.DecodeBody(1<<20))
+	assert.Equal(t, plain, req.Body)
+	assert.Empty(t, req.Headers.Get("Content-Encoding"))
+	assert.Empty(t, req.Headers.Get("Transfer-Encoding"))
+	assert.Equal(t, strconv.Itoa(len(plain)), req.Headers.Get("Content-Length"))
+
+	// Test: Content-Encoding이 없으면 그대로
+	req, err = RequestFromReader(strings.NewReader("POST / HTTP/1.1\r\nHost: localhost\r\nContent-Length: 3\r\n\r\nabc"))
+	require.NoError(t, err)
+	require.NoError(t, req.DecodeBody(1))
+	assert.Equal(t, []byte("abc"), req.Body)
+}
+
+func TestDecodeBodyErrors(t *testing.T) {
+	// Test: 압축 폭탄 (작게 압축되지만 풀면 크기 제한을 넘는 body)
+	bomb := compress(t, gzipWriter, make([]byte, 10<<20))
+	req := encodedRequest(t, "gzip", bomb)
+	assert.ErrorIs(t, req.DecodeBody(1<<20), ErrBodyTooLarge)
+	// @@@ 실패하면 원래 body와 헤더 유지
+	assert.Equal(t, bomb, req.Body)
+	assert.Equal(t, "gzip", req.Headers.Get("Content-Encoding"))
+
+	// Test: 압축된 body가 크기 제한을 넘으면 풀기 전에, Content-Length만 보고 읽지 않고 거절
+	req, err := RequestHeadersFromReader(strings.NewReader("POST / HTTP/1.1\r\nContent-Encoding: gzip\r\nContent-Length: 2048\r\n\r\n"))
+	require.NoError(t, err)
+	assert.ErrorIs(t, req.DecodeBody(1024), ErrBodyTooLarge)
+	assert.False(t, req.BodyComplete())
+
+	// Test: 길이를 모르는 chunked body는 제한까지만 읽는다
+	chunk := strings.Repeat("x", 1000)
+	raw := "POST / HTTP/1.1\r\nContent-Encoding: identity\r\nTransfer-Encoding: chunked\r\n\r\n" +
+		strings.Repeat("3e8\r\n"+chunk+"\r\n", 3) + "0\r\n\r\n"
+	req, err = RequestHeadersFromReader(strings.NewReader(raw))
+	require.NoError(t, err)
+	assert.ErrorIs(t, req.DecodeBody(1024), ErrBodyTooLarge)
+	assert.False(t, req.BodyComplete())
+
+	req, err = RequestHeadersFromReader(strings.NewReader(raw))
+	require.NoError(t, err)
+	require.NoError(t, req.DecodeBody(4096))
+	assert.Len(t, req.Body, 3000)
+
+	// Test: 지원하지 않는 encoding
+	req = encodedRequest(t, "br", []byte("whatever"))
+	assert.ErrorIs(t, req.DecodeBody(1<<20), ErrUnsupportedEncoding)
+
+	// Test: 깨진 데이터
+	req = encodedRequest(t, "gzip", []byte("definitely not gzip"))
+	assert.ErrorIs(t, req.DecodeBody(1<<20), ErrInvalidEncodedBody)
+}
@@ -0,0 +1,94 @@
+package request
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"maps"
+	"testing"
+)
+
+// 한 번에 numBytesPerRead 바이트씩 읽으며 data를 파싱하는 함수
+// reader에서 읽어간 바이트 수도 반환한다
+func parseInChunks(data []byte, numBytesPerRead int) (*Request, int, error) {
+	reader := &chunkReader{data: string(data), numBytesPerRead: numBytesPerRead}
+	req, err := RequestFromReader(reader)
+	return req, reader.pos, err
+}
+
+// 파싱 결과 비교에서 body framing 헤더는 뺀다
+// @@@ WriteTo는 풀린 body를 Content-Length로 다시 쓰므로 두 헤더 값은 바뀔 수 있다
+func withoutFraming(h map[string]string) map[string]string {
+	h = maps.Clone(h)
+	delete(h, "content-length")
+	delete(h, "transfer-encoding")
+	return h
+}
+
+func sameRequest(a, b *Request) bool {
+	return a.RequestLine == b.RequestLine &&
+		maps.Equal(withoutFraming(a.Headers), withoutFraming(b.Headers)) &&
+		bytes.Equal(a.Body, b.Body)
+}
+
+// seed corpus는 testdata/fuzz/FuzzRequestFromReader (curl, 브라우저가 보낸 request들)
+func FuzzRequestFromReader(f *testing.F) {
+	f.Add([]byte("GET / HTTP/1.1\r\nHost: localhost:42069\r\n\r\n"), uint8(3))
+	f.Add([]byte("POST /submit HTTP/1.1\r\nHost: localhost\r\nContent-Length: 5\r\n\r\nhello"), uint8(7))
+	f.Add([]byte("POST /upload HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n0\r\n\r\n"), uint8(5))
+
+	f.Fuzz(func(t *testing.T, data []byte, readSize uint8) {
+		// @@@ 1바이트씩 읽으면 request 끝을 넘어서 읽지 않으므로 기준으로 사용
+		want, consumed, wantErr := parseInChunks(data, 1)
+		n := int(readSize)%64 + 1
+
+		// Property: 한 번에 읽히는 크기와 상관없이 같은 결과 (request 뒤에 바이트가 더 있어도)
+		for _, n := range []int{n, len(data) + 1} {
+			got, _, err := parseInChunks(data, n)
+			if (err == nil) != (wantErr == nil) {
+				t.Fatalf("read size %d: error %v, with 1 byte reads: %v", n, err, wantErr)
+			}
+			if err == nil && !sameRequest(got, want) {
+				t.Fatalf("read size %d: parsed %+v, with 1 byte reads: %+v", n, got, want)
+			}
+		}
+		if wantErr != nil {
+			return
+		}
+
+		// Property: *bufio.Reader로 읽으면 request 뒤의 바이트는 읽지 않고 reader에 남겨둔다
+		reader := bufio.NewReader(&chunkReader{data: string(data), numBytesPerRead: n})
+		got, err := RequestHeadersFromReader(reader)
+		if err == nil {
+			err = got.ReadBody()
+		}
+		if err != nil || !sameRequest(got, want) {
+			t.Fatalf("reading from bufio.Reader: %v\n%+v\n%+v", err, got, want)
+		}
+		rest, _ := io.ReadAll(reader)
+		if !bytes.Equal(rest, data[consumed:]) {
+			t.Fatalf("bytes after the request were consumed: %q, want %q", rest, data[consumed:])
+		}
+
+		// Property: 파싱한 request는 항상 다시 쓸 수 있고, 다시 파싱하면 같은 request
+		written := &bytes.Buffer{}
+		_, err = want.WriteTo(written)
+		if err != nil {
+			t.Fatalf("parsed request cannot be written: %v\n%+v", err, want)
+		}
+		reparsed, _, err := parseInChunks(written.Bytes(), 1)
+		if err != nil {
+			t.Fatalf("written request cannot be parsed: %v\n%q", err, written.String())
+		}
+		if !sameRequest(reparsed, want) {
+			t.Fatalf("round trip changed request:\n%+v\n%+v", want, reparsed)
+		}
+
+		// Property: 한 번 쓴 request는 다시 파싱해서 써도 같은 바이트
+		rewritten := &bytes.Buffer{}
+		_, err = reparsed.WriteTo(rewritten)
+		if err != nil || !bytes.Equal(written.Bytes(), rewritten.Bytes()) {
+			t.Fatalf("serialization is not stable: %v\n%q\n%q", err, written.String(), rewritten.String())
+		}
+	})
+}
@@ -1,21 +1,52 @@
 package request
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
-	"strconv"
+	"slices"
 	"strings"
+	"sync"
 	"unicode"
 
 	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/transfer"
 )
 
 type Request struct {
 	RequestLine RequestLine
 	Headers     headers.Headers
-	Body        []byte
-	State       int // 파싱 상태를 알리는 State
+	// @@@ RequestHeadersFromReader로 만든 request는 ReadBody를 호출하기 전까지 비어있다
+	// chunked body는 chunk들을 이어붙인 값
+	Body []byte
+	// chunked body 뒤에 온 trailer 필드들 (chunked가 아니면 nil)
+	Trailers headers.Headers
+	State    int // 파싱 상태를 알리는 State
+
+	// @@@ 아래 필드들은 파서가 채우지 않고 server가 연결 정보를 보고 채운다
+	// request를 보낸 client의 주소 (ex: "127.0.0.1:51234")
+	RemoteAddr string
+	// HTTPS 연결로 들어온 request면 협상된 TLS 정보 (평문 연결이면 nil)
+	TLS *tls.ConnectionState
+
+	// RequestHeadersFromReader로 만든 request의 body 읽기 상태 (ReadBody 참고)
+	src            *bufio.Reader
+	pooled         bool               // src가 readerPool에서 빌려온 reader인지
+	lines          headers.LineReader // request line과 헤더 블록을 headers.MaxHeaderBytes까지만 읽는다
+	chunked        bool               // Transfer-Encoding: chunked body인지
+	contentLength  int64              // chunked가 아니면 Content-Length 값 (없으면 0)
+	pending        io.Reader          // 읽기 시작한 body의 LengthReader나 ChunkedReader
+	bodyErr        error
+	expectContinue func() error
+	maxBodySize    int64 // 0이면 제한 없음 (SetMaxBodySize)
+	bodyRead       int64 // 연결에서 읽은 body 바이트 수
+
+	// SetBodyReader로 정한, WriteTo가 Body 대신 보낼 body와 그 길이
+	stream       io.Reader
+	streamLength int64
 }
 
 type RequestLine struct {
@@ -36,9 +67,28 @@ var ErrIncompleteRequest = errors.New("incomplete request")
 var ErrMissingEndofHeaders = errors.New("there must be an additional crlf at the end of headers")
 var ErrIncorrectContentLength = errors.New("actual body length and reported content length are different")
 
-// var ErrInvalidContentLength = errors.New("content length value must be a number(the size of body in bytes)")
+// request line과 헤더 블록이 headers.MaxHeaderBytes나 headers.MaxFields를 넘을 때의 에러 (server는 431로 응답)
+var ErrHeadersTooLarge = headers.ErrTooLarge
 
-const bufferSize = 8
+var ErrInvalidContentLength = transfer.ErrInvalidContentLength
+
+// @@@ Transfer-Encoding과 Content-Length가 같이 오면 앞단 proxy와 body 길이를 다르게 볼 수 있으므로(request smuggling) 거절 (RFC 9112 6.1)
+var ErrConflictingFraming = errors.New("request must not contain both Transfer-Encoding and Content-Length")
+
+// chunked 하나만 있는 Transfer-Encoding이 아닐 때의 에러 (server는 501로 응답)
+var ErrUnsupportedTransferEncoding = errors.New("unsupported transfer coding")
+
+// bufio.Reader가 아닌 reader를 감쌀 때 쓰는 버퍼 크기
+// @@@ 처음에는 1~3바이트 조각으로 들어오는 테스트 케이스를 위해 8바이트씩 읽었지만
+// @@@ 버퍼 크기와 상관없이 줄 단위로 파싱하므로 보통 쓰는 크기로 변경
+const readBufferSize = 4096
+
+// bufio.Reader가 아닌 reader를 감쌀 bufio.Reader들을 재사용하는 pool
+var readerPool = sync.Pool{
+	New: func() any {
+		return bufio.NewReaderSize(nil, readBufferSize)
+	},
+}
 
 // @@@ 예시 따라서 Request의 State 필드에 들어갈 값 const 지정
 const (
@@ -51,306 +101,482 @@ const (
 // @@@ 예시 따라서 crlf도 const 지정
 const crlf = "\r\n"
 
-// io.Reader를 받아 HTTP request를 파싱하는 함수
+// io.Reader를 받아 HTTP request를 파싱하는 함수 (body까지 모두 읽는다)
+// body는 Content-Length 만큼만 읽으므로 그 뒤의 바이트는 다음 request로 보고 에러 없이 무시한다
+// @@@ 예전에는 Content-Length 뒤에 이미 도착한 바이트가 있으면 에러였지만 reader가 한 번에 주는 크기에 따라 결과가 달라져서 제거
+// 연결처럼 request가 이어서 들어오는 reader는 *bufio.Reader로 감싸서 RequestHeadersFromReader와 ReadBody로 읽는다
 func RequestFromReader(reader io.Reader) (*Request, error) {
-	// 파싱 완료된 데이터를 담을 구조체 선언
-	req := Request{
+	req := newRequest(reader)
+	defer req.release()
+
+	err := req.readUntil(requestStateDone)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// io.Reader에서 request line과 헤더까지만 파싱하고 body는 ReadBody를 호출할 때 읽도록 남겨두는 함수
+// @@@ Expect: 100-continue request는 handler가 body를 읽기로 했을 때 100 Continue를 보내야 하므로 body 읽기를 미룬다
+// reader가 *bufio.Reader면 그 버퍼에서 바로 파싱하고 request 뒤의 바이트는 읽지 않고 남겨둔다 (다음 request, CONNECT 뒤의 터널 데이터 등)
+// 다른 reader는 pool의 bufio.Reader로 감싸서 읽으므로 request 뒤의 바이트는 버려질 수 있다
+func RequestHeadersFromReader(reader io.Reader) (*Request, error) {
+	req := newRequest(reader)
+
+	err := req.readUntil(requestStateParsingBody)
+	if err != nil {
+		req.release()
+		return nil, err
+	}
+	if req.State == requestStateDone {
+		req.release()
+	}
+
+	return req, nil
+}
+
+// reader에서 읽을 빈 Request를 만드는 함수
+func newRequest(reader io.Reader) *Request {
+	req := &Request{
 		State:   requestStateInitialized,
 		Headers: headers.NewHeaders(), // @@@ 여기서 맵 초기화 해놓지 않으면 에러 발생
 	}
 
-	// 첫단계에서는 전체 데이터를 한꺼번에 메모리에 올려도 문제 없으므로
-	// io.ReadAll 사용
-	// raw, err := io.ReadAll(reader)
-	// if err != nil {
-	// 	return nil, fmt.Errorf("error reading io reader: %w", err)
-	// }
-	// @@@ 이제는 조각조각 데이터가 들어와도 처리 가능하도록 변경
-	buffer := make([]byte, 0, bufferSize)
-	// make([]byte, 8) 이렇게만 두면 len 8, cap 8로 이미 8개의 0이 들어있는 취급이라
-	// 뒤에 buffer = append(buffer, chunk...)를 하면 8개의 0이 대체되는 것이 아니라
-	// 그 0 뒤에 chunk의 데이터가 추가된다
-	bytesRead := 0
-	bytesParsed := 0
-
-	for req.State != requestStateDone { // req.State == 1 즉 파싱 완료가 되기 전까지 루프 반복
-		chunk := make([]byte, 8)
-		// @@@@@ 과제 tips에서는 chunk를 따로 만들지 않고
-		// @@@@@ reader.Read(buffer[bytesRead:])
-		// @@@@@ 그대신 Read하기 전에 buffer가 가득찬지 확인하고 가득찬 경우
-		// @@@@@ 크키가 2배인 새 버퍼를 만들고 거기에 구 buffer를 복사한다
-		// @@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@
-		// 일반적으로는 const bufferSize = 8 와 같이 버퍼 크기를 매우 작은 값으로 두기 보단
-		// 1024, 4096과 같은 값을 사용하지만
-		// 여기서는 1~3바이트 조각으로 들어오는 테스트 케이스들을 테스트 하기 위해 작은 값으로 둔 상태
-		// 일반적인 케이스처럼 버퍼 크기를 키울 경우 매번 chunk를 생성하기보다는
-		// 과제 tips처럼 Read에 buffer를 넣는 방식으로 바꾸는게 좋아 보임
-		// @@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@
-
-		n, err := reader.Read(chunk)
-		if err != nil {
-			if errors.Is(err, io.EOF) {
-				// Read가 끝났을 때 행하는 코드들
-				//
-				// @@@ n!=0, io.EOF이 반환되는 경우가 현재는 없다?
-				// n이 0이 아닐 경우
-				// if n != 0 {
-				// 	// 현재까지 읽은 바이트 길이 기록
-				// 	bytesRead += n
-				//
-				// 	// 새로 읽은 부분을 buffer에 추가
-				// 	buffer = append(buffer, chunk[:n]...)
-				// 	// chunk 슬라이스 뒤에 ...을 붙여서 unpack한 뒤에 append에 입력
-				// 	// @@@ chunk안의 유효 데이터만 buffer에 붙일 수 있도록 슬라이싱 [:n] 필요
-				//
-				// 	m, err := req.parse(buffer)
-				// 	if err != nil {
-				// 		if errors.Is(err, ErrInvalidState) {
-				// 			return &req, fmt.Errorf("error trying to read data in a done state: %w", err)
-				// 		}
-				// 		return nil, fmt.Errorf("error parsing buffer: %w", err)
-				// 	}
-				// 	// 현재까지 파싱한 바이트 길이 기록
-				// 	if m != 0 {
-				// 		bytesParsed += m
-				// 		// 파싱 완료된 부분들은 buffer에서 필요 없음
-				// 		// @@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@
-				// 		// oldBuffer := buffer
-				// 		// buffer = make([]byte, len(oldBuffer)-m)
-				// 		// _ = copy(buffer, oldBuffer[m:])
-				// 		// 이 방식은 데이터 복사 완료 후에도
-				// 		// 구 버퍼를 가리키는 변수가 남아있어
-				// 		// 메모리 회수에 불리하므로 변경하기
-				// 		// @@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@
-				// 		newBuffer := make([]byte, len(buffer)-m)
-				// 		_ = copy(newBuffer, buffer[m:])
-				// 		buffer = newBuffer
-				// 		// @@@@@ 과제 tips 방식을 사용했을 경우에도
-				// 		// @@@@@ 위와 같이 파싱된 부분을 제외한 부분을 새로운 buffer를 생성해 복사하고
-				// 		// @@@@@ bytesRead에서 파싱된 길이만큼을 빼준다
-				// 	}
-				// }
-
-				// reader에 들어있는 데이터가 없는 경우
-				if bytesRead == 0 {
-					return nil, ErrEmptyReader
-				}
-				// reader를 다 읽었는데도 파싱된 데이터가 없는 경우
-				if bytesParsed == 0 {
-					return nil, ErrNotParsed
-				}
-
-				// request가 incomplete라 마지막에 파싱 불가능한 조각이 남은 경우
-				if bytesParsed != bytesRead {
-					return nil, ErrIncompleteRequest
-				}
-
-				// reader를 다 읽었는데도 requestStateParsingHeaders 상태가 안끝남
-				if req.State == requestStateParsingHeaders {
-					return nil, ErrMissingEndofHeaders
-				}
-
-				// reader를 다 읽었는데도 requestStateParsingBody 상태가 안끝남
-				// (주어진 content length보다 body가 짧음)
-				if req.State == requestStateParsingBody {
-
-					// @@@ parseSingle 함수가 아니라 여기서 body 길이 확인할 경우
-					// length, err := strconv.Atoi(req.Headers.Get("Content-Length"))
-					// if err != nil {
-					// 	return nil, err
-					// }
-
-					// if len(req.Body) == length {
-					// 	req.State = requestStateDone
-					// 	break
-					// }
-
-					return nil, ErrIncorrectContentLength
-				}
-
-				break
-			}
-			return nil, fmt.Errorf("error reading io reader: %w", err)
-		}
-		// 현재까지 읽은 바이트 길이 기록
-		bytesRead += n
+	if br, ok := reader.(*bufio.Reader); ok {
+		req.src = br
+	} else {
+		req.src = readerPool.Get().(*bufio.Reader)
+		req.src.Reset(reader)
+		req.pooled = true
+	}
+	req.lines.Reset(req.src, headers.MaxHeaderBytes)
 
-		// 새로 읽은 부분을 buffer에 추가
-		buffer = append(buffer, chunk[:n]...)
-		// chunk 슬라이스 뒤에 ...을 붙여서 unpack한 뒤에 append에 입력
-		// @@@ chunk안의 유효 데이터만 buffer에 붙일 수 있도록 슬라이싱 [:n] 필요
+	return req
+}
 
-		m, err := req.parse(buffer)
-		if err != nil {
-			if errors.Is(err, ErrInvalidState) {
-				return &req, fmt.Errorf("error trying to read data in a done state: %w", err)
-			}
-			return nil, fmt.Errorf("error parsing buffer: %w", err)
-		}
-		// 현재까지 파싱한 바이트 길이 기록
-		if m != 0 {
-			bytesParsed += m
-			// 파싱 완료된 부분들은 buffer에서 필요 없음
-			// @@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@
-			// oldBuffer := buffer
-			// buffer = make([]byte, len(oldBuffer)-m)
-			// _ = copy(buffer, oldBuffer[m:])
-			// 이 방식은 데이터 복사 완료 후에도
-			// 구 버퍼를 가리키는 변수가 남아있어
-			// 메모리 회수에 불리하므로 변경하기
-			// @@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@
-			newBuffer := make([]byte, len(buffer)-m)
-			_ = copy(newBuffer, buffer[m:])
-			buffer = newBuffer
-			// @@@@@ 과제 tips 방식을 사용했을 경우에도
-			// @@@@@ 위와 같이 파싱된 부분을 제외한 부분을 새로운 buffer를 생성해 복사하고
-			// @@@@@ bytesRead에서 파싱된 길이만큼을 빼준다
-		}
+// 다 읽었거나 읽기에 실패한 request가 reader를 더 잡고 있지 않도록 놓아주는 메소드
+// pool에서 빌려온 bufio.Reader면 돌려준다
+func (r *Request) release() {
+	if r.src == nil {
+		return
+	}
+	if r.pooled {
+		r.src.Reset(nil)
+		readerPool.Put(r.src)
+	}
+	r.src = nil
+	r.pooled = false
+}
+
+// body를 아직 읽지 않았으면 Content-Length 만큼 (chunked면 마지막 chunk까지) 읽어서 Body에 저장하는 메소드
+// 이미 다 읽었거나 reader 없이 만든 Request면 아무것도 하지 않는다
+// Expect: 100-continue request면 처음 읽기 전에 SetExpectContinue로 설정된 함수를 먼저 호출한다
+// 읽기에 실패하면 이후에도 같은 에러를 반환한다
+// @@@ BodyReader로 이미 읽어간 부분은 Body에 들어가지 않는다
+func (r *Request) ReadBody() error {
+	if r.bodyErr != nil {
+		return r.bodyErr
+	}
+	if r.src == nil || r.State == requestStateDone {
+		return nil
+	}
+
+	err := r.sendContinue()
+	if err != nil {
+		return err
 	}
 
-	return &req, nil
+	err = r.readUntil(requestStateDone)
+	r.release()
+	if err != nil {
+		r.bodyErr = err
+		return err
+	}
+
+	return nil
 }
 
-// request의 state에 따라 파싱을 진행할지 안할지 결정하는 함수
-func (r *Request) parse(data []byte) (int, error) {
-	if r.State == requestStateDone {
-		return 0, ErrInvalidState
+// body를 Body에 모으지 않고 읽는 io.Reader를 반환하는 메소드
+// 아직 읽지 않은 body면 연결에서 바로 읽어서 끝까지 읽으면 BodyComplete가 true가 되고,
+// 이미 읽었거나 reader 없이 만든 Request면 Body를 읽는다
+// Expect: 100-continue request면 처음 읽을 때 SetExpectContinue로 설정된 함수를 먼저 호출한다
+// @@@ 큰 body를 메모리에 다 올리지 않고 proxy upstream 같은 곳으로 바로 넘길 때 사용
+func (r *Request) BodyReader() io.Reader {
+	if r.bodyErr == nil && (r.src == nil || r.State == requestStateDone) {
+		return bytes.NewReader(r.Body)
 	}
-	if r.State < requestStateInitialized || r.State > requestStateDone {
-		return 0, ErrUnknownState
+
+	return bodyReader{r}
+}
+
+// BodyReader로 읽을 body의 길이를 반환하는 메소드
+// 아직 읽지 않은 chunked body처럼 길이를 미리 알 수 없으면 -1
+func (r *Request) ContentLength() int64 {
+	switch {
+	case r.src == nil || r.State == requestStateDone:
+		return int64(len(r.Body))
+	case r.chunked:
+		return -1
+	case r.pending != nil:
+		return r.pending.(*transfer.LengthReader).Remaining()
+	default:
+		return r.contentLength
 	}
+}
 
-	// 현재 들어온 데이터 조각 내에서 몇 바이트 파싱되었는지 기록하는 변수 선언
-	totalBytesParsed := 0
-	for r.State != requestStateDone {
+// 연결에서 아직 읽지 않은 body를 읽는 reader (Request.BodyReader)
+type bodyReader struct {
+	r *Request
+}
 
-		n, err := r.parseSingle(data[totalBytesParsed:])
-		if err != nil {
-			return 0, err
-		}
+func (b bodyReader) Read(p []byte) (int, error) {
+	r := b.r
+	if r.bodyErr != nil {
+		return 0, r.bodyErr
+	}
+	if r.src == nil || r.State == requestStateDone {
+		return 0, io.EOF
+	}
 
-		if n == 0 {
-			// 추가 데이터 조각이 parse 메소드로 더 들어올 필요가 있음
-			break
-		}
+	err := r.sendContinue()
+	if err != nil {
+		return 0, err
+	}
 
-		// 파싱된 바이트 길이 기록
-		totalBytesParsed += n
-		// r.parseSingle 실행 후 헤더라인까지 파싱이 끝난 상태면
-		// r.parseSingle은 2, nil을 반환하고 r.State == requestStateDone
-		// // => 다음 for 루프 실행 안됨
+	n, err := r.readPending(p)
+	switch {
+	case errors.Is(err, io.EOF):
+		r.State = requestStateDone
+		r.release()
+	case err != nil:
+		err = r.bodyError(err)
+		r.bodyErr = err
+		r.release()
 	}
 
-	return totalBytesParsed, nil
+	return n, err
 }
 
-func (r *Request) parseSingle(data []byte) (int, error) {
-	// r.State 값으로 switch 구성
-	switch r.State {
-	case requestStateInitialized:
-		// request line 파싱
-		n, err := parseRequestLine(string(data), r)
-		if err != nil {
-			return 0, err
-		}
-		if n == 0 {
-			// data에 아직 더 추가되어야 파싱 가능하다고
-			// 0, nil로 알리기
-			return 0, nil
-		}
+// 연결에서 읽을 body를 n 바이트까지로 제한하는 메소드 (n <= 0이면 제한 없음)
+// 넘으면 ReadBody와 BodyReader가 ErrBodyTooLarge를 반환한다
+// @@@ Content-Length가 n보다 크면 body를 읽지 않고 바로 에러, chunked body는 n+1 바이트째를 읽을 때 에러
+func (r *Request) SetMaxBodySize(n int64) {
+	r.maxBodySize = n
+}
 
-		// 리퀘스트 라인 파싱 완료 state로 변경
-		r.State = requestStateParsingHeaders
+// SetMaxBodySize로 정한 크기를 넘는 body인지 미리 알 수 있으면 true를 반환하는 메소드 (Content-Length로 판단)
+func (r *Request) BodyTooLarge() bool {
+	return r.maxBodySize > 0 && !r.chunked && r.contentLength > r.maxBodySize
+}
 
-		return n, nil
-	case requestStateParsingHeaders:
-		// 헤더라인 파싱
-		n, done, err := r.Headers.Parse(data)
-		if err != nil {
-			return 0, err
-		}
-		// 헤더 라인 파싱이 끝난 경우
-		if done {
-			r.State = requestStateParsingBody
-			return n, nil
-		}
+// SetExpectContinue로 설정된 함수가 있으면 body를 처음 읽기 전에 한번만 호출하는 메소드
+func (r *Request) sendContinue() error {
+	if r.expectContinue == nil || !r.ExpectsContinue() {
+		return nil
+	}
 
-		// n == 0 이건 아니건 done이 false이면 똑같이 n, nil 반환
-		return n, nil
-	case requestStateParsingBody:
-		contentLength := r.Headers.Get("Content-Length")
+	sendContinue := r.expectContinue
+	r.expectContinue = nil
+	err := sendContinue()
+	if err != nil {
+		r.bodyErr = err
+	}
 
-		// Content-Length 헤더가 없으면
-		if contentLength == "" {
+	return err
+}
+
+// client가 Expect: 100-continue를 보내고 아직 읽지 않은 body가 남아있는지 확인하는 메소드
+func (r *Request) ExpectsContinue() bool {
+	if r.State == requestStateDone || !strings.EqualFold(r.Headers.Get("expect"), "100-continue") {
+		return false
+	}
+
+	return r.chunked || r.contentLength > 0
+}
+
+// Expect: 100-continue request의 body를 처음 읽기 직전에 한번 호출할 함수를 설정하는 메소드 (server가 100 Continue를 보내는 데 사용)
+func (r *Request) SetExpectContinue(f func() error) {
+	r.expectContinue = f
+}
+
+// body까지 다 읽었는지 확인하는 메소드
+// reader 없이 만든 Request는 Body가 전부이므로 true
+func (r *Request) BodyComplete() bool {
+	return r.State == requestStateDone || (r.src == nil && r.bodyErr == nil)
+}
+
+// State가 target에 이를 때까지 src에서 읽으며 파싱하는 메소드
+// @@@ 구조 변경
+// @@@ 예전에는 8바이트 chunk를 매번 새로 만들어 읽고, 파싱할 때마다 남은 buffer를 새 slice로 복사하고,
+// @@@ 파싱 함수들이 buffer 전체를 string으로 바꿔 strings.Split 하느라 헤더가 길어질수록 느려졌다 (O(n^2))
+// @@@ 이제는 bufio.Reader의 버퍼에서 CRLF로 끝나는 줄을 하나씩 꺼내 복사 없이 파싱하고,
+// @@@ body는 Content-Length 만큼만 읽으므로 request 뒤의 바이트를 먼저 읽어버리지 않는다
+func (r *Request) readUntil(target int) error {
+	for r.State < target {
+		switch r.State {
+		case requestStateInitialized:
+			line, err := r.lines.ReadLine()
+			if err != nil {
+				return r.readLineError(err)
+			}
+			// request line 파싱 (CRLF 제외)
+			err = parseRequestLine(line[:len(line)-len(crlf)], r)
+			if err != nil {
+				return err
+			}
+			// 리퀘스트 라인 파싱 완료 state로 변경
+			r.State = requestStateParsingHeaders
+
+		case requestStateParsingHeaders:
+			// 빈 줄이 나올 때까지 헤더라인 파싱
+			err := r.lines.ReadHeaders(r.Headers)
+			if err != nil {
+				return r.readLineError(err)
+			}
+			err = r.startBody()
+			if err != nil {
+				return err
+			}
+			r.State = requestStateParsingBody
+			// body가 없으면 읽을 것이 없으므로 바로 done
+			if !r.chunked && r.contentLength == 0 {
+				r.State = requestStateDone
+			}
+
+		case requestStateParsingBody:
+			err := r.readBody()
+			if err != nil {
+				return err
+			}
 			r.State = requestStateDone
-			return 0, nil
+
+		default:
+			return ErrUnknownState
 		}
+	}
 
-		// string을 int로 변환
-		length, err := strconv.Atoi(contentLength)
-		if err != nil {
-			return 0, err
+	return nil
+}
+
+// request line과 헤더를 읽다가 실패했을 때 어디까지 읽었는지에 따라 에러를 고르는 메소드
+// @@@ 줄을 읽기 전에 reader가 끝나면 io.EOF, 줄 중간에 끝나면 io.ErrUnexpectedEOF (headers.LineReader)
+func (r *Request) readLineError(err error) error {
+	partial := errors.Is(err, io.ErrUnexpectedEOF)
+	if !partial && !errors.Is(err, io.EOF) {
+		// 헤더 형식 에러, ErrHeadersTooLarge는 그대로
+		if errors.Is(err, headers.ErrMalformed) || errors.Is(err, headers.ErrTooLarge) {
+			return err
 		}
+		return fmt.Errorf("error reading io reader: %w", err)
+	}
+
+	switch {
+	// reader에 들어있는 데이터가 없는 경우
+	case r.State == requestStateInitialized && !partial:
+		return ErrEmptyReader
+	// reader를 다 읽었는데도 파싱된 데이터가 없는 경우
+	case r.State == requestStateInitialized:
+		return ErrNotParsed
+	// request가 incomplete라 마지막에 파싱 불가능한 조각이 남은 경우
+	case partial:
+		return ErrIncompleteRequest
+	// reader를 다 읽었는데도 헤더의 끝을 알리는 빈 줄이 없는 경우
+	default:
+		return ErrMissingEndofHeaders
+	}
+}
 
-		// 들어온 데이터를 r.Body에 저장
-		r.Body = append(r.Body, data...)
+// 최대 이만큼만 body 버퍼를 미리 잡아둔다
+// @@@ Content-Length 값만 믿고 한 번에 할당하면 큰 값 하나로 메모리를 다 쓰게 만들 수 있다
+const maxBodyPrealloc = 64 * 1024
 
-		// 헤더의 Content-Length 값과 r.Body 길이 비교
-		if len(r.Body) > length {
-			return 0, ErrIncorrectContentLength
-		} else if len(r.Body) == length {
-			r.State = requestStateDone
-			// 이번에 들어온 데이터 조각을 더해 len(r.Body) == length 인 상태에서
-			// 추가로 남은 데이터 조각이 있으면?
+// 헤더를 다 읽은 뒤 body 길이를 알아내는 방식을 정하는 메소드 (RFC 9112 6.3)
+// Transfer-Encoding도 Content-Length도 없으면 body가 없는 것으로 본다
+// @@@ body를 읽기 전에 확인하므로 Expect: 100-continue request도 handler 호출 전에 거절할 수 있다
+func (r *Request) startBody() error {
+	te := r.Headers.Get("transfer-encoding")
+	cl := r.Headers.Get("content-length")
+
+	if te != "" {
+		if cl != "" {
+			return ErrConflictingFraming
+		}
+		// @@@ gzip 같은 다른 transfer coding은 풀 수 없고, chunked가 마지막이 아니면 body 끝을 알 수 없다
+		if !strings.EqualFold(strings.TrimSpace(te), "chunked") {
+			return fmt.Errorf("%w: %s", ErrUnsupportedTransferEncoding, te)
 		}
+		r.chunked = true
+		return nil
+	}
 
-		return len(data), nil
+	if cl == "" {
+		return nil
+	}
+	length, err := transfer.ParseContentLength(cl)
+	if err != nil {
+		return err
+	}
+	r.contentLength = length
 
-	default:
-		return 0, ErrUnknownState
+	return nil
+}
+
+// 읽기 시작한 body의 reader를 반환하는 메소드 (처음이면 Content-Length나 chunked에 맞는 reader를 만든다)
+func (r *Request) pendingBody() io.Reader {
+	if r.pending != nil {
+		return r.pending
+	}
+
+	if r.chunked {
+		r.Trailers = headers.NewHeaders()
+		r.pending = transfer.NewChunkedReader(r.src, r.Trailers)
+	} else {
+		r.pending = transfer.NewLengthReader(r.src, r.contentLength)
 	}
+
+	return r.pending
 }
 
-// raw 스트링을 받아서 그 안의 request line을 찾아내는 함수
-func parseRequestLine(raw string, req *Request) (int, error) {
-	// crlf("\r\n")이 포함되어 있지않으면 chunk를 더 읽어서 raw에 붙인 후 다시 이 함수를 실행하도록 일단 반환
-	if !strings.Contains(raw, crlf) {
-		return 0, nil
-		// 단순히 chunk를 더 읽고 다시 이 함수를 호출해야한다고 알 수 있도록
-		// 0, nil 반환
-		// 그 외의 경우는 raw 스트링의 길이를 반환
+// 읽기 시작한 body에서 SetMaxBodySize로 정한 크기까지만 읽는 메소드
+func (r *Request) readPending(p []byte) (int, error) {
+	if r.maxBodySize <= 0 {
+		return r.pendingBody().Read(p)
+	}
+	if r.BodyTooLarge() {
+		return 0, ErrBodyTooLarge
+	}
+
+	// @@@ 제한을 넘는지 알 수 있도록 남은 크기보다 1바이트 더 읽는다
+	if remaining := r.maxBodySize - r.bodyRead + 1; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := r.pendingBody().Read(p)
+	r.bodyRead += int64(n)
+	if r.bodyRead > r.maxBodySize {
+		return n, ErrBodyTooLarge
 	}
 
-	// HTTP는 각 줄 구분을 CRLF("\r\n")으로 한다
-	lines := strings.Split(raw, crlf)
+	return n, err
+}
+
+// body를 끝까지 읽어 r.Body에 저장하는 메소드
+func (r *Request) readBody() error {
+	body := r.pendingBody()
+	lengthReader, _ := body.(*transfer.LengthReader)
+
+	r.Body = make([]byte, 0, min(r.contentLength, maxBodyPrealloc))
+	for {
+		if len(r.Body) == cap(r.Body) {
+			grow := max(len(r.Body), 512)
+			if lengthReader != nil {
+				grow = min(grow, int(lengthReader.Remaining()))
+			}
+			r.Body = slices.Grow(r.Body, grow)
+		}
+
+		n, err := r.readPending(r.Body[len(r.Body):cap(r.Body)])
+		r.Body = r.Body[:len(r.Body)+n]
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return r.bodyError(err)
+		}
+	}
+}
 
-	// 이 함수는 request-line만 처리
+// body를 읽다가 난 에러를 request 에러로 바꾸는 메소드
+func (r *Request) bodyError(err error) error {
+	switch {
+	case errors.Is(err, io.ErrUnexpectedEOF) && r.chunked:
+		// reader를 다 읽었는데도 마지막 chunk가 없음
+		return ErrIncompleteRequest
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		// reader를 다 읽었는데도 body가 Content-Length보다 짧음
+		return ErrIncorrectContentLength
+	case errors.Is(err, transfer.ErrInvalidChunk), errors.Is(err, headers.ErrTooLarge), errors.Is(err, ErrBodyTooLarge):
+		return err
+	default:
+		return fmt.Errorf("error reading io reader: %w", err)
+	}
+}
+
+// request line(CRLF 제외)을 파싱해서 req에 저장하는 함수
+// @@@ 예전에는 raw 스트링 전체를 strings.Split 했지만 줄 단위로 받으므로 공백 위치만 찾는다
+func parseRequestLine(line []byte, req *Request) error {
 	// request-line은 공백 한칸으로 3 파트 분리
-	reqLineParts := strings.Split(lines[0], " ")
-	// @@@ 퍼플렉시티 추천은 reqLineParts := strings.Fields(lines[0]) ==> 이러면 공백이 복수개거나 \t인 경우도 3개 파트로 나눠진다
-	if len(reqLineParts) != 3 {
-		return 0, ErrInvalidRequestLine
+	// @@@ 퍼플렉시티 추천은 strings.Fields ==> 이러면 공백이 복수개거나 \t인 경우도 3개 파트로 나눠진다
+	method, rest, ok := bytes.Cut(line, []byte(" "))
+	target, version, ok2 := bytes.Cut(rest, []byte(" "))
+	if !ok || !ok2 || bytes.IndexByte(version, ' ') != -1 {
+		return ErrInvalidRequestLine
 	}
 
-	if !isUpper(reqLineParts[0]) {
-		return 0, ErrInvalidMethod
+	// @@@ isUpper는 빈 문자열도 true이므로 method가 비어있는지 따로 확인 (" / HTTP/1.1")
+	m := methodString(method)
+	if m == "" || !isUpper(m) {
+		return ErrInvalidMethod
 	}
 	// req 구조체에 method 입력
-	req.RequestLine.Method = reqLineParts[0]
+	req.RequestLine.Method = m
 
 	// 일단 HTTP/1.1만 지원
-	if reqLineParts[2] != "HTTP/1.1" {
-		return 0, ErrInvalidVersion
+	if string(version) != "HTTP/1.1" {
+		return ErrInvalidVersion
 	}
 	// req.RequestLine.HttpVersion 에는 HTTP/ 부분 없이 숫자 버전만 입력
-	version := strings.Split(reqLineParts[2], "/")[1]
-	// req 구조체에 http 버전 입력
-	req.RequestLine.HttpVersion = version
+	req.RequestLine.HttpVersion = "1.1"
+
+	// request-target은 비어있거나 공백, 제어 문자를 포함할 수 없다
+	t := string(target)
+	if !validRequestTarget(t) {
+		return ErrInvalidRequestTarget
+	}
 	// req 구조체에 request-target 입력
-	req.RequestLine.RequestTarget = reqLineParts[1]
+	req.RequestLine.RequestTarget = t
+
+	return nil
+}
+
+// 자주 쓰는 method는 미리 만들어진 string을 반환하는 함수 (매번 새 string을 할당하지 않도록)
+// @@@ switch string(b)는 컴파일러가 할당 없이 비교한다
+func methodString(b []byte) string {
+	switch string(b) {
+	case "GET":
+		return "GET"
+	case "HEAD":
+		return "HEAD"
+	case "POST":
+		return "POST"
+	case "PUT":
+		return "PUT"
+	case "PATCH":
+		return "PATCH"
+	case "DELETE":
+		return "DELETE"
+	case "OPTIONS":
+		return "OPTIONS"
+	case "CONNECT":
+		return "CONNECT"
+	}
+	return string(b)
+}
+
+// 같은 request를 여러 번 보내도 결과가 같은 method인지 확인하는 함수 (RFC 9110 9.2.2)
+// @@@ client와 proxy가 실패한 request를 다시 보내도 되는지 정할 때 사용
+func Idempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE":
+		return true
+	default:
+		return false
+	}
+}
 
-	// 파싱된 request line 길이 + crlf 길이(2) 반환
-	return len(lines[0]) + 2, nil
+// request-target이 비어있지 않고 공백이나 제어 문자가 없는지 확인하는 함수
+func validRequestTarget(target string) bool {
+	return target != "" && strings.IndexFunc(target, func(c rune) bool { return c <= ' ' || c == 0x7f }) == -1
 }
 
 // unicode.IsUpper를 이용해 입력된 string이 대문자로만 이루어져있는지 확인하는 함수
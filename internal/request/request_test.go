@@ -5,6 +5,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -107,6 +108,14 @@ func TestRequestLineParse(t *testing.T) {
 	require.Error(t, err)
 	require.ErrorIs(t, err, ErrInvalidMethod)
 
+	// Test: Empty method or request target in request line
+	_, err = RequestFromReader(strings.NewReader(" /coffee HTTP/1.1\r\nHost: localhost:42069\r\n\r\n"))
+	require.ErrorIs(t, err, ErrInvalidMethod)
+	_, err = RequestFromReader(strings.NewReader("GET  HTTP/1.1\r\nHost: localhost:42069\r\n\r\n"))
+	require.ErrorIs(t, err, ErrInvalidRequestTarget)
+	_, err = RequestFromReader(strings.NewReader("GET /cof\x00fee HTTP/1.1\r\nHost: localhost:42069\r\n\r\n"))
+	require.ErrorIs(t, err, ErrInvalidRequestTarget)
+
 	// Test: Invalid version in request line
 	reader = &chunkReader{
 		data:            "GET /coffee HTTP/2.0\r\nHost: localhost:42069\r\nUser-Agent: curl/7.81.0\r\nAccept: */*\r\n\r\n",
@@ -271,17 +280,44 @@ func TestRequestLineParse(t *testing.T) {
 	require.ErrorIs(t, err, ErrIncorrectContentLength)
 
 	// Test: Body longer than reported content length
+	// @@@ Content-Length 뒤의 바이트는 다음 request(pipelining)이므로 에러가 아니고, 읽히는 크기와 상관없이 같은 결과
+	for _, n := range []int{1, 3, 100} {
+		reader = &chunkReader{
+			data: "POST /submit HTTP/1.1\r\n" +
+				"Host: localhost:42069\r\n" +
+				"Content-Length: 4\r\n" +
+				"\r\n" +
+				"long content",
+			numBytesPerRead: n,
+		}
+		r, err = RequestFromReader(reader)
+		require.NoError(t, err)
+		assert.Equal(t, "long", string(r.Body))
+	}
+
+	// Test: CR 없이 LF로 끝나는 줄은 거절
 	reader = &chunkReader{
-		data: "POST /submit HTTP/1.1\r\n" +
-			"Host: localhost:42069\r\n" +
-			"Content-Length: 4\r\n" +
-			"\r\n" +
-			"long content",
+		data:            "GET / HTTP/1.1\r\nHost: localhost:42069\n\r\n",
 		numBytesPerRead: 3,
 	}
 	_, err = RequestFromReader(reader)
-	require.Error(t, err)
-	require.ErrorIs(t, err, ErrIncorrectContentLength)
+	require.ErrorIs(t, err, headers.ErrBareLF)
+
+	// Test: 헤더 블록이 headers.MaxHeaderBytes를 넘으면 ErrHeadersTooLarge
+	reader = &chunkReader{
+		data:            "GET / HTTP/1.1\r\nX-Long: " + strings.Repeat("a", headers.MaxHeaderBytes) + "\r\n\r\n",
+		numBytesPerRead: 4096,
+	}
+	_, err = RequestFromReader(reader)
+	require.ErrorIs(t, err, ErrHeadersTooLarge)
+
+	// Test: 필드 수가 headers.MaxFields를 넘어도 ErrHeadersTooLarge
+	reader = &chunkReader{
+		data:            "GET / HTTP/1.1\r\n" + strings.Repeat("X-A: b\r\n", headers.MaxFields+1) + "\r\n",
+		numBytesPerRead: 4096,
+	}
+	_, err = RequestFromReader(reader)
+	require.ErrorIs(t, err, ErrHeadersTooLarge)
 
 	// @@@ 퍼플렉시티 추천
 	// Test: Empty request line
@@ -322,3 +358,12 @@ func TestRequestLineParse(t *testing.T) {
 	// require.Error(t, err)
 	// require.ErrorIs(t, err, ErrInvalidMethod)
 }
+
+func TestIdempotent(t *testing.T) {
+	for _, method := range []string{"GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE"} {
+		assert.True(t, Idempotent(method), method)
+	}
+	for _, method := range []string{"POST", "PATCH", "CONNECT", "get"} {
+		assert.False(t, Idempotent(method), method)
+	}
+}
@@ -0,0 +1,161 @@
+package request
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/textproto"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/transfer"
+)
+
+var ErrInvalidRequestTarget = errors.New("request target must not be empty or contain whitespace or control characters")
+var ErrInvalidHeaderValue = headers.ErrInvalidValue
+
+// request를 HTTP/1.1 형식으로 w에 쓰는 메소드 (io.WriterTo)
+// 같은 request는 항상 같은 바이트로 쓰도록 Host를 맨 앞에, 나머지 헤더는 이름 순으로 쓰고
+// 헤더 이름은 보통 쓰는 형태(ex: Content-Type)로 바꾼다
+// body는 길이를 알면 Content-Length로 (body가 없으면 원래 content-length 헤더가 있었을 때만 0으로), 모르면 chunked로 보낸다
+// @@@ 원래 transfer-encoding 헤더는 쓰지 않고 body를 보내는 방식에 맞춰 새로 쓴다
+// body를 아직 읽지 않은 request면 Body에 모으지 않고 BodyReader로 읽으면서 바로 보낸다
+func (r *Request) WriteTo(w io.Writer) (int64, error) {
+	if r.bodyErr != nil {
+		return 0, r.bodyErr
+	}
+
+	err := r.Validate()
+	if err != nil {
+		return 0, err
+	}
+
+	body, length := r.outgoingBody()
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(r.RequestLine.Method + " " + r.RequestLine.RequestTarget + " HTTP/1.1" + crlf)
+
+	keys := make([]string, 0, len(r.Headers))
+	host, hasHost, hasLength := "", false, false
+	for key, value := range r.Headers {
+		switch strings.ToLower(key) {
+		case "host":
+			host, hasHost = value, true
+		case "content-length":
+			hasLength = true
+		case "transfer-encoding":
+		default:
+			keys = append(keys, key)
+		}
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	})
+
+	// @@@ RFC 9112 3.2: Host는 request line 바로 뒤에 오는 것이 좋다
+	if hasHost {
+		writeHeader(buf, "host", host)
+	}
+	for _, key := range keys {
+		writeHeader(buf, key, r.Headers[key])
+	}
+	switch {
+	case length < 0:
+		writeHeader(buf, "transfer-encoding", "chunked")
+	case hasLength || length > 0:
+		writeHeader(buf, "content-length", strconv.FormatInt(length, 10))
+	}
+	buf.WriteString(crlf)
+
+	if body == nil {
+		buf.Write(r.Body)
+		n, err := w.Write(buf.Bytes())
+		return int64(n), err
+	}
+
+	cw := &countingWriter{w: w}
+	_, err = cw.Write(buf.Bytes())
+	if err != nil {
+		return cw.n, err
+	}
+
+	if length < 0 {
+		chunked := transfer.NewChunkedWriter(cw)
+		_, err = io.Copy(chunked, body)
+		if err == nil {
+			err = chunked.Close()
+		}
+		return cw.n, err
+	}
+
+	_, err = io.CopyN(cw, body, length)
+	if errors.Is(err, io.EOF) {
+		// @@@ 알려준 길이보다 짧은 body를 보내면 서버가 다음 request를 body로 읽게 된다
+		err = ErrIncorrectContentLength
+	}
+	return cw.n, err
+}
+
+// 실제로 쓴 바이트 수를 세는 writer (chunk 크기 줄까지 포함한 WriteTo의 반환값)
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// WriteTo가 보낼 body와 그 길이를 고르는 메소드 (길이를 모르면 -1)
+// Body를 그대로 보내면 nil reader와 Body 길이를 반환한다
+func (r *Request) outgoingBody() (io.Reader, int64) {
+	if r.stream != nil {
+		return r.stream, r.streamLength
+	}
+	if r.src != nil && r.State != requestStateDone {
+		return r.BodyReader(), r.ContentLength()
+	}
+
+	return nil, int64(len(r.Body))
+}
+
+// WriteTo가 Body 대신 body에서 읽어서 보내도록 설정하는 메소드 (client가 body를 메모리에 모으지 않고 보낼 때 사용)
+// length가 0 이상이면 Content-Length로 그 길이만큼, -1이면 chunked로 body가 끝날 때까지 보낸다
+func (r *Request) SetBodyReader(body io.Reader, length int64) {
+	r.stream = body
+	r.streamLength = length
+}
+
+func writeHeader(buf *bytes.Buffer, key, value string) {
+	buf.WriteString(textproto.CanonicalMIMEHeaderKey(key) + ": " + value + crlf)
+}
+
+// WriteTo로 쓰면 다시 파싱할 수 없는 값이 있는지 확인하는 메소드 (client가 연결하기 전에 확인하는 데도 사용)
+// @@@ 헤더 값에 CRLF가 들어가면 헤더나 request를 끼워넣을 수 있으므로 쓰지 않고 에러 반환
+func (r *Request) Validate() error {
+	if r.RequestLine.Method == "" || !isUpper(r.RequestLine.Method) {
+		return ErrInvalidMethod
+	}
+	if v := r.RequestLine.HttpVersion; v != "" && v != "1.1" {
+		return ErrInvalidVersion
+	}
+
+	if !validRequestTarget(r.RequestLine.RequestTarget) {
+		return ErrInvalidRequestTarget
+	}
+
+	for key, value := range r.Headers {
+		if headers.ContainsInvalidChar(key) {
+			return headers.ErrInvalidName
+		}
+		if strings.ContainsAny(value, "\r\n\x00") {
+			return ErrInvalidHeaderValue
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,236 @@
+package request
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteTo(t *testing.T) {
+	r := &Request{
+		RequestLine: RequestLine{Method: "POST", RequestTarget: "/coffee?size=large", HttpVersion: "1.1"},
+		Headers: headers.Headers{
+			"user-agent":        "curl/8.5.0",
+			"accept":            "*/*",
+			"host":              "localhost:42069",
+			"content-type":      "application/json",
+			"transfer-encoding": "chunked",
+			"x-request-id":      "abc",
+		},
+		Body: []byte(`{"shots":2}`),
+	}
+
+	// Test: Host가 먼저, 나머지는 이름 순, body는 Content-Length로
+	buf := &bytes.Buffer{}
+	n, err := r.WriteTo(buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+	assert.Equal(t, "POST /coffee?size=large HTTP/1.1\r\n"+
+		"Host: localhost:42069\r\n"+
+		"Accept: */*\r\n"+
+		"Content-Type: application/json\r\n"+
+		"User-Agent: curl/8.5.0\r\n"+
+		"X-Request-Id: abc\r\n"+
+		"Content-Length: 11\r\n"+
+		"\r\n"+
+		`{"shots":2}`, buf.String())
+
+	// Test: body가 없으면 Content-Length도 없다
+	r = &Request{
+		RequestLine: RequestLine{Method: "GET", RequestTarget: "/", HttpVersion: "1.1"},
+		Headers:     headers.Headers{"host": "example.com"},
+	}
+	buf.Reset()
+	_, err = r.WriteTo(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n", buf.String())
+}
+
+func TestWriteToStreamsPendingBody(t *testing.T) {
+	r, err := RequestHeadersFromReader(strings.NewReader("PUT /items/1 HTTP/1.1\r\nHost: localhost\r\nContent-Length: 5\r\n\r\nhello"))
+	require.NoError(t, err)
+	require.False(t, r.BodyComplete())
+
+	// Test: 아직 읽지 않은 body는 Body에 모으지 않고 읽는 대로 쓴다
+	buf := &bytes.Buffer{}
+	_, err = r.WriteTo(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "PUT /items/1 HTTP/1.1\r\nHost: localhost\r\nContent-Length: 5\r\n\r\nhello", buf.String())
+	assert.True(t, r.BodyComplete())
+	assert.Empty(t, r.Body)
+
+	// Test: chunked body는 길이를 모르므로 chunked로 다시 쓴다
+	r, err = RequestHeadersFromReader(strings.NewReader("POST / HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\n\r\n2\r\nhi\r\n0\r\n\r\n"))
+	require.NoError(t, err)
+	buf.Reset()
+	_, err = r.WriteTo(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "POST / HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\n\r\n2\r\nhi\r\n0\r\n\r\n", buf.String())
+
+	// Test: body가 Content-Length보다 짧으면 에러
+	r, err = RequestHeadersFromReader(strings.NewReader("PUT / HTTP/1.1\r\nHost: localhost\r\nContent-Length: 10\r\n\r\nshort"))
+	require.NoError(t, err)
+	_, err = r.WriteTo(&bytes.Buffer{})
+	require.ErrorIs(t, err, ErrIncorrectContentLength)
+}
+
+func TestSetBodyReader(t *testing.T) {
+	r := &Request{
+		RequestLine: RequestLine{Method: "POST", RequestTarget: "/upload", HttpVersion: "1.1"},
+		Headers:     headers.Headers{"host": "example.com"},
+	}
+
+	// Test: 길이를 알면 Content-Length로 그 길이만큼만 보낸다
+	r.SetBodyReader(strings.NewReader("hello, world"), 5)
+	buf := &bytes.Buffer{}
+	n, err := r.WriteTo(buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+	assert.Equal(t, "POST /upload HTTP/1.1\r\nHost: example.com\r\nContent-Length: 5\r\n\r\nhello", buf.String())
+
+	// Test: 길이를 모르면 chunked
+	r.SetBodyReader(strings.NewReader("hello"), -1)
+	buf.Reset()
+	n, err = r.WriteTo(buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+	assert.Equal(t, "POST /upload HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n0\r\n\r\n", buf.String())
+
+	// Test: 알려준 길이보다 짧으면 에러
+	r.SetBodyReader(strings.NewReader("hi"), 5)
+	_, err = r.WriteTo(&bytes.Buffer{})
+	require.ErrorIs(t, err, ErrIncorrectContentLength)
+}
+
+func TestWriteToInvalid(t *testing.T) {
+	valid := func() *Request {
+		return &Request{
+			RequestLine: RequestLine{Method: "GET", RequestTarget: "/", HttpVersion: "1.1"},
+			Headers:     headers.Headers{"host": "localhost"},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(r *Request)
+		err    error
+	}{
+		{"lowercase method", func(r *Request) { r.RequestLine.Method = "get" }, ErrInvalidMethod},
+		{"empty method", func(r *Request) { r.RequestLine.Method = "" }, ErrInvalidMethod},
+		{"other version", func(r *Request) { r.RequestLine.HttpVersion = "2" }, ErrInvalidVersion},
+		{"space in target", func(r *Request) { r.RequestLine.RequestTarget = "/a b" }, ErrInvalidRequestTarget},
+		{"empty target", func(r *Request) { r.RequestLine.RequestTarget = "" }, ErrInvalidRequestTarget},
+		{"invalid header name", func(r *Request) { r.Headers["bad name"] = "x" }, headers.ErrInvalidName},
+		// Test: 헤더 값으로 다른 헤더를 끼워넣을 수 없다
+		{"header injection", func(r *Request) { r.Headers["x-user"] = "bob\r\nX-Admin: true" }, ErrInvalidHeaderValue},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := valid()
+			tc.modify(r)
+			buf := &bytes.Buffer{}
+			_, err := r.WriteTo(buf)
+			assert.ErrorIs(t, err, tc.err)
+			assert.Zero(t, buf.Len())
+		})
+	}
+}
+
+// testing/quick으로 만드는 임의의 올바른 request
+type randomRequest struct {
+	*Request
+}
+
+const tokenChars = "abcdefghijklmnopqrstuvwxyz0123456789!#$%&'*+-.^_`|~"
+const targetChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-._~/?=&%:@"
+
+func randomString(rand *rand.Rand, chars string, minLen, maxLen int) string {
+	b := make([]byte, minLen+rand.Intn(maxLen-minLen+1))
+	for i := range b {
+		b[i] = chars[rand.Intn(len(chars))]
+	}
+	return string(b)
+}
+
+// quick.Generator 구현
+func (randomRequest) Generate(rand *rand.Rand, size int) reflect.Value {
+	r := &Request{
+		RequestLine: RequestLine{
+			Method:        randomString(rand, "ABCDEFGHIJKLMNOPQRSTUVWXYZ", 1, 10),
+			RequestTarget: "/" + randomString(rand, targetChars, 0, 40),
+			HttpVersion:   "1.1",
+		},
+		Headers: headers.NewHeaders(),
+	}
+	r.Headers["host"] = randomString(rand, targetChars, 1, 20)
+
+	for range rand.Intn(10) {
+		name := randomString(rand, tokenChars, 1, 16)
+		switch name {
+		case "host", "content-length", "transfer-encoding":
+			continue
+		}
+		// 헤더 값은 보이는 ASCII 문자와 중간의 공백, 탭 (앞뒤 공백은 파서가 지운다)
+		value := []byte{}
+		for range rand.Intn(30) {
+			if rand.Intn(8) == 0 {
+				value = append(value, " \t"[rand.Intn(2)])
+				continue
+			}
+			value = append(value, byte(0x21+rand.Intn(0x7e-0x21+1)))
+		}
+		r.Headers[name] = strings.Trim(string(value), " \t")
+	}
+
+	if rand.Intn(2) == 0 {
+		r.Body = make([]byte, rand.Intn(size*8+1))
+		rand.Read(r.Body)
+	}
+	if len(r.Body) > 0 || rand.Intn(2) == 0 {
+		r.Headers["content-length"] = strconv.Itoa(len(r.Body))
+	}
+
+	return reflect.ValueOf(randomRequest{r})
+}
+
+func TestWriteToRoundTripProperty(t *testing.T) {
+	// Property: WriteTo로 쓴 request를 파싱하면 원래 request와 같고,
+	// 파싱한 request를 다시 쓰면 같은 바이트가 나온다 (한 번에 읽히는 크기와 상관없이)
+	property := func(in randomRequest, readSize uint8) bool {
+		written := &bytes.Buffer{}
+		_, err := in.WriteTo(written)
+		if err != nil {
+			t.Logf("write: %v", err)
+			return false
+		}
+
+		parsed, err := RequestFromReader(&chunkReader{data: written.String(), numBytesPerRead: int(readSize)%64 + 1})
+		if err != nil {
+			t.Logf("parse %q: %v", written.String(), err)
+			return false
+		}
+
+		if parsed.RequestLine != in.RequestLine ||
+			!reflect.DeepEqual(parsed.Headers, in.Headers) ||
+			!bytes.Equal(parsed.Body, in.Body) {
+			t.Logf("round trip mismatch:\n%+v\n%+v", in.Request, parsed)
+			return false
+		}
+
+		rewritten := &bytes.Buffer{}
+		_, err = parsed.WriteTo(rewritten)
+		return err == nil && bytes.Equal(written.Bytes(), rewritten.Bytes())
+	}
+
+	err := quick.Check(property, &quick.Config{MaxCount: 500})
+	require.NoError(t, err)
+}
@@ -0,0 +1,167 @@
+package response
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"strconv"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+)
+
+// Content-Length가 이 크기 이하인 body는 인코딩 결과를 메모리에 모았다가 Content-Length와 함께 보낸다
+// (더 크거나 길이를 모르면 chunked encoding으로 바로바로 보낸다)
+const bufferedEncodeLimit = 64 * 1024
+
+// body를 Content-Encoding에 맞게 변환하는 writer (gzip.Writer, zlib.Writer 등)
+// Flush는 지금까지 받은 데이터를 압축 상태와 상관없이 다 내보내는 메소드 (스트리밍용)
+type Encoder interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// response의 body를 변환할 Encoder를 고르는 함수
+// WriteHeaders가 헤더를 쓰기 직전에 status code와 헤더로 호출하며, 헤더를 고칠 수 있다 (Content-Encoding, Vary 등)
+// dst로 쓰는 Encoder를 반환하면 이후 body는 Encoder를 거쳐서 보내지고, nil을 반환하면 body를 그대로 보낸다
+type EncoderFunc func(code StatusCode, h headers.Headers, dst io.Writer) Encoder
+
+// WriteHeaders 때 body를 변환할 Encoder를 고르는 함수를 설정하는 메소드 (압축 middleware 등에서 사용)
+// 헤더를 쓰기 전에만 설정할 수 있고, Encoder가 선택되었으면 body를 다 쓴 뒤 FinishEncoding을 호출해야 한다
+func (w *Writer) SetEncoder(f EncoderFunc) error {
+	if w.State > WriterStateStatusLineDone {
+		return ErrWriterInvalidState
+	}
+
+	w.encode = f
+
+	return nil
+}
+
+// Encoder가 선택되어 body가 변환되고 있는지 확인하는 메소드
+func (w *Writer) Encoding() bool {
+	return w.encoder != nil
+}
+
+// WriteHeaders에서 Encoder를 고르고 framing 헤더를 바꾸는 메소드
+// Encoder가 선택되면
+// - Content-Length가 bufferedEncodeLimit 이하: 인코딩 결과를 모았다가 FinishEncoding 때 헤더와 함께 작성
+// - 그 외: Content-Length를 지우고 Transfer-Encoding: chunked로 바로바로 작성
+func (w *Writer) startEncoder(h headers.Headers) {
+	length, err := strconv.Atoi(h.Lookup("Content-Length"))
+	buffered := err == nil && length <= bufferedEncodeLimit
+
+	var dst io.Writer = chunkWriter{w}
+	var buf *bytes.Buffer
+	if buffered {
+		buf = &bytes.Buffer{}
+		dst = buf
+	}
+
+	enc := w.encode(w.statusCode, h, dst)
+	if enc == nil {
+		return
+	}
+
+	w.encoder = enc
+
+	if buffered {
+		// @@@ handler가 WriteHeaders 뒤에 헤더 맵을 다시 고쳐 쓸 수도 있으므로 복사해두기
+		w.encodeBuf = buf
+		w.pending = maps.Clone(h)
+		return
+	}
+
+	h.Delete("Content-Length")
+	h.Delete("Transfer-Encoding")
+	h.SetOverride("Transfer-Encoding", "chunked")
+}
+
+// body 변환을 끝내는 메소드 (Encoder가 선택되지 않았으면 아무것도 하지 않는다)
+// Encoder에 남은 데이터를 내보내고, 모아둔 경우에는 Content-Length를 정해서 헤더와 body를,
+// chunked인 경우에는 마지막 chunk를 작성한다
+// @@@ handler가 WriteChunkedBodyDone을 호출한 경우에는 이미 끝난 상태이므로 다시 하지 않는다
+func (w *Writer) FinishEncoding() error {
+	if w.encoder == nil {
+		return nil
+	}
+
+	chunked := w.pending == nil
+
+	err := w.closeEncoder()
+	if err != nil {
+		return err
+	}
+
+	if chunked {
+		w.Data = append(w.Data, []byte("0\r\n\r\n")...)
+	}
+	w.State = WriterStateDone
+
+	return nil
+}
+
+// Encoder를 닫고, 모아둔 경우에는 헤더와 body를 Data에 작성하는 메소드
+func (w *Writer) closeEncoder() error {
+	enc := w.encoder
+	w.encoder = nil
+
+	err := enc.Close()
+	if err != nil {
+		return err
+	}
+
+	if w.pending == nil {
+		return nil
+	}
+
+	h := w.pending
+	w.pending = nil
+
+	h.Delete("Content-Length")
+	h.SetOverride("Content-Length", strconv.Itoa(w.encodeBuf.Len()))
+	w.appendHeaders(h)
+
+	w.bytesWritten += w.encodeBuf.Len()
+	w.Data = append(w.Data, w.encodeBuf.Bytes()...)
+	w.encodeBuf = nil
+
+	return nil
+}
+
+// handler가 쓴 body 데이터를 Encoder로 넘기는 메소드
+// @@@ chunked 모드에서는 Encoder가 chunkWriter로 쓰면서 Data가 커지므로 여기서도 flushThreshold 확인
+func (w *Writer) writeEncoded(p []byte) (int, error) {
+	n, err := w.encoder.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	if len(w.Data) >= flushThreshold {
+		err = w.flushData()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return n, nil
+}
+
+// Encoder의 출력을 chunk로 나눠서 Writer의 Data에 작성하는 io.Writer
+type chunkWriter struct {
+	w *Writer
+}
+
+func (c chunkWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	c.w.Data = append(c.w.Data, []byte(fmt.Sprintf("%x\r\n", len(p)))...)
+	c.w.Data = append(c.w.Data, p...)
+	c.w.Data = append(c.w.Data, []byte("\r\n")...)
+
+	c.w.bytesWritten += len(p)
+
+	return len(p), nil
+}
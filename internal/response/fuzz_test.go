@@ -0,0 +1,62 @@
+package response
+
+import (
+	"bytes"
+	"maps"
+	"strconv"
+	"testing"
+)
+
+const chunkedHead = "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n"
+
+// body를 size 바이트씩 나눈 chunk들과 trailers로 chunked body를 만드는 함수
+func encodeChunked(body []byte, size int, trailers map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	for len(body) > 0 {
+		n := min(size, len(body))
+		buf.WriteString(strconv.FormatInt(int64(n), 16) + crlf)
+		buf.Write(body[:n])
+		buf.WriteString(crlf)
+		body = body[n:]
+	}
+	buf.WriteString("0" + crlf)
+	for key, value := range trailers {
+		buf.WriteString(key + ": " + value + crlf)
+	}
+	buf.WriteString(crlf)
+	return buf.Bytes()
+}
+
+// seed corpus는 testdata/fuzz/FuzzChunkedBody (curl로 받은 chunked response body들)
+func FuzzChunkedBody(f *testing.F) {
+	f.Add([]byte("5\r\nhello\r\n7;ext=1\r\n, world\r\n0\r\n\r\n"), uint8(3))
+	f.Add([]byte("A\r\n0123456789\r\n0\r\nX-Checksum: 42\r\n\r\n"), uint8(1))
+
+	f.Fuzz(func(t *testing.T, chunked []byte, readSize uint8) {
+		raw := chunkedHead + string(chunked)
+		want, wantErr := ResponseFromReader(&chunkReader{data: raw, numBytesPerRead: 1})
+
+		// Property: 한 번에 읽히는 크기와 상관없이 같은 결과
+		n := int(readSize)%64 + 1
+		got, err := ResponseFromReader(&chunkReader{data: raw, numBytesPerRead: n})
+		if (err == nil) != (wantErr == nil) {
+			t.Fatalf("read size %d: error %v, with 1 byte reads: %v", n, err, wantErr)
+		}
+		if wantErr != nil {
+			return
+		}
+		if !bytes.Equal(got.Body, want.Body) || !maps.Equal(got.Trailers, want.Trailers) {
+			t.Fatalf("read size %d: body %q trailers %v, with 1 byte reads: %q %v", n, got.Body, got.Trailers, want.Body, want.Trailers)
+		}
+
+		// Property: 풀어낸 body와 trailers를 다른 크기의 chunk로 다시 인코딩해도 같은 결과
+		reencoded := chunkedHead + string(encodeChunked(want.Body, n, want.Trailers))
+		again, err := ResponseFromReader(&chunkReader{data: reencoded, numBytesPerRead: 7})
+		if err != nil {
+			t.Fatalf("re-encoded body cannot be parsed: %v\n%q", err, reencoded)
+		}
+		if !bytes.Equal(again.Body, want.Body) || !maps.Equal(again.Trailers, want.Trailers) {
+			t.Fatalf("round trip changed body: %q %v\n%q %v", want.Body, want.Trailers, again.Body, again.Trailers)
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package response
+
+import (
+	"bufio"
+	"errors"
+	"net"
+)
+
+var ErrNotHijackable = errors.New("writer is not attached to a connection that can be hijacked")
+var ErrHijacked = errors.New("connection has already been hijacked")
+
+// 연결을 handler에게 넘겨주는 함수 (server가 Writer를 만들 때 설정)
+// 반환하는 bufio.Reader에는 server가 연결에서 읽었지만 아직 파싱하지 않은 바이트가 남아있을 수 있다
+type Hijacker func() (net.Conn, *bufio.Reader, error)
+
+// Hijack 때 호출할 함수를 설정하는 메소드 (server가 사용)
+func (w *Writer) SetHijacker(h Hijacker) {
+	w.hijacker = h
+}
+
+// 연결을 handler가 직접 다루도록 넘겨받는 메소드 (WebSocket, CONNECT 터널 등)
+// 그때까지 작성된 Data는 먼저 연결로 내보내며, 이후 server는 이 연결을 읽거나 쓰거나 닫지 않는다
+// 반환된 bufio.Reader에는 server가 이미 읽었지만 request로 파싱하지 않은 바이트가 있을 수 있으므로
+// 연결에서 읽을 때는 net.Conn 대신 이 Reader를 써야 한다
+// Hijack 뒤에는 Writer의 쓰기 메소드들이 ErrHijacked를 반환하며, 연결을 닫는 것은 호출한 쪽의 책임
+func (w *Writer) Hijack() (net.Conn, *bufio.Reader, error) {
+	if w.hijacker == nil {
+		return nil, nil, ErrNotHijackable
+	}
+	if w.hijacked {
+		return nil, nil, ErrHijacked
+	}
+
+	err := w.flushData()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn, reader, err := w.hijacker()
+	if err != nil {
+		return nil, nil, err
+	}
+	w.hijacked = true
+
+	return conn, reader, nil
+}
+
+// Hijack으로 연결을 넘겨줬는지 확인하는 메소드
+func (w *Writer) Hijacked() bool {
+	return w.hijacked
+}
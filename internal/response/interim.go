@@ -0,0 +1,49 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+)
+
+var ErrNotInterimStatus = errors.New("interim responses must use a 1xx status code other than 101")
+var ErrInterimStatus = errors.New("1xx status codes other than 101 must be sent with WriteInterim")
+
+// 최종 response 전에 보내는 1xx interim response인지 확인하는 함수
+// @@@ 101 Switching Protocols는 프로토콜이 바뀌기 전의 마지막 HTTP response이므로 WriteStatusLine으로 쓴다
+func interim(code StatusCode) bool {
+	return code >= 100 && code < 200 && code != StatusSwitchingProtocols
+}
+
+// 1xx interim response를 헤더와 함께 바로 dst로 내보내는 메소드 (ex: 103 Early Hints에 Link 헤더)
+// 최종 response의 status line을 쓰기 전이면 몇 번이든 호출할 수 있고, 그 뒤에는 ErrWriterInvalidState
+// interim response의 헤더는 최종 response의 연결 유지나 body framing에 영향을 주지 않는다
+func (w *Writer) WriteInterim(code StatusCode, h headers.Headers) error {
+	if w.hijacked {
+		return ErrHijacked
+	}
+
+	if w.State != WriterStateInitialized {
+		return ErrWriterInvalidState
+	}
+
+	if !interim(code) {
+		return ErrNotInterimStatus
+	}
+
+	w.Data = append(w.Data, []byte(fmt.Sprintf("HTTP/1.1 %d %s\r\n", code, StatusText(code)))...)
+	for key, value := range h {
+		w.Data = append(w.Data, []byte(key+": "+value+"\r\n")...)
+	}
+	w.Data = append(w.Data, []byte("\r\n")...)
+
+	// client가 최종 response를 기다리는 동안 먼저 받아볼 수 있도록 바로 내보낸다
+	return w.flushData()
+}
+
+// 100 Continue interim response를 바로 dst로 내보내는 메소드
+// Expect: 100-continue를 보낸 client는 이 response를 받은 뒤에 body를 보낸다
+func (w *Writer) WriteContinue() error {
+	return w.WriteInterim(StatusContinue, nil)
+}
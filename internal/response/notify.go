@@ -0,0 +1,20 @@
+package response
+
+// client가 연결을 끊었는지 지켜보기 시작하고 그 결과를 알려줄 채널을 반환하는 함수 (server가 Writer를 만들 때 설정)
+type CloseNotifier func() <-chan struct{}
+
+// CloseNotify 때 호출할 함수를 설정하는 메소드 (server가 사용)
+func (w *Writer) SetCloseNotifier(n CloseNotifier) {
+	w.closeNotifier = n
+}
+
+// client가 연결을 끊거나 server가 종료를 시작하면 닫히는 채널을 반환하는 메소드
+// SSE처럼 오래 이어지는 response를 쓰는 handler가 언제 멈춰야 하는지 알 수 있게 한다
+// 연결이 없는 Writer(테스트 등)에서는 닫히지 않는 nil 채널을 반환한다
+// @@@ 처음 호출할 때 server가 연결을 백그라운드로 읽기 시작하므로 필요할 때만 호출
+func (w *Writer) CloseNotify() <-chan struct{} {
+	if w.closeNotifier == nil {
+		return nil
+	}
+	return w.closeNotifier()
+}
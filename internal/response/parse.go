@@ -0,0 +1,214 @@
+package response
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/transfer"
+)
+
+// ResponseFromReader로 파싱한 HTTP response
+type Response struct {
+	StatusLine StatusLine
+	Headers    headers.Headers // key는 소문자
+	Body       []byte          // chunked body는 chunk들을 이어붙인 값
+	Trailers   headers.Headers // chunked body 뒤에 온 trailer 필드들 (key는 소문자)
+	State      int             // 파싱 상태를 알리는 State
+
+	// HEAD request에 대한 response면 Content-Length가 있어도 body가 없다
+	method string
+}
+
+type StatusLine struct {
+	HttpVersion  string
+	StatusCode   StatusCode
+	ReasonPhrase string
+}
+
+// ResponseFromReader에 넘겨 파싱 방식을 바꾸는 옵션 함수 타입
+type ParseOption func(*Response)
+
+// 어떤 method의 request에 대한 response인지 알려주는 옵션 (기본값 GET)
+// @@@ HEAD의 response는 Content-Length나 Transfer-Encoding이 있어도 body가 없다
+func ForMethod(method string) ParseOption {
+	return func(r *Response) {
+		r.method = method
+	}
+}
+
+var ErrResponseEmptyReader = errors.New("reader does not contain any response data")
+var ErrInvalidStatusLine = errors.New("status line must contain HTTP-version, a three-digit status code and an optional reason phrase")
+var ErrInvalidResponseVersion = errors.New("HTTP-version must be HTTP/1.1 or HTTP/1.0")
+var ErrIncompleteResponse = errors.New("incomplete response")
+
+// @@@ client와 같은 에러를 쓰도록 transfer 패키지의 에러를 그대로 노출
+var ErrInvalidContentLength = transfer.ErrInvalidContentLength
+var ErrInvalidChunk = transfer.ErrInvalidChunk
+
+const crlf = "\r\n"
+
+const (
+	responseStateInitialized = iota
+	responseStateParsingHeaders
+	responseStateParsingBody
+	responseStateDone
+)
+
+// body 길이를 알아내는 방식 (RFC 9112 6.3)
+type Framing int
+
+const (
+	FramingNone    Framing = iota // body 없음
+	FramingLength                 // Content-Length 만큼
+	FramingChunked                // chunked transfer coding
+	FramingClose                  // 연결이 끝날 때까지
+)
+
+// io.Reader를 받아 HTTP response 하나를 파싱하는 함수 (body까지 모두 읽는다)
+// body가 없는 status(1xx, 204, 304)와 HEAD의 response는 헤더까지만 파싱한다
+// Content-Length도 Transfer-Encoding도 없으면 reader가 끝날 때까지를 body로 본다
+// @@@ 1xx response도 그대로 반환하므로 뒤이은 최종 response가 필요하면 이어서 파싱해야 한다
+// @@@ *bufio.Reader를 넘기면 response 뒤의 바이트는 읽지 않고 남겨두므로 같은 reader로 다음 response를 이어서 파싱할 수 있다
+func ResponseFromReader(reader io.Reader, opts ...ParseOption) (*Response, error) {
+	resp := Response{
+		State:    responseStateInitialized,
+		Headers:  headers.NewHeaders(),
+		Trailers: headers.NewHeaders(),
+		method:   "GET",
+	}
+	for _, opt := range opts {
+		opt(&resp)
+	}
+
+	src, ok := reader.(*bufio.Reader)
+	if !ok {
+		src = bufio.NewReader(reader)
+	}
+
+	var lines headers.LineReader
+	lines.Reset(src, headers.MaxHeaderBytes)
+
+	line, err := lines.ReadLine()
+	if errors.Is(err, io.EOF) {
+		return nil, ErrResponseEmptyReader
+	}
+	if err != nil {
+		return nil, responseError(err)
+	}
+	resp.StatusLine, err = ParseStatusLine(line[:len(line)-len(crlf)])
+	if err != nil {
+		return nil, responseError(err)
+	}
+	resp.State = responseStateParsingHeaders
+
+	err = lines.ReadHeaders(resp.Headers)
+	if err != nil {
+		return nil, responseError(err)
+	}
+	resp.State = responseStateParsingBody
+
+	framing, length, err := BodyFraming(resp.StatusLine.StatusCode, resp.method, resp.Headers)
+	if err != nil {
+		return nil, responseError(err)
+	}
+
+	var body io.Reader
+	switch framing {
+	case FramingLength:
+		body = transfer.NewLengthReader(src, length)
+	case FramingChunked:
+		body = transfer.NewChunkedReader(src, resp.Trailers)
+	case FramingClose:
+		body = src
+	}
+	if body != nil {
+		resp.Body, err = io.ReadAll(body)
+		if err != nil {
+			return nil, responseError(err)
+		}
+	}
+
+	resp.State = responseStateDone
+	return &resp, nil
+}
+
+// 파싱 중 생긴 에러를 ResponseFromReader가 반환할 에러로 바꾸는 함수
+// @@@ response 도중에 reader가 끝난 경우는 모두 ErrIncompleteResponse
+func responseError(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrIncompleteResponse
+	}
+	return fmt.Errorf("error parsing response: %w", err)
+}
+
+// status code, request method, 헤더로 body 길이를 알아내는 방식을 정하는 함수 (RFC 9112 6.3)
+// Transfer-Encoding 없이 Content-Length가 있으면 그 값도 반환하고, 없으면 -1
+// @@@ HEAD의 response처럼 body가 없어도 Content-Length 값은 반환한다
+// @@@ client도 response를 읽을 때 이 함수로 framing을 정한다
+func BodyFraming(code StatusCode, method string, h headers.Headers) (Framing, int64, error) {
+	noBody := method == "HEAD" || bodyless(code)
+
+	// Transfer-Encoding이 있으면 Content-Length보다 우선
+	if te := h.Get("transfer-encoding"); te != "" {
+		codings := strings.Split(te, ",")
+		switch {
+		case noBody:
+			return FramingNone, -1, nil
+		case strings.EqualFold(strings.TrimSpace(codings[len(codings)-1]), "chunked"):
+			return FramingChunked, -1, nil
+		default:
+			// 마지막 coding이 chunked가 아니면 연결이 끝날 때까지가 body
+			return FramingClose, -1, nil
+		}
+	}
+
+	if cl := h.Get("content-length"); cl != "" {
+		length, err := transfer.ParseContentLength(cl)
+		if err != nil {
+			return FramingNone, -1, err
+		}
+		if noBody || length == 0 {
+			return FramingNone, length, nil
+		}
+		return FramingLength, length, nil
+	}
+
+	if noBody {
+		return FramingNone, -1, nil
+	}
+	return FramingClose, -1, nil
+}
+
+// status line 한 줄(CRLF 제외)을 파싱하는 함수 (ex: "HTTP/1.1 404 Not Found")
+// @@@ reason phrase는 비어있어도 되고, 그 앞의 공백까지 빠뜨리는 서버도 있어서 둘 다 받는다
+func ParseStatusLine(line []byte) (StatusLine, error) {
+	raw := string(line)
+
+	version, rest, _ := strings.Cut(raw, " ")
+	code, reason, _ := strings.Cut(rest, " ")
+
+	switch version {
+	case "HTTP/1.1", "HTTP/1.0":
+	default:
+		if !strings.HasPrefix(version, "HTTP/") {
+			return StatusLine{}, ErrInvalidStatusLine
+		}
+		return StatusLine{}, ErrInvalidResponseVersion
+	}
+
+	if len(code) != 3 || strings.IndexFunc(code, func(c rune) bool { return c < '0' || c > '9' }) != -1 || code[0] == '0' {
+		return StatusLine{}, ErrInvalidStatusLine
+	}
+	n, _ := strconv.Atoi(code)
+
+	return StatusLine{
+		HttpVersion:  strings.TrimPrefix(version, "HTTP/"),
+		StatusCode:   StatusCode(n),
+		ReasonPhrase: reason,
+	}, nil
+}
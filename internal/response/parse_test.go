@@ -0,0 +1,195 @@
+package response
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 한 번에 numBytesPerRead 바이트씩만 읽어주는 reader (request_test.go의 chunkReader와 같다)
+type chunkReader struct {
+	data            string
+	numBytesPerRead int
+	pos             int
+}
+
+func (cr *chunkReader) Read(p []byte) (n int, err error) {
+	if cr.pos >= len(cr.data) {
+		return 0, io.EOF
+	}
+	endIndex := min(cr.pos+cr.numBytesPerRead, len(cr.data))
+	n = copy(p, cr.data[cr.pos:endIndex])
+	cr.pos += n
+
+	return n, nil
+}
+
+func TestResponseFromReader(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		opts     []ParseOption
+		code     StatusCode
+		reason   string
+		version  string
+		body     string
+		headers  map[string]string
+		trailers map[string]string
+	}{
+		{
+			name:    "content length",
+			raw:     "HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\nContent-Length: 13\r\n\r\nHello, World!",
+			code:    StatusOK,
+			reason:  "OK",
+			version: "1.1",
+			body:    "Hello, World!",
+			headers: map[string]string{"content-type": "text/plain"},
+		},
+		{
+			name:     "chunked with extensions and trailers",
+			raw:      "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\nTrailer: X-Checksum\r\n\r\nA;name=value\r\n0123456789\r\n3\r\nabc\r\n0\r\nX-Checksum: 42\r\n\r\n",
+			code:     StatusOK,
+			reason:   "OK",
+			version:  "1.1",
+			body:     "0123456789abc",
+			trailers: map[string]string{"x-checksum": "42"},
+		},
+		{
+			name:    "close delimited",
+			raw:     "HTTP/1.0 200 OK\r\nContent-Type: text/html\r\n\r\n<html>until the end</html>",
+			code:    StatusOK,
+			reason:  "OK",
+			version: "1.0",
+			body:    "<html>until the end</html>",
+		},
+		{
+			name:    "transfer encoding wins over content length",
+			raw:     "HTTP/1.1 200 OK\r\nContent-Length: 100\r\nTransfer-Encoding: chunked\r\n\r\n2\r\nok\r\n0\r\n\r\n",
+			code:    StatusOK,
+			reason:  "OK",
+			version: "1.1",
+			body:    "ok",
+		},
+		{
+			name:    "no content",
+			raw:     "HTTP/1.1 204 No Content\r\nX-Request-Id: 7\r\n\r\n",
+			code:    StatusNoContent,
+			reason:  "No Content",
+			version: "1.1",
+			headers: map[string]string{"x-request-id": "7"},
+		},
+		{
+			name:    "not modified keeps content length without body",
+			raw:     "HTTP/1.1 304 Not Modified\r\nContent-Length: 1234\r\nETag: \"v1\"\r\n\r\n",
+			code:    StatusNotModified,
+			reason:  "Not Modified",
+			version: "1.1",
+			headers: map[string]string{"content-length": "1234", "etag": `"v1"`},
+		},
+		{
+			name:    "response to HEAD",
+			raw:     "HTTP/1.1 200 OK\r\nContent-Length: 1234\r\n\r\n",
+			opts:    []ParseOption{ForMethod("HEAD")},
+			code:    StatusOK,
+			reason:  "OK",
+			version: "1.1",
+		},
+		{
+			name:    "interim response",
+			raw:     "HTTP/1.1 103 Early Hints\r\nLink: </style.css>; rel=preload\r\n\r\n",
+			code:    StatusEarlyHints,
+			reason:  "Early Hints",
+			version: "1.1",
+			headers: map[string]string{"link": "</style.css>; rel=preload"},
+		},
+		{
+			name:    "empty reason phrase",
+			raw:     "HTTP/1.1 599\r\nContent-Length: 0\r\n\r\n",
+			code:    StatusCode(599),
+			version: "1.1",
+		},
+	}
+
+	for _, tc := range tests {
+		// Test: 한 번에 읽히는 바이트 수와 상관없이 같은 결과
+		for _, n := range []int{1, 3, 8, 1024} {
+			t.Run(tc.name+"/"+strconv.Itoa(n), func(t *testing.T) {
+				resp, err := ResponseFromReader(&chunkReader{data: tc.raw, numBytesPerRead: n}, tc.opts...)
+				require.NoError(t, err)
+
+				assert.Equal(t, tc.version, resp.StatusLine.HttpVersion)
+				assert.Equal(t, tc.code, resp.StatusLine.StatusCode)
+				assert.Equal(t, tc.reason, resp.StatusLine.ReasonPhrase)
+				assert.Equal(t, tc.body, string(resp.Body))
+				for k, v := range tc.headers {
+					assert.Equal(t, v, resp.Headers.Get(k))
+				}
+				for k, v := range tc.trailers {
+					assert.Equal(t, v, resp.Trailers.Get(k))
+				}
+			})
+		}
+	}
+}
+
+func TestResponseFromReaderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		err  error
+	}{
+		{"empty reader", "", ErrResponseEmptyReader},
+		{"not a status line", "GET / HTTP/1.1\r\n\r\n", ErrInvalidStatusLine},
+		{"unsupported version", "HTTP/2.0 200 OK\r\n\r\n", ErrInvalidResponseVersion},
+		{"status code too short", "HTTP/1.1 20 OK\r\n\r\n", ErrInvalidStatusLine},
+		{"status code not a number", "HTTP/1.1 2x0 OK\r\n\r\n", ErrInvalidStatusLine},
+		{"invalid header name", "HTTP/1.1 200 OK\r\nBad Name: x\r\n\r\n", headers.ErrInvalidName},
+		{"missing end of headers", "HTTP/1.1 200 OK\r\nContent-Length: 0\r\n", ErrIncompleteResponse},
+		{"short body", "HTTP/1.1 200 OK\r\nContent-Length: 10\r\n\r\nshort", ErrIncompleteResponse},
+		{"negative content length", "HTTP/1.1 200 OK\r\nContent-Length: -1\r\n\r\n", ErrInvalidContentLength},
+		{"conflicting content lengths", "HTTP/1.1 200 OK\r\nContent-Length: 2\r\nContent-Length: 3\r\n\r\nok", ErrInvalidContentLength},
+		{"invalid chunk size", "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n+5\r\nhello\r\n0\r\n\r\n", ErrInvalidChunk},
+		{"chunk longer than size", "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n2\r\nabc\r\n0\r\n\r\n", ErrInvalidChunk},
+		{"missing last chunk", "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n2\r\nab\r\n", ErrIncompleteResponse},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ResponseFromReader(&chunkReader{data: tc.raw, numBytesPerRead: 3})
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestResponseFromWriterData(t *testing.T) {
+	// Test: Writer가 작성한 response를 다시 파싱하면 같은 값
+	w := &Writer{}
+	require.NoError(t, w.WriteStatusLine(StatusNotFound))
+	h := headers.NewHeaders()
+	h.SetOverride("Content-Type", "text/plain")
+	h.SetOverride("Transfer-Encoding", "chunked")
+	require.NoError(t, w.WriteHeaders(h))
+	for _, part := range []string{"not ", "found"} {
+		_, err := w.WriteChunkedBody([]byte(part))
+		require.NoError(t, err)
+	}
+	_, err := w.WriteChunkedBodyDone()
+	require.NoError(t, err)
+
+	resp, err := ResponseFromReader(bytes.NewReader(w.Data))
+	require.NoError(t, err)
+	assert.Equal(t, StatusNotFound, resp.StatusLine.StatusCode)
+	assert.Equal(t, "Not Found", resp.StatusLine.ReasonPhrase)
+	assert.Equal(t, "text/plain", resp.Headers.Get("Content-Type"))
+	assert.Equal(t, "not found", string(resp.Body))
+
+	// Test: Content-Length 뒤에 남은 바이트는 body에 넣지 않는다
+	resp, err = ResponseFromReader(bytes.NewReader([]byte("HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nokHTTP/1.1 200 OK\r\n")))
+	require.NoError(t, err)
+	assert.Equal(t, "ok", string(resp.Body))
+}
@@ -1,21 +1,15 @@
 package response
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"strings"
 
 	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
 )
 
-type StatusCode int
-
-const (
-	StatusOK                  StatusCode = 200
-	StatusBadRequest          StatusCode = 400
-	StatusInternalServerError StatusCode = 500
-)
-
 type writerState int
 
 const (
@@ -29,31 +23,111 @@ const (
 var ErrWriterInvalidState = errors.New("you must call the struct's methods in the correct order")
 var ErrWriterNoTrailerHeader = errors.New("you must have Trailer header and its value defined to write trailers")
 
+// Writer 안에 쌓인 Data가 이 크기를 넘으면 Write가 dst로 바로 내보낸다
+const flushThreshold = 32 * 1024
+
 type Writer struct {
 	Data  []byte
 	State writerState
+
+	// nil이 아니면 Flush 때 Data를 여기로(보통 net.Conn) 내보낸다
+	// nil이면 예전처럼 Data에 전부 쌓아두기만 한다
+	dst     io.Writer
+	flushed int // dst로 이미 내보낸 바이트 수
+
+	// WriteHeaders에서 기록하는 연결 유지 관련 정보
+	closeConn bool // Connection: close 헤더 존재 여부
+	framed    bool // Content-Length 또는 Transfer-Encoding 헤더 존재 여부
+
+	// 접근 로그 등에서 쓰는 기록
+	statusCode   StatusCode // WriteStatusLine으로 작성한 status code
+	bytesWritten int        // 작성된 body 바이트 수 (chunk 길이 표기 등 framing 제외)
+
+	// body 변환(압축 등) 관련 상태 (encoder.go 참고)
+	encode    EncoderFunc     // WriteHeaders 때 Encoder를 고르는 함수
+	encoder   Encoder         // 선택된 Encoder (nil이면 body를 그대로 작성)
+	encodeBuf *bytes.Buffer   // 인코딩 결과를 모아두는 경우의 버퍼
+	pending   headers.Headers // 인코딩 결과를 모아두는 동안 작성을 미룬 헤더
+
+	// 연결 hijack 관련 상태 (hijack.go 참고)
+	hijacker Hijacker
+	hijacked bool
+
+	// 연결 종료 알림 (notify.go 참고)
+	closeNotifier CloseNotifier
+}
+
+// Flush 시 dst로 response를 내보내는 Writer 생성 함수
+func NewWriter(dst io.Writer) *Writer {
+	return &Writer{
+		State: WriterStateInitialized,
+		dst:   dst,
+	}
+}
+
+// Data에 쌓인 내용을 dst로 내보내고 Data를 비우는 메소드
+// dst가 없는 Writer면 아무것도 하지 않는다
+// body를 chunked로 인코딩하는 중이면 Encoder 안에 남은 데이터도 먼저 Data로 내보낸다
+// @@@ 한번 내보낸 내용은 되돌릴 수 없으므로 status line과 headers를 바꿀 수 없게 된다
+func (w *Writer) Flush() error {
+	if w.hijacked {
+		return ErrHijacked
+	}
+
+	if w.encoder != nil && w.pending == nil {
+		err := w.encoder.Flush()
+		if err != nil {
+			return err
+		}
+	}
+
+	return w.flushData()
+}
+
+// Data에 쌓인 내용만 dst로 내보내는 메소드
+func (w *Writer) flushData() error {
+	if w.dst == nil || len(w.Data) == 0 {
+		return nil
+	}
+
+	n, err := w.dst.Write(w.Data)
+	w.flushed += n
+	if err != nil {
+		return err
+	}
+
+	w.Data = w.Data[:0]
+
+	return nil
+}
+
+// dst로 이미 내보낸 바이트 수를 반환하는 메소드
+func (w *Writer) Flushed() int {
+	return w.flushed
 }
 
 // Status Line을 주어진 statusCode에 맞게 Writer 구조체에 저장하는 메소드
+// 최종 response의 status line만 쓴다 (101을 뺀 1xx는 WriteInterim 사용)
 func (w *Writer) WriteStatusLine(statusCode StatusCode) error {
+	if w.hijacked {
+		return ErrHijacked
+	}
+
 	if w.State != WriterStateInitialized {
 		return ErrWriterInvalidState
 	}
 
-	line := ""
-	switch statusCode {
-	case StatusOK:
-		line = "HTTP/1.1 200 OK\r\n"
-	case StatusBadRequest:
-		line = "HTTP/1.1 400 Bad Request\r\n"
-	case StatusInternalServerError:
-		line = "HTTP/1.1 500 Internal Server Error\r\n"
-	default:
-		line = fmt.Sprintf("HTTP/1.1 %v \r\n", statusCode)
+	if interim(statusCode) {
+		return ErrInterimStatus
 	}
 
+	// @@@ status code가 늘어나면서 switch 대신 status.go의 reason phrase 표 사용
+	// @@@ 모르는 코드면 reason phrase 없이 "HTTP/1.1 <code> \r\n"
+	line := fmt.Sprintf("HTTP/1.1 %d %s\r\n", statusCode, StatusText(statusCode))
+
 	w.Data = append(w.Data, []byte(line)...)
 
+	w.statusCode = statusCode
 	w.State = WriterStateStatusLineDone
 
 	return nil
@@ -61,11 +135,42 @@ func (w *Writer) WriteStatusLine(statusCode StatusCode) error {
 
 // headers에 저장되어 있는 헤더들을 Writer 구조체에 저장하는 메소드
 func (w *Writer) WriteHeaders(headers headers.Headers) error {
+	if w.hijacked {
+		return ErrHijacked
+	}
+
 	if w.State != WriterStateStatusLineDone {
 		return ErrWriterInvalidState
 	}
 
-	for key, value := range headers {
+	if w.encode != nil {
+		w.startEncoder(headers)
+	}
+
+	w.State = WriterStateHeadersDone
+
+	// 인코딩 결과를 모아두는 경우에는 Content-Length를 알게 되는 FinishEncoding 때 작성
+	if w.pending != nil {
+		return nil
+	}
+
+	w.appendHeaders(headers)
+
+	return nil
+}
+
+// 헤더들과 헤더 블록 끝의 \r\n을 Data에 작성하는 메소드
+func (w *Writer) appendHeaders(h headers.Headers) {
+	for key, value := range h {
+		switch strings.ToLower(key) {
+		case "connection":
+			if headers.HasToken(value, "close") {
+				w.closeConn = true
+			}
+		case "content-length", "transfer-encoding":
+			w.framed = true
+		}
+
 		header := ""
 
 		header += key
@@ -79,31 +184,109 @@ func (w *Writer) WriteHeaders(headers headers.Headers) error {
 
 	// headers 맵 순회가 끝나면 헤더 블록이 끝났다고 알리는 \r\n를 마지막으로 쓰고 종료
 	w.Data = append(w.Data, []byte("\r\n")...)
+}
 
-	w.State = WriterStateHeadersDone
+// 작성된 response를 보낸 뒤 같은 연결로 다음 request를 받아도 되는지 알려주는 메소드
+// 헤더가 작성되지 않았거나, Connection: close 헤더가 있거나,
+// body 길이를 알 수 있는 헤더(Content-Length, Transfer-Encoding)가 없으면 false
+// (단, 304처럼 body가 없는 status code는 길이 헤더가 없어도 된다)
+func (w *Writer) KeepAlive() bool {
+	if w.State < WriterStateHeadersDone {
+		return false
+	}
 
-	return nil
+	return (w.framed || bodyless(w.statusCode)) && !w.closeConn
+}
+
+// response를 보낸 뒤 연결을 닫도록 표시하는 메소드
+// body를 약속한 만큼 쓰지 못한 경우 등 다음 request를 같은 연결로 받으면 안 될 때 사용
+func (w *Writer) CloseConnection() {
+	w.closeConn = true
+}
+
+// 작성된 status code를 반환하는 메소드 (아직 작성 전이면 0)
+func (w *Writer) StatusCode() StatusCode {
+	return w.statusCode
+}
+
+// 지금까지 작성된 body 바이트 수를 반환하는 메소드
+// chunked encoding이면 chunk 길이 줄 등을 뺀 실제 데이터 길이의 합
+func (w *Writer) BytesWritten() int {
+	return w.bytesWritten
 }
 
 // 주어진 body 데이터를 Writer 구조체에 저장하는 메소드
 func (w *Writer) WriteBody(p []byte) (int, error) {
+	if w.hijacked {
+		return 0, ErrHijacked
+	}
+
 	if w.State != WriterStateHeadersDone {
 		return 0, ErrWriterInvalidState
 	}
 
+	if w.encoder != nil {
+		n, err := w.writeEncoded(p)
+		if err != nil {
+			return n, err
+		}
+		w.State = WriterStateDone
+		return n, nil
+	}
+
 	w.Data = append(w.Data, p...)
 
+	w.bytesWritten += len(p)
 	w.State = WriterStateDone
 
 	return len(p), nil
 }
 
+// io.Writer 구현 메소드
+// WriteBody와 달리 여러 번 호출할 수 있어서 io.Copy 등으로 body를 나눠 쓸 때 사용
+// (Content-Length 만큼 다 쓰는 것은 호출하는 쪽의 책임)
+// 쌓인 Data가 flushThreshold를 넘으면 dst로 바로 내보내서 큰 body도 메모리에 다 올리지 않는다
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.hijacked {
+		return 0, ErrHijacked
+	}
+
+	if w.State != WriterStateHeadersDone {
+		return 0, ErrWriterInvalidState
+	}
+
+	if w.encoder != nil {
+		return w.writeEncoded(p)
+	}
+
+	w.Data = append(w.Data, p...)
+	w.bytesWritten += len(p)
+
+	if len(w.Data) >= flushThreshold {
+		err := w.flushData()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
 // chunk 데이터 길이와 데이터 자체를 Writer에 저장하는 함수
 func (w *Writer) WriteChunkedBody(p []byte) (int, error) {
+	if w.hijacked {
+		return 0, ErrHijacked
+	}
+
 	if w.State != WriterStateHeadersDone {
 		return 0, ErrWriterInvalidState
 	}
 
+	// Encoder가 있으면 chunk framing은 Encoder 출력 쪽(chunkWriter)에서 한다
+	if w.encoder != nil {
+		return w.writeEncoded(p)
+	}
+
 	// chunk 길이는 16진법으로 표현 (%x 이용)
 	chunkLen := []byte(fmt.Sprintf("%x", len(p)) + "\r\n")
 
@@ -113,16 +296,29 @@ func (w *Writer) WriteChunkedBody(p []byte) (int, error) {
 	w.Data = append(w.Data, p...)
 	w.Data = append(w.Data, []byte("\r\n")...)
 
+	w.bytesWritten += len(p)
+
 	return len(chunkLen) + len(p) + 2, nil
 	// w.Data에 추가되는 바이트 길이는 len(chunkLen) + len(p) + len("\r\n")
 }
 
 // chunked encoding이 끝났음을 알리는 마지막줄을 Writer에 저장하는 함수
 func (w *Writer) WriteChunkedBodyDone() (int, error) {
+	if w.hijacked {
+		return 0, ErrHijacked
+	}
+
 	if w.State != WriterStateHeadersDone {
 		return 0, ErrWriterInvalidState
 	}
 
+	if w.encoder != nil {
+		err := w.closeEncoder()
+		if err != nil {
+			return 0, err
+		}
+	}
+
 	lastChunk := []byte(fmt.Sprintf("%x", 0) + "\r\n\r\n")
 
 	w.Data = append(w.Data, lastChunk...)
@@ -134,6 +330,10 @@ func (w *Writer) WriteChunkedBodyDone() (int, error) {
 
 // 바디 작성 후에 Trailer에 명시된 헤더들 작성하는 함수
 func (w *Writer) WriteTrailers(h headers.Headers) error {
+	if w.hijacked {
+		return ErrHijacked
+	}
+
 	if w.State != WriterStateBodyDone {
 		return ErrWriterInvalidState
 	}
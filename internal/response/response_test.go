@@ -1,5 +1,128 @@
 package response
 
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriterStatusAndBytes(t *testing.T) {
+	// Test: Content-Length body
+	w := &Writer{}
+	assert.Equal(t, StatusCode(0), w.StatusCode())
+	require.NoError(t, w.WriteStatusLine(StatusBadRequest))
+	h := headers.NewHeaders()
+	h.SetOverride("Content-Length", "5")
+	require.NoError(t, w.WriteHeaders(h))
+	_, err := w.WriteBody([]byte("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, StatusBadRequest, w.StatusCode())
+	assert.Equal(t, 5, w.BytesWritten())
+	assert.True(t, w.KeepAlive())
+
+	// Test: chunked body는 chunk framing을 빼고 센다
+	w = &Writer{}
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	h = headers.NewHeaders()
+	h.SetOverride("Transfer-Encoding", "chunked")
+	h.SetOverride("Connection", "close")
+	require.NoError(t, w.WriteHeaders(h))
+	_, err = w.WriteChunkedBody([]byte("abc"))
+	require.NoError(t, err)
+	_, err = w.WriteChunkedBody([]byte("defg"))
+	require.NoError(t, err)
+	_, err = w.WriteChunkedBodyDone()
+	require.NoError(t, err)
+	assert.Equal(t, 7, w.BytesWritten())
+	assert.False(t, w.KeepAlive())
+
+	// Test: Connection 값이 token 목록이어도 close가 있으면 연결을 유지하지 않는다
+	w = &Writer{}
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	h = headers.NewHeaders()
+	h.SetOverride("Content-Length", "0")
+	h.SetOverride("Connection", "Upgrade, close")
+	require.NoError(t, w.WriteHeaders(h))
+	assert.False(t, w.KeepAlive())
+}
+
+// 테스트용 Encoder: 받은 데이터를 대문자로 바꿔서 dst에 쓴다
+type upperEncoder struct {
+	dst    io.Writer
+	closed bool
+}
+
+func (e *upperEncoder) Write(p []byte) (int, error) {
+	return e.dst.Write(bytes.ToUpper(p))
+}
+
+func (e *upperEncoder) Flush() error { return nil }
+
+func (e *upperEncoder) Close() error {
+	e.closed = true
+	return nil
+}
+
+func TestWriterEncoder(t *testing.T) {
+	upper := func(code StatusCode, h headers.Headers, dst io.Writer) Encoder {
+		h.SetOverride("Content-Encoding", "upper")
+		return &upperEncoder{dst: dst}
+	}
+
+	// Test: Content-Length가 작으면 인코딩 결과를 모았다가 새 Content-Length와 함께 작성
+	w := &Writer{}
+	require.NoError(t, w.SetEncoder(upper))
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	h := headers.NewHeaders()
+	h.SetOverride("Content-Length", "5")
+	require.NoError(t, w.WriteHeaders(h))
+	assert.True(t, w.Encoding())
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", string(w.Data))
+	_, err := w.WriteBody([]byte("hello"))
+	require.NoError(t, err)
+	require.NoError(t, w.FinishEncoding())
+	assert.False(t, w.Encoding())
+	assert.Contains(t, string(w.Data), "Content-Encoding: upper\r\n")
+	assert.Contains(t, string(w.Data), "Content-Length: 5\r\n")
+	assert.True(t, strings.HasSuffix(string(w.Data), "\r\n\r\nHELLO"), string(w.Data))
+	assert.True(t, w.KeepAlive())
+
+	// Test: handler가 chunked로 보내면 chunk framing은 Encoder 출력 기준
+	w = &Writer{}
+	require.NoError(t, w.SetEncoder(upper))
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	h = headers.NewHeaders()
+	h.SetOverride("Transfer-Encoding", "chunked")
+	require.NoError(t, w.WriteHeaders(h))
+	_, err = w.WriteChunkedBody([]byte("abc"))
+	require.NoError(t, err)
+	_, err = w.WriteChunkedBodyDone()
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(string(w.Data), "\r\n\r\n3\r\nABC\r\n0\r\n\r\n"), string(w.Data))
+	// @@@ 이미 끝났으므로 FinishEncoding은 아무것도 하지 않는다
+	before := len(w.Data)
+	require.NoError(t, w.FinishEncoding())
+	assert.Equal(t, before, len(w.Data))
+
+	// Test: Encoder를 반환하지 않으면 그대로 작성
+	w = &Writer{}
+	require.NoError(t, w.SetEncoder(func(StatusCode, headers.Headers, io.Writer) Encoder { return nil }))
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	require.NoError(t, w.WriteHeaders(headers.NewHeaders()))
+	assert.False(t, w.Encoding())
+	_, err = w.WriteBody([]byte("plain"))
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n\r\nplain", string(w.Data))
+
+	// Test: 헤더를 쓴 뒤에는 설정할 수 없다
+	assert.ErrorIs(t, w.SetEncoder(upper), ErrWriterInvalidState)
+}
+
 // type testWriter struct {
 // 	data string
 // }
@@ -66,3 +189,63 @@ package response
 // 	assert.Equal(t, "HTTP/1.1 200 OK\r\nContent-Length: 100\r\nConnection: close\r\nContent-Type: text/plain\r\n\r\n", writer.data)
 
 // }
+
+func TestWriteContinue(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf)
+
+	// Test: 100 Continue는 최종 response보다 먼저 바로 내보낸다
+	require.NoError(t, w.WriteContinue())
+	assert.Equal(t, "HTTP/1.1 100 Continue\r\n\r\n", buf.String())
+	assert.Equal(t, StatusCode(0), w.StatusCode())
+
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	assert.ErrorIs(t, w.WriteContinue(), ErrWriterInvalidState)
+}
+
+func TestWriteInterim(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf)
+
+	// Test: 최종 response 전에 1xx를 여러 번 보낼 수 있고, 각각 바로 내보낸다
+	h := headers.NewHeaders()
+	h.SetOverride("Link", "</style.css>; rel=preload; as=style")
+	require.NoError(t, w.WriteInterim(StatusEarlyHints, h))
+	assert.Equal(t, "HTTP/1.1 103 Early Hints\r\nLink: </style.css>; rel=preload; as=style\r\n\r\n", buf.String())
+
+	h = headers.NewHeaders()
+	h.SetOverride("Link", "</app.js>; rel=preload; as=script")
+	require.NoError(t, w.WriteInterim(StatusEarlyHints, h))
+	require.NoError(t, w.WriteInterim(StatusCode(199), nil))
+	assert.Equal(t, 3, strings.Count(buf.String(), "HTTP/1.1 1"))
+
+	// Test: 1xx가 아니거나 101이면 interim response가 아니다
+	assert.ErrorIs(t, w.WriteInterim(StatusOK, nil), ErrNotInterimStatus)
+	assert.ErrorIs(t, w.WriteInterim(StatusSwitchingProtocols, nil), ErrNotInterimStatus)
+	// Test: 101을 뺀 1xx는 최종 status line으로 쓸 수 없다
+	assert.ErrorIs(t, w.WriteStatusLine(StatusEarlyHints), ErrInterimStatus)
+
+	// Test: interim 헤더의 Connection: close는 최종 response의 연결 유지에 영향이 없다
+	h = headers.NewHeaders()
+	h.SetOverride("Connection", "close")
+	require.NoError(t, w.WriteInterim(StatusEarlyHints, h))
+
+	require.NoError(t, w.WriteStatusLine(StatusOK))
+	h = headers.NewHeaders()
+	h.SetOverride("Content-Length", "2")
+	require.NoError(t, w.WriteHeaders(h))
+	_, err := w.WriteBody([]byte("ok"))
+	require.NoError(t, err)
+	require.NoError(t, w.Flush())
+
+	assert.Equal(t, StatusOK, w.StatusCode())
+	assert.True(t, w.KeepAlive())
+	assert.True(t, strings.HasSuffix(buf.String(), "\r\n\r\nHTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok"))
+
+	// Test: 최종 status line 뒤에는 interim response를 보낼 수 없다
+	assert.ErrorIs(t, w.WriteInterim(StatusEarlyHints, nil), ErrWriterInvalidState)
+
+	// Test: 101은 WriteStatusLine으로 쓴다
+	w = &Writer{}
+	require.NoError(t, w.WriteStatusLine(StatusSwitchingProtocols))
+}
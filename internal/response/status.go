@@ -0,0 +1,72 @@
+package response
+
+type StatusCode int
+
+const (
+	StatusContinue             StatusCode = 100
+	StatusSwitchingProtocols   StatusCode = 101
+	StatusEarlyHints           StatusCode = 103
+	StatusOK                   StatusCode = 200
+	StatusNoContent            StatusCode = 204
+	StatusPartialContent       StatusCode = 206
+	StatusMovedPermanently     StatusCode = 301
+	StatusNotModified          StatusCode = 304
+	StatusBadRequest           StatusCode = 400
+	StatusForbidden            StatusCode = 403
+	StatusNotFound             StatusCode = 404
+	StatusMethodNotAllowed     StatusCode = 405
+	StatusProxyAuthRequired    StatusCode = 407
+	StatusRequestTimeout       StatusCode = 408
+	StatusPreconditionFailed   StatusCode = 412
+	StatusContentTooLarge      StatusCode = 413
+	StatusUnsupportedMediaType StatusCode = 415
+	StatusRangeNotSatisfiable  StatusCode = 416
+	StatusExpectationFailed    StatusCode = 417
+	StatusUpgradeRequired      StatusCode = 426
+	StatusHeaderFieldsTooLarge StatusCode = 431
+	StatusInternalServerError  StatusCode = 500
+	StatusNotImplemented       StatusCode = 501
+	StatusBadGateway           StatusCode = 502
+	StatusServiceUnavailable   StatusCode = 503
+	StatusGatewayTimeout       StatusCode = 504
+)
+
+// status code별 reason phrase
+var statusText = map[StatusCode]string{
+	StatusContinue:             "Continue",
+	StatusSwitchingProtocols:   "Switching Protocols",
+	StatusEarlyHints:           "Early Hints",
+	StatusOK:                   "OK",
+	StatusNoContent:            "No Content",
+	StatusPartialContent:       "Partial Content",
+	StatusMovedPermanently:     "Moved Permanently",
+	StatusNotModified:          "Not Modified",
+	StatusBadRequest:           "Bad Request",
+	StatusForbidden:            "Forbidden",
+	StatusNotFound:             "Not Found",
+	StatusMethodNotAllowed:     "Method Not Allowed",
+	StatusProxyAuthRequired:    "Proxy Authentication Required",
+	StatusRequestTimeout:       "Request Timeout",
+	StatusPreconditionFailed:   "Precondition Failed",
+	StatusContentTooLarge:      "Content Too Large",
+	StatusUnsupportedMediaType: "Unsupported Media Type",
+	StatusRangeNotSatisfiable:  "Range Not Satisfiable",
+	StatusExpectationFailed:    "Expectation Failed",
+	StatusUpgradeRequired:      "Upgrade Required",
+	StatusHeaderFieldsTooLarge: "Request Header Fields Too Large",
+	StatusInternalServerError:  "Internal Server Error",
+	StatusNotImplemented:       "Not Implemented",
+	StatusBadGateway:           "Bad Gateway",
+	StatusServiceUnavailable:   "Service Unavailable",
+	StatusGatewayTimeout:       "Gateway Timeout",
+}
+
+// status code의 reason phrase를 반환하는 함수 (모르는 코드면 "")
+func StatusText(code StatusCode) string {
+	return statusText[code]
+}
+
+// body가 없는 것으로 정해진 status code인지 확인하는 함수 (1xx, 204, 304)
+func bodyless(code StatusCode) bool {
+	return (code >= 100 && code < 200) || code == 204 || code == StatusNotModified
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"net"
+	"time"
+)
+
+// 서버가 관리하는 연결(net.Conn)의 현재 상태
+type ConnState int
+
+const (
+	// Accept 직후, 아직 request의 첫 바이트를 받지 못한 상태
+	StateNew ConnState = iota
+	// request를 읽거나 handler가 response를 작성하는 중인 상태
+	StateActive
+	// response 전송 후 keep-alive로 다음 request를 기다리는 상태
+	StateIdle
+	// 연결이 닫혀 서버의 관리 대상에서 빠진 상태
+	StateClosed
+	// handler가 Writer.Hijack으로 연결을 가져가서 서버의 관리 대상에서 빠진 상태
+	// (Shutdown은 이 연결을 기다리거나 닫지 않는다)
+	StateHijacked
+)
+
+func (c ConnState) String() string {
+	switch c {
+	case StateNew:
+		return "new"
+	case StateActive:
+		return "active"
+	case StateIdle:
+		return "idle"
+	case StateClosed:
+		return "closed"
+	case StateHijacked:
+		return "hijacked"
+	default:
+		return "unknown"
+	}
+}
+
+// 연결의 상태를 갱신하는 메소드
+// StateClosed나 StateHijacked가 되면 관리 대상 맵에서 제거한다
+func (s *Server) setState(conn net.Conn, state ConnState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if state == StateClosed || state == StateHijacked {
+		if _, ok := s.conns[conn]; ok {
+			delete(s.conns, conn)
+			s.metrics.connClosed()
+		}
+		return
+	}
+
+	s.conns[conn] = state
+}
+
+// 연결을 StateNew나 StateIdle로 두고 다음 request를 기다릴 read deadline을 정하는 메소드
+// 새 연결은 read header timeout, keep-alive 연결은 idle timeout까지 기다린다 (idle timeout이 0이면 read header timeout)
+func (s *Server) waitForRequest(conn net.Conn, state ConnState) {
+	timeout := s.readHeaderTimeout
+	if state == StateIdle && s.idleTimeout > 0 {
+		timeout = s.idleTimeout
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.conns[conn] = state
+	_ = conn.SetReadDeadline(deadline(timeout))
+}
+
+// request의 첫 바이트를 받은 연결을 StateActive로 바꾸고 헤더를 읽을 read deadline을 정하는 메소드
+func (s *Server) startRequest(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.conns[conn] = StateActive
+	_ = conn.SetReadDeadline(deadline(s.readHeaderTimeout))
+}
+
+// 지금부터 timeout 뒤의 시각을 반환하는 함수 (timeout이 0 이하면 deadline 없음)
+func deadline(timeout time.Duration) time.Time {
+	if timeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(timeout)
+}
+
+// 새 연결을 관리 대상에 추가하는 메소드
+// 서버가 이미 닫히는 중이면 false를 반환하고 연결은 추가하지 않는다
+func (s *Server) trackConn(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed.Load() {
+		return false
+	}
+
+	s.conns[conn] = StateNew
+	s.metrics.connOpened()
+
+	return true
+}
+
+// 요청을 기다리는 중인(StateNew, StateIdle) 연결들을 깨워서 닫게 하는 메소드
+// 깨운 뒤 남아있는 연결이 없으면 true 반환
+// @@@ 바로 Close하면 handle 고루틴이 request의 첫 바이트를 받고 StateActive로 바꾸기 직전인 연결의 request를 버리게 된다
+// @@@ 그래서 지난 read deadline으로 대기 중인 Peek만 깨우고, 닫는 것은 handle 고루틴에 맡긴다
+// @@@ (이미 받은 바이트가 있으면 Peek은 성공하고, startRequest가 같은 lock 안에서 deadline을 덮어쓰므로 그 request는 처리된다)
+func (s *Server) closeIdleConns() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for conn, state := range s.conns {
+		if state == StateNew || state == StateIdle {
+			_ = conn.SetReadDeadline(time.Unix(1, 0))
+			// @@@ 맵에서의 제거는 handle 고루틴이 Read 에러를 받고 종료하면서 처리
+		}
+	}
+
+	return len(s.conns) == 0
+}
+
+// 상태와 상관없이 관리 중인 모든 연결을 닫는 메소드
+func (s *Server) closeAllConns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// body가 10바이트를 넘으면 읽지 않고 413으로 거절하고, 아니면 body를 그대로 돌려주는 handler
+func limitedEchoHandler(w *response.Writer, req *request.Request) {
+	length, _ := strconv.Atoi(req.Headers.Get("content-length"))
+	if length > 10 {
+		writeStatus(w, response.StatusContentTooLarge, "too large")
+		return
+	}
+	if req.RequestLine.RequestTarget == "/ignore" {
+		writeOK(w, "ignored")
+		return
+	}
+
+	err := req.ReadBody()
+	if err != nil {
+		writeStatus(w, response.StatusBadRequest, err.Error())
+		return
+	}
+	writeOK(w, string(req.Body))
+}
+
+func writeStatus(w *response.Writer, code response.StatusCode, body string) {
+	_ = w.WriteStatusLine(code)
+	h := headers.NewHeaders()
+	h.SetOverride("Content-Length", strconv.Itoa(len(body)))
+	_ = w.WriteHeaders(h)
+	_, _ = w.WriteBody([]byte(body))
+}
+
+func dialExpect(t *testing.T) (net.Conn, *bufio.Reader) {
+	t.Helper()
+
+	s, err := ServeAddr("tcp", "127.0.0.1:0", limitedEchoHandler)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+
+	return conn, bufio.NewReader(conn)
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestExpectContinue(t *testing.T) {
+	conn, reader := dialExpect(t)
+
+	// Test: handler가 body를 읽으려 할 때 100 Continue가 나가고, 그 뒤에 보낸 body를 받는다
+	_, err := conn.Write([]byte("POST / HTTP/1.1\r\nHost: localhost\r\nExpect: 100-continue\r\nContent-Length: 5\r\n\r\n"))
+	require.NoError(t, err)
+
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1 100 Continue\r\n", line)
+	line, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "\r\n", line)
+
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "hello", readBody(t, resp))
+
+	// Test: body를 다 읽었으므로 같은 연결로 다음 request를 받는다
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	resp, err = http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestExpectContinueRejected(t *testing.T) {
+	conn, reader := dialExpect(t)
+
+	// Test: handler가 body를 읽기 전에 거절하면 100 없이 최종 response만 가고 연결을 닫는다
+	_, err := conn.Write([]byte("POST / HTTP/1.1\r\nHost: localhost\r\nExpect: 100-continue\r\nContent-Length: 1000\r\n\r\n"))
+	require.NoError(t, err)
+
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	assert.Equal(t, "too large", readBody(t, resp))
+
+	_, err = reader.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestExpectContinueIgnored(t *testing.T) {
+	conn, reader := dialExpect(t)
+
+	// Test: handler가 body를 읽지 않고 response를 쓰면 남은 body를 request로 읽지 않도록 연결을 닫는다
+	_, err := conn.Write([]byte("POST /ignore HTTP/1.1\r\nHost: localhost\r\nExpect: 100-continue\r\nContent-Length: 5\r\n\r\n"))
+	require.NoError(t, err)
+
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "ignored", readBody(t, resp))
+
+	_, err = reader.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestExpectationFailed(t *testing.T) {
+	conn, reader := dialExpect(t)
+
+	_, err := conn.Write([]byte("POST / HTTP/1.1\r\nHost: localhost\r\nExpect: something-else\r\nContent-Length: 5\r\n\r\nhello"))
+	require.NoError(t, err)
+
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusExpectationFailed, resp.StatusCode)
+}
+
+func TestExpectContinueWithNetHTTPClient(t *testing.T) {
+	s, err := ServeAddr("tcp", "127.0.0.1:0", limitedEchoHandler)
+	require.NoError(t, err)
+	defer s.Close()
+
+	// @@@ net/http client는 100 Continue를 ExpectContinueTimeout 동안 기다리므로 길게 잡아서 실제로 100을 받는지 확인
+	client := &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: &http.Transport{ExpectContinueTimeout: 3 * time.Second},
+	}
+	req, err := http.NewRequest("POST", "http://"+s.Addr().String()+"/", strings.NewReader("0123456789"))
+	require.NoError(t, err)
+	req.Header.Set("Expect", "100-continue")
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, "0123456789", readBody(t, resp))
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,30 @@
+package server
+
+import (
+	"errors"
+	"net"
+	"sync"
+)
+
+// handler가 Hijack으로 넘겨받는 연결
+// handler가 Close할 때 admit으로 차지한 자리(WithMaxConns, WithMaxConnsPerIP)를 돌려놓는다
+// @@@ handle 고루틴이 끝날 때 돌려놓으면 WebSocket, CONNECT 터널처럼 오래 열려있는 연결이 제한에서 빠져버린다
+type hijackedConn struct {
+	net.Conn
+	closeOnce sync.Once
+	release   func()
+}
+
+func (c *hijackedConn) Close() error {
+	err := c.Conn.Close()
+	c.closeOnce.Do(c.release)
+	return err
+}
+
+// 원래 연결의 CloseWrite를 그대로 노출하는 메소드 (CONNECT 터널이 한쪽 방향만 닫을 때 사용)
+func (c *hijackedConn) CloseWrite() error {
+	if cw, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// /upgrade request를 받으면 101을 보내고 연결을 넘겨받아 줄 단위 echo 프로토콜로 바꾸는 handler
+func echoUpgradeHandler(hijacked chan<- error) Handler {
+	return func(w *response.Writer, req *request.Request) {
+		if req.RequestLine.RequestTarget != "/upgrade" {
+			writeOK(w, "plain http")
+			return
+		}
+
+		_ = w.WriteStatusLine(response.StatusSwitchingProtocols)
+		h := headers.NewHeaders()
+		h.SetOverride("Upgrade", "echo")
+		h.SetOverride("Connection", "Upgrade")
+		_ = w.WriteHeaders(h)
+
+		conn, reader, err := w.Hijack()
+		hijacked <- err
+		if err != nil {
+			return
+		}
+
+		// Test: Hijack 뒤에는 Writer를 쓸 수 없다
+		_, writeErr := w.Write([]byte("nope"))
+		hijacked <- writeErr
+
+		// @@@ handler가 반환된 뒤에도 연결을 계속 쓸 수 있는지 보기 위해 고루틴에서 처리
+		go func() {
+			defer conn.Close()
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				_, _ = conn.Write([]byte("echo: " + line))
+			}
+		}()
+	}
+}
+
+func TestHijack(t *testing.T) {
+	hijacked := make(chan error, 2)
+	s, err := ServeAddr("tcp", "127.0.0.1:0", echoUpgradeHandler(hijacked))
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+	reader := bufio.NewReader(conn)
+
+	// Test: Hijack 전에는 평범한 keep-alive HTTP 연결
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	status, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", status)
+	for line := ""; line != "\r\n"; {
+		line, err = reader.ReadString('\n')
+		require.NoError(t, err)
+	}
+	body := make([]byte, len("plain http"))
+	_, err = reader.Read(body)
+	require.NoError(t, err)
+
+	// Test: Hijack 전에 써둔 101 response는 먼저 보내진다
+	_, err = conn.Write([]byte("GET /upgrade HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	require.NoError(t, <-hijacked)
+	assert.ErrorIs(t, <-hijacked, response.ErrHijacked)
+
+	status, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1 101 Switching Protocols\r\n", status)
+	for line := ""; line != "\r\n"; {
+		line, err = reader.ReadString('\n')
+		require.NoError(t, err)
+	}
+
+	// Test: 이후로는 server가 끼어들지 않고 handler의 프로토콜로 주고받는다
+	for _, msg := range []string{"hello\n", "GET / HTTP/1.1\n"} {
+		_, err = conn.Write([]byte(msg))
+		require.NoError(t, err)
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, "echo: "+msg, line)
+	}
+
+	// Test: Hijack된 연결은 관리 대상에서 빠진다
+	s.mu.Lock()
+	assert.Empty(t, s.conns)
+	s.mu.Unlock()
+
+	// Test: Shutdown은 Hijack된 연결을 기다리거나 닫지 않는다
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, s.Shutdown(ctx))
+
+	_, err = conn.Write([]byte("still here\n"))
+	require.NoError(t, err)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "echo: still here\n", line)
+}
+
+func TestHijackWithoutConn(t *testing.T) {
+	// Test: server가 만들지 않은 Writer는 Hijack할 수 없다
+	w := &response.Writer{}
+	_, _, err := w.Hijack()
+	assert.ErrorIs(t, err, response.ErrNotHijackable)
+
+	assert.Equal(t, "hijacked", StateHijacked.String())
+}
+
+func TestHijackKeepsConnSlot(t *testing.T) {
+	hijacked := make(chan error, 2)
+	s, err := ServeAddr("tcp", "127.0.0.1:0", echoUpgradeHandler(hijacked),
+		WithMaxConns(1, OverloadReject), WithMaxConnsPerIP(1))
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET /upgrade HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	require.NoError(t, <-hijacked)
+	<-hijacked
+
+	// Test: Hijack된 연결이 열려있는 동안은 연결 수 제한의 자리를 계속 차지한다
+	// @@@ handle 고루틴은 이미 끝났으므로 자리를 돌려놓았다면 200이 온다
+	assert.Contains(t, get(t, "tcp", s.Addr().String()), "503 Service Unavailable")
+
+	// Test: handler가 연결을 닫으면 자리를 돌려놓는다
+	require.NoError(t, conn.Close())
+	require.Eventually(t, func() bool {
+		return strings.HasPrefix(get(t, "tcp", s.Addr().String()), "HTTP/1.1 200 OK")
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"io"
+	"net"
+	"time"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+)
+
+// Accept 에러 발생 시 재시도 대기 시간의 최소, 최대값
+const (
+	minAcceptBackoff = 5 * time.Millisecond
+	maxAcceptBackoff = 1 * time.Second
+)
+
+// 503이나 400 같은 에러 response를 보낸 뒤 client가 보낸 데이터를 버리며 기다리는 최대 시간
+// @@@ 읽지 않은 데이터가 남은 채로 소켓을 닫으면 RST가 가서 client가 response를 못 읽을 수 있다
+const rejectLingerTimeout = 500 * time.Millisecond
+
+// 이전 대기 시간을 받아 다음 Accept 재시도 대기 시간을 반환하는 함수 (지수 증가)
+func nextAcceptBackoff(prev time.Duration) time.Duration {
+	if prev == 0 {
+		return minAcceptBackoff
+	}
+
+	next := prev * 2
+	if next > maxAcceptBackoff {
+		next = maxAcceptBackoff
+	}
+
+	return next
+}
+
+// OverloadBlock 정책일 때 Accept 전에 연결 자리를 확보하는 메소드
+// 자리가 날 때까지 기다리며, 그 사이 서버가 닫히면 false 반환
+func (s *Server) waitForSlot() bool {
+	if s.sem == nil || s.overload != OverloadBlock {
+		return true
+	}
+
+	select {
+	case s.sem <- struct{}{}:
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+// Accept된 연결을 처리해도 되는지 확인하고 필요한 자리를 차지하는 메소드
+// 받아들일 수 없으면 차지했던 자리를 모두 돌려놓고 false 반환
+// @@@ OverloadBlock 정책이면 waitForSlot에서 이미 자리를 확보한 상태로 호출된다
+func (s *Server) admit(conn net.Conn) bool {
+	if s.sem != nil && s.overload == OverloadReject {
+		select {
+		case s.sem <- struct{}{}:
+		default:
+			return false
+		}
+	}
+
+	if s.maxConnsPerIP > 0 {
+		ip := remoteIP(conn)
+
+		s.mu.Lock()
+		if ip != "" && s.connsPerIP[ip] >= s.maxConnsPerIP {
+			s.mu.Unlock()
+			s.releaseSlot()
+			return false
+		}
+		if ip != "" {
+			s.connsPerIP[ip]++
+		}
+		s.mu.Unlock()
+	}
+
+	return true
+}
+
+// admit으로 차지한 자리를 돌려놓는 메소드
+func (s *Server) leave(conn net.Conn) {
+	if s.maxConnsPerIP > 0 {
+		ip := remoteIP(conn)
+
+		s.mu.Lock()
+		if ip != "" {
+			s.connsPerIP[ip]--
+			if s.connsPerIP[ip] <= 0 {
+				delete(s.connsPerIP, ip)
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	s.releaseSlot()
+}
+
+// 동시 연결 수 세마포어에서 자리 하나를 반납하는 메소드
+func (s *Server) releaseSlot() {
+	if s.sem != nil {
+		<-s.sem
+	}
+}
+
+// 서버가 포화 상태라 받을 수 없는 연결에 503을 보내고 닫는 메소드
+func (s *Server) reject(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.setState(conn, StateClosed)
+	}()
+
+	writer := response.NewWriter(conn)
+	WriteHandlerError(writer, conn, response.StatusServiceUnavailable, []byte("server is at capacity, try again later"))
+
+	linger(conn)
+}
+
+// 에러 response를 보낸 연결을 닫기 전에 client가 보낸 나머지 데이터를 잠시 읽어서 버리는 함수
+// 쓰기 방향만 먼저 닫으므로 client는 response 뒤에 EOF를 받는다
+func linger(conn net.Conn) {
+	if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+		_ = cw.CloseWrite()
+		_ = conn.SetReadDeadline(time.Now().Add(rejectLingerTimeout))
+		_, _ = io.Copy(io.Discard, io.LimitReader(conn, 64<<10))
+	}
+}
+
+// 연결된 client의 IP를 반환하는 함수 (IP가 없는 연결이면 "")
+func remoteIP(conn net.Conn) string {
+	addr, ok := conn.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return ""
+	}
+
+	return addr.IP.String()
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// request 하나를 보내 handler가 시작될 때까지 기다리는 함수
+func startRequest(t *testing.T, addr string, started <-chan struct{}) net.Conn {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"))
+	require.NoError(t, err)
+	<-started
+
+	return conn
+}
+
+// handler를 release가 닫힐 때까지 붙잡아 두는 테스트용 서버 생성 함수
+func blockingServer(t *testing.T, opts ...Option) (*Server, chan struct{}, chan struct{}) {
+	t.Helper()
+
+	started := make(chan struct{}, 16)
+	release := make(chan struct{})
+
+	s, err := ServeAddr("tcp", "127.0.0.1:0", func(w *response.Writer, req *request.Request) {
+		started <- struct{}{}
+		<-release
+		writeOK(w, "done")
+	}, opts...)
+	require.NoError(t, err)
+
+	return s, started, release
+}
+
+func TestMaxConnsReject(t *testing.T) {
+	s, started, release := blockingServer(t, WithMaxConns(1, OverloadReject))
+	defer s.Close()
+
+	first := startRequest(t, s.Addr().String(), started)
+	defer first.Close()
+
+	// Test: 자리가 없으면 바로 503
+	assert.Contains(t, get(t, "tcp", s.Addr().String()), "503 Service Unavailable")
+
+	// Test: 처리 중이던 연결은 정상적으로 끝난다
+	close(release)
+	data, err := io.ReadAll(first)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "done")
+
+	// Test: 자리가 나면 다시 받는다
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", s.Addr().String())
+		if err != nil {
+			return false
+		}
+		defer conn.Close()
+		_, _ = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"))
+		data, _ := io.ReadAll(conn)
+		return len(data) > 0 && string(data[:15]) == "HTTP/1.1 200 OK"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestMaxConnsBlock(t *testing.T) {
+	s, started, release := blockingServer(t, WithMaxConns(1, OverloadBlock))
+	defer s.Close()
+
+	first := startRequest(t, s.Addr().String(), started)
+	defer first.Close()
+
+	// Test: 두번째 연결은 자리가 날 때까지 handler가 실행되지 않는다
+	second, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer second.Close()
+	_, err = second.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"))
+	require.NoError(t, err)
+
+	select {
+	case <-started:
+		t.Fatal("second connection was handled while the server was saturated")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	for _, conn := range []net.Conn{first, second} {
+		data, err := io.ReadAll(conn)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "done")
+	}
+}
+
+func TestMaxConnsPerIP(t *testing.T) {
+	s, started, release := blockingServer(t, WithMaxConnsPerIP(1))
+	defer s.Close()
+	defer close(release)
+
+	first := startRequest(t, s.Addr().String(), started)
+	defer first.Close()
+
+	// Test: 같은 IP에서 온 두번째 연결은 503
+	assert.Contains(t, get(t, "tcp", s.Addr().String()), "503 Service Unavailable")
+}
+
+func TestNextAcceptBackoff(t *testing.T) {
+	backoff := nextAcceptBackoff(0)
+	assert.Equal(t, minAcceptBackoff, backoff)
+
+	backoff = nextAcceptBackoff(backoff)
+	assert.Equal(t, 2*minAcceptBackoff, backoff)
+
+	// Test: 최대값을 넘지 않는다
+	for range 20 {
+		backoff = nextAcceptBackoff(backoff)
+	}
+	assert.Equal(t, maxAcceptBackoff, backoff)
+}
+
+func TestMaxBodySize(t *testing.T) {
+	called := make(chan struct{}, 10)
+	s, err := ServeAddr("tcp", "127.0.0.1:0", func(w *response.Writer, req *request.Request) {
+		called <- struct{}{}
+		writeOK(w, string(req.Body))
+	}, WithMaxBodySize(1024))
+	require.NoError(t, err)
+	defer s.Close()
+
+	send := func(raw []byte) *response.Response {
+		conn, err := net.Dial("tcp", s.Addr().String())
+		require.NoError(t, err)
+		defer conn.Close()
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+
+		_, err = conn.Write(raw)
+		require.NoError(t, err)
+		resp, err := response.ResponseFromReader(conn)
+		require.NoError(t, err)
+		return resp
+	}
+
+	// Test: 제한보다 큰 gzip body를 chunked로 보내면 읽는 도중 413으로 응답하고 연결을 닫는다
+	// @@@ 압축된 body를 다 모은 뒤 풀 때가 아니라 읽는 동안 거절해야 한다
+	data := make([]byte, 4096)
+	_, err = rand.Read(data)
+	require.NoError(t, err)
+	compressed := &bytes.Buffer{}
+	zw := gzip.NewWriter(compressed)
+	_, err = zw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	require.Greater(t, compressed.Len(), 1024)
+
+	raw := &bytes.Buffer{}
+	raw.WriteString("POST /upload HTTP/1.1\r\nHost: localhost\r\nContent-Encoding: gzip\r\nTransfer-Encoding: chunked\r\n\r\n")
+	for chunk := range slices.Chunk(compressed.Bytes(), 512) {
+		fmt.Fprintf(raw, "%x\r\n%s\r\n", len(chunk), chunk)
+	}
+	raw.WriteString("0\r\n\r\n")
+	resp := send(raw.Bytes())
+	assert.Equal(t, response.StatusContentTooLarge, resp.StatusLine.StatusCode)
+	assert.Equal(t, "close", resp.Headers.Get("connection"))
+
+	// Test: Content-Length가 제한보다 크면 body를 읽지 않고 413
+	resp = send([]byte("POST /upload HTTP/1.1\r\nHost: localhost\r\nContent-Length: 2048\r\n\r\n"))
+	assert.Equal(t, response.StatusContentTooLarge, resp.StatusLine.StatusCode)
+
+	// handler는 호출되지 않았다
+	assert.Empty(t, called)
+
+	// Test: 제한 안의 body는 그대로 handler에 넘긴다
+	resp = send([]byte("POST /upload HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n0\r\n\r\n"))
+	assert.Equal(t, response.StatusOK, resp.StatusLine.StatusCode)
+	assert.Equal(t, "hello", string(resp.Body))
+}
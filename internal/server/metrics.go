@@ -0,0 +1,177 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/metrics"
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/paokimsiwoong/httpfromtcp/internal/transfer"
+)
+
+// 서버가 채우는 메트릭들
+type serverMetrics struct {
+	activeConns    *metrics.Gauge
+	requests       *metrics.CounterVec // label: method, status
+	bytesIn        *metrics.Counter
+	bytesOut       *metrics.Counter
+	parseErrors    *metrics.CounterVec // label: type
+	handlerLatency *metrics.Histogram
+}
+
+// 서버 메트릭들을 reg에 등록하도록 하는 옵션
+// reg를 metrics.Handler로 노출하면 Prometheus가 수집할 수 있다
+func WithMetrics(reg *metrics.Registry) Option {
+	return func(s *Server) {
+		s.metrics = &serverMetrics{
+			activeConns: reg.NewGauge("httpfromtcp_active_connections",
+				"Number of currently open client connections."),
+			requests: reg.NewCounterVec("httpfromtcp_requests_total",
+				"Number of handled requests by method and status code.", "method", "status"),
+			bytesIn: reg.NewCounter("httpfromtcp_received_bytes_total",
+				"Number of bytes read from client connections."),
+			bytesOut: reg.NewCounter("httpfromtcp_sent_bytes_total",
+				"Number of bytes written to client connections."),
+			parseErrors: reg.NewCounterVec("httpfromtcp_parse_errors_total",
+				"Number of requests that could not be parsed by error type.", "type"),
+			handlerLatency: reg.NewHistogram("httpfromtcp_handler_duration_seconds",
+				"Time spent in the request handler.", metrics.DefBuckets),
+		}
+	}
+}
+
+// 연결이 열리고 닫힐 때 호출하는 메소드들
+func (m *serverMetrics) connOpened() {
+	if m != nil {
+		m.activeConns.Inc()
+	}
+}
+
+func (m *serverMetrics) connClosed() {
+	if m != nil {
+		m.activeConns.Dec()
+	}
+}
+
+// handler가 처리한 request 하나를 기록하는 메소드
+// @@@ Hijack한 handler는 연결을 다 쓸 때까지(ex: WebSocket) 반환하지 않을 수 있으므로 처리 시간은 기록하지 않는다
+func (m *serverMetrics) observeRequest(method string, w *response.Writer, elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.requests.WithLabelValues(methodLabel(method), statusLabel(w)).Inc()
+	if !w.Hijacked() {
+		m.handlerLatency.Observe(elapsed.Seconds())
+	}
+}
+
+// 파싱에 실패한 request를 에러 종류별로 기록하는 메소드
+func (m *serverMetrics) observeParseError(err error) {
+	if m != nil {
+		m.parseErrors.WithLabelValues(parseErrorType(err)).Inc()
+	}
+}
+
+// 연결에 쓴 바이트 수를 세는 io.Writer를 반환하는 메소드
+func (m *serverMetrics) countWrites(w io.Writer) io.Writer {
+	if m == nil {
+		return w
+	}
+
+	return &countingWriter{w: w, counter: m.bytesOut}
+}
+
+// 연결에서 읽은 바이트 수를 세는 io.Reader를 반환하는 메소드
+func (m *serverMetrics) countReads(r io.Reader) io.Reader {
+	if m == nil {
+		return r
+	}
+
+	return &countingReader{r: r, counter: m.bytesIn}
+}
+
+// Read한 바이트 수를 counter에 더하는 io.Reader
+type countingReader struct {
+	r       io.Reader
+	counter *metrics.Counter
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.counter.Add(float64(n))
+	return n, err
+}
+
+// Write한 바이트 수를 counter에 더하는 io.Writer
+type countingWriter struct {
+	w       io.Writer
+	counter *metrics.Counter
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.counter.Add(float64(n))
+	return n, err
+}
+
+// handler가 쓴 response를 메트릭 status label 값으로 변환하는 함수
+// Hijack한 연결은 "hijacked", status line을 쓰지 않았으면 "none"
+func statusLabel(w *response.Writer) string {
+	switch {
+	case w.Hijacked():
+		return "hijacked"
+	case w.StatusCode() == 0:
+		return "none"
+	default:
+		return strconv.Itoa(int(w.StatusCode()))
+	}
+}
+
+// request method를 메트릭 label 값으로 변환하는 함수
+// @@@ method는 client가 아무 token이나 보낼 수 있으므로 표준 method가 아니면 "OTHER"로 묶어 label 조합 수를 제한한다
+func methodLabel(method string) string {
+	switch method {
+	case "GET", "HEAD", "POST", "PUT", "DELETE", "CONNECT", "OPTIONS", "TRACE", "PATCH":
+		return method
+	default:
+		return "OTHER"
+	}
+}
+
+// request 파싱 에러를 메트릭 label 값으로 변환하는 함수
+func parseErrorType(err error) string {
+	switch {
+	case errors.Is(err, request.ErrInvalidRequestLine):
+		return "invalid_request_line"
+	case errors.Is(err, request.ErrInvalidMethod):
+		return "invalid_method"
+	case errors.Is(err, request.ErrInvalidVersion):
+		return "invalid_version"
+	case errors.Is(err, request.ErrMissingEndofHeaders):
+		return "missing_end_of_headers"
+	case errors.Is(err, request.ErrIncorrectContentLength):
+		return "incorrect_content_length"
+	case errors.Is(err, request.ErrHeadersTooLarge):
+		return "headers_too_large"
+	case errors.Is(err, request.ErrBodyTooLarge):
+		return "body_too_large"
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, request.ErrConflictingFraming), errors.Is(err, request.ErrUnsupportedTransferEncoding),
+		errors.Is(err, request.ErrInvalidContentLength), errors.Is(err, transfer.ErrInvalidChunk):
+		return "invalid_framing"
+	case errors.Is(err, request.ErrIncompleteRequest), errors.Is(err, request.ErrNotParsed), errors.Is(err, request.ErrEmptyReader):
+		return "incomplete_request"
+	case errors.Is(err, headers.ErrMissingColon), errors.Is(err, headers.ErrMissingName),
+		errors.Is(err, headers.ErrInvalidName), errors.Is(err, headers.ErrInvalidWSBetweenNameAndColon):
+		return "invalid_header"
+	default:
+		return "other"
+	}
+}
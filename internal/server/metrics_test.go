@@ -0,0 +1,122 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/metrics"
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+
+	s, err := ServeAddr("tcp", "127.0.0.1:0", func(w *response.Writer, req *request.Request) {
+		writeOK(w, "hello")
+	}, WithMetrics(reg))
+	require.NoError(t, err)
+	defer s.Close()
+
+	addr := s.Addr().String()
+	get(t, "tcp", addr)
+	get(t, "tcp", addr)
+
+	// Test: 파싱 에러는 종류별로 센다
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("get / HTTP/1.1\r\n\r\n"))
+	require.NoError(t, err)
+	_, _ = conn.Read(make([]byte, 1024))
+	conn.Close()
+
+	// Test: 표준이 아닌 method는 OTHER 하나로 묶는다
+	for _, method := range []string{"PROPFIND", "FOOBAR"} {
+		conn, err := net.Dial("tcp", addr)
+		require.NoError(t, err)
+		_, err = conn.Write([]byte(method + " / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"))
+		require.NoError(t, err)
+		_, _ = io.ReadAll(conn)
+		conn.Close()
+	}
+
+	expected := []string{
+		`httpfromtcp_requests_total{method="GET",status="200"} 2`,
+		`httpfromtcp_requests_total{method="OTHER",status="200"} 2`,
+		`httpfromtcp_parse_errors_total{type="invalid_method"} 1`,
+		"httpfromtcp_active_connections 0",
+		"httpfromtcp_handler_duration_seconds_count 4",
+	}
+	require.Eventually(t, func() bool {
+		out := &bytes.Buffer{}
+		_ = reg.WriteText(out)
+		for _, line := range expected {
+			if !strings.Contains(out.String(), line+"\n") {
+				return false
+			}
+		}
+		return true
+	}, time.Second, 10*time.Millisecond)
+
+	out := &bytes.Buffer{}
+	require.NoError(t, reg.WriteText(out))
+	assert.NotContains(t, out.String(), `method="PROPFIND"`)
+	assert.NotContains(t, out.String(), "httpfromtcp_received_bytes_total 0\n")
+	assert.NotContains(t, out.String(), "httpfromtcp_sent_bytes_total 0\n")
+}
+
+func TestServerMetricsStatusLabels(t *testing.T) {
+	reg := metrics.NewRegistry()
+
+	s, err := ServeAddr("tcp", "127.0.0.1:0", func(w *response.Writer, req *request.Request) {
+		switch req.RequestLine.RequestTarget {
+		case "/hijack":
+			// status line을 쓰지 않고 연결을 넘겨받는다
+			conn, _, err := w.Hijack()
+			if err == nil {
+				conn.Close()
+			}
+		case "/empty":
+			// 아무것도 쓰지 않는다
+		}
+	}, WithMetrics(reg))
+	require.NoError(t, err)
+	defer s.Close()
+
+	for _, target := range []string{"/hijack", "/empty"} {
+		conn, err := net.Dial("tcp", s.Addr().String())
+		require.NoError(t, err)
+		_, err = conn.Write([]byte("GET " + target + " HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"))
+		require.NoError(t, err)
+		_, _ = io.ReadAll(conn)
+		conn.Close()
+	}
+
+	// Test: Hijack한 request는 hijacked, status를 쓰지 않은 request는 none으로 센다 (status="0"은 없다)
+	expected := []string{
+		`httpfromtcp_requests_total{method="GET",status="hijacked"} 1`,
+		`httpfromtcp_requests_total{method="GET",status="none"} 1`,
+		// Hijack한 handler의 처리 시간은 기록하지 않는다
+		"httpfromtcp_handler_duration_seconds_count 1",
+	}
+	require.Eventually(t, func() bool {
+		out := &bytes.Buffer{}
+		_ = reg.WriteText(out)
+		for _, line := range expected {
+			if !strings.Contains(out.String(), line+"\n") {
+				return false
+			}
+		}
+		return true
+	}, time.Second, 10*time.Millisecond)
+
+	out := &bytes.Buffer{}
+	require.NoError(t, reg.WriteText(out))
+	assert.NotContains(t, out.String(), `status="0"`)
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// handler가 실행되는 동안 연결을 백그라운드로 읽어서 client가 연결을 끊었는지 알려주는 구조체
+// Writer.CloseNotify가 처음 호출될 때 시작한다
+// @@@ handler가 실행 중일 때는 server가 연결을 읽지 않으므로 그동안만 reader를 빌려 쓴다
+type closeWatcher struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	// server가 종료를 시작하면 닫히는 채널 (Server.done)
+	serverDone <-chan struct{}
+
+	mu       sync.Mutex
+	running  bool
+	finished bool
+
+	gone     chan struct{} // client가 떠났거나 server가 종료를 시작하면 close
+	goneOnce sync.Once
+
+	// 실행 중인 백그라운드 읽기마다 새로 만든다
+	stop     chan struct{} // pause가 close
+	readDone chan struct{} // 백그라운드 읽기가 끝나면 close
+	stopping *atomic.Bool
+}
+
+func newCloseWatcher(conn net.Conn, reader *bufio.Reader, serverDone <-chan struct{}) *closeWatcher {
+	return &closeWatcher{
+		conn:       conn,
+		reader:     reader,
+		serverDone: serverDone,
+		gone:       make(chan struct{}),
+	}
+}
+
+// 백그라운드 읽기를 시작하고 gone 채널을 반환하는 메소드 (response.CloseNotifier)
+func (c *closeWatcher) notify() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.running || c.finished {
+		return c.gone
+	}
+	c.running = true
+
+	stop := make(chan struct{})
+	readDone := make(chan struct{})
+	stopping := &atomic.Bool{}
+	c.stop, c.readDone, c.stopping = stop, readDone, stopping
+
+	go func() {
+		defer close(readDone)
+		// @@@ Peek은 바이트를 소비하지 않으므로 client가 다음 request를 미리 보냈어도 잃지 않는다
+		_, err := c.reader.Peek(1)
+		if err != nil && !stopping.Load() {
+			c.markGone()
+		}
+	}()
+
+	go func() {
+		select {
+		case <-c.serverDone:
+			c.markGone()
+		case <-stop:
+		}
+	}()
+
+	return c.gone
+}
+
+func (c *closeWatcher) markGone() {
+	c.goneOnce.Do(func() {
+		close(c.gone)
+	})
+}
+
+// 백그라운드 읽기를 잠시 멈추는 메소드 (다시 notify하면 이어서 지켜본다)
+// handler가 Expect: 100-continue request의 body를 읽는 동안 reader를 같이 쓰지 않도록 사용
+func (c *closeWatcher) pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pauseLocked()
+}
+
+// 백그라운드 읽기를 멈추고 다시 시작하지 않도록 하는 메소드
+// handler가 반환된 뒤 다음 request를 읽기 전이나, Hijack으로 연결을 넘기기 전에 호출
+func (c *closeWatcher) stopWatching() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.finished = true
+	c.pauseLocked()
+}
+
+// 실행 중인 백그라운드 읽기를 멈추고 끝날 때까지 기다리는 메소드 (mu를 잡은 상태에서 호출)
+func (c *closeWatcher) pauseLocked() {
+	if !c.running {
+		return
+	}
+	c.running = false
+	close(c.stop)
+
+	// 이미 지난 deadline으로 대기 중인 Peek을 깨운 뒤 원래대로 되돌린다
+	// @@@ bufio.Reader는 읽기 에러를 한번 반환한 뒤 지우므로 다음 request 읽기에는 영향이 없다
+	c.stopping.Store(true)
+	_ = c.conn.SetReadDeadline(time.Unix(1, 0))
+	<-c.readDone
+	_ = c.conn.SetReadDeadline(time.Time{})
+}
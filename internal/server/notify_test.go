@@ -0,0 +1,113 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// /wait request는 CloseNotify 채널이 닫히거나 release를 받을 때까지 기다리고 결과를 gone으로 보내는 handler
+func waitHandler(gone chan<- bool, release <-chan struct{}) Handler {
+	return func(w *response.Writer, req *request.Request) {
+		if req.RequestLine.RequestTarget != "/wait" {
+			writeOK(w, "plain http")
+			return
+		}
+
+		notify := w.CloseNotify()
+		select {
+		case <-notify:
+			gone <- true
+		case <-release:
+			gone <- false
+		}
+		writeOK(w, "released")
+	}
+}
+
+func TestCloseNotifyOnDisconnect(t *testing.T) {
+	gone := make(chan bool, 1)
+	s, err := ServeAddr("tcp", "127.0.0.1:0", waitHandler(gone, nil))
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("GET /wait HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+
+	// Test: handler가 실행 중일 때 client가 연결을 끊으면 채널이 닫힌다
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, conn.Close())
+
+	select {
+	case g := <-gone:
+		assert.True(t, g)
+	case <-time.After(5 * time.Second):
+		t.Fatal("CloseNotify was not closed after the client disconnected")
+	}
+}
+
+func TestCloseNotifyKeepAlive(t *testing.T) {
+	gone := make(chan bool, 1)
+	release := make(chan struct{})
+	s, err := ServeAddr("tcp", "127.0.0.1:0", waitHandler(gone, release))
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+	reader := bufio.NewReader(conn)
+
+	// @@@ 두 request를 한번에 보내서 백그라운드 읽기가 두 번째 request를 먼저 읽어도 잃지 않는지 확인
+	_, err = conn.Write([]byte("GET /wait HTTP/1.1\r\nHost: localhost\r\n\r\nGET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	assert.False(t, <-gone)
+
+	// Test: 백그라운드 읽기가 멈춘 뒤에도 같은 연결로 다음 request를 처리한다
+	for _, body := range []string{"released", "plain http"} {
+		resp, err := http.ReadResponse(reader, nil)
+		require.NoError(t, err)
+		buf := make([]byte, resp.ContentLength)
+		_, err = reader.Read(buf)
+		require.NoError(t, err)
+		assert.Equal(t, body, string(buf))
+	}
+}
+
+func TestCloseNotifyOnShutdown(t *testing.T) {
+	gone := make(chan bool, 1)
+	s, err := ServeAddr("tcp", "127.0.0.1:0", waitHandler(gone, nil))
+	require.NoError(t, err)
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET /wait HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+
+	// Test: Shutdown이 시작되면 오래 걸리는 handler도 멈출 수 있도록 채널이 닫힌다
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, s.Shutdown(ctx))
+	assert.True(t, <-gone)
+}
+
+func TestCloseNotifyWithoutConn(t *testing.T) {
+	w := response.NewWriter(nil)
+	assert.Nil(t, w.CloseNotify())
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"time"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+)
+
+// Serve 계열 함수에 넘겨 서버 설정을 바꾸는 옵션 함수 타입
+// ex) server.Serve(42069, handler, server.WithMaxConns(100, server.OverloadReject))
+type Option func(*Server)
+
+// 동시 연결 수가 최대치에 도달했을 때의 동작
+type OverloadPolicy int
+
+const (
+	// 연결이 끝나 자리가 날 때까지 Accept를 멈춘다 (새 연결은 OS의 backlog에서 대기)
+	OverloadBlock OverloadPolicy = iota
+	// 일단 Accept한 뒤 503 Service Unavailable을 보내고 바로 연결을 닫는다
+	OverloadReject
+)
+
+// 동시에 처리하는 연결 수를 n개로 제한하는 옵션 (n <= 0이면 제한 없음)
+func WithMaxConns(n int, policy OverloadPolicy) Option {
+	return func(s *Server) {
+		if n <= 0 {
+			s.sem = nil
+			return
+		}
+		s.sem = make(chan struct{}, n)
+		s.overload = policy
+	}
+}
+
+// client IP 하나당 동시 연결 수를 n개로 제한하는 옵션 (n <= 0이면 제한 없음)
+// 제한을 넘는 연결에는 503 Service Unavailable을 보내고 닫는다
+// @@@ unix socket처럼 IP가 없는 연결에는 적용되지 않음
+func WithMaxConnsPerIP(n int) Option {
+	return func(s *Server) {
+		s.maxConnsPerIP = n
+	}
+}
+
+// request line과 헤더를 다 받을 때까지 기다리는 최대 시간을 정하는 옵션 (0이면 제한 없음)
+// 넘으면 408 Request Timeout을 보내고 연결을 닫는다
+// @@@ 새 연결은 첫 request의 첫 바이트를 기다리는 시간도 여기에 포함된다 (헤더를 아주 천천히 보내 연결을 붙잡아두는 client 방어)
+func WithReadHeaderTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.readHeaderTimeout = timeout
+	}
+}
+
+// keep-alive 연결이 다음 request를 기다리는 최대 시간을 정하는 옵션 (0이면 WithReadHeaderTimeout 값을 쓴다)
+// 넘으면 response 없이 연결을 닫는다
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.idleTimeout = timeout
+	}
+}
+
+// match가 true를 반환하는 request는 handler를 호출하기 전에 body를 읽지 않도록 하는 옵션
+// handler는 req.BodyReader()로 body를 받는 대로 읽거나 req.ReadBody()로 한 번에 읽는다
+// @@@ proxy처럼 큰 body를 메모리에 모으지 않고 바로 넘겨야 하는 route에 사용
+// @@@ body를 읽는 동안 CloseNotify는 쓰지 않는다 (백그라운드 읽기와 같은 reader를 쓰게 된다)
+func WithStreamingBody(match func(req *request.Request) bool) Option {
+	return func(s *Server) {
+		s.streamBody = match
+	}
+}
+
+// request body를 n 바이트까지만 받도록 하는 옵션 (n <= 0이면 제한 없음)
+// Content-Length가 n보다 크거나 chunked body가 읽는 도중 n을 넘으면 413 Content Too Large를 보내고 연결을 닫는다
+// @@@ body를 메모리에 다 모은 뒤가 아니라 읽는 동안 확인하므로 큰 body 하나로 메모리를 다 쓰게 만들 수 없다
+// @@@ WithStreamingBody로 handler가 읽는 body는 읽다가 request.ErrBodyTooLarge를 받으므로 handler가 413을 보낸다
+func WithMaxBodySize(n int64) Option {
+	return func(s *Server) {
+		s.maxBodySize = n
+	}
+}
@@ -1,12 +1,20 @@
 package server
 
 import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
+	"os"
 	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
 	"github.com/paokimsiwoong/httpfromtcp/internal/request"
@@ -14,15 +22,50 @@ import (
 )
 
 type Server struct {
-	port     int //@@@ 예시의 경우 구조체에 port 저장 안함
 	handler  Handler
 	listener net.Listener
 	closed   atomic.Bool
+
+	// 서버가 닫힐 때 close되는 채널 (Accept 대기 중인 고루틴들을 깨우는 용도)
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// 현재 열려있는 연결들과 그 상태 (graceful shutdown에 사용)
+	mu    sync.Mutex
+	conns map[net.Conn]ConnState
+
+	// 동시 연결 수 제한 (WithMaxConns, WithMaxConnsPerIP 옵션)
+	sem           chan struct{}
+	overload      OverloadPolicy
+	maxConnsPerIP int
+	connsPerIP    map[string]int
+
+	// nil이 아니면 listener를 TLS로 감싸 HTTPS로 동작 (WithTLSConfig 옵션)
+	tlsConfig *tls.Config
+
+	// nil이 아니면 요청 수, 처리 시간 등을 기록 (WithMetrics 옵션)
+	metrics *serverMetrics
+
+	// 0이면 제한 없음 (WithReadHeaderTimeout, WithIdleTimeout 옵션)
+	readHeaderTimeout time.Duration
+	idleTimeout       time.Duration
+
+	// true를 반환하는 request는 body를 미리 읽지 않는다 (WithStreamingBody 옵션)
+	streamBody func(req *request.Request) bool
+	// WithMaxBodySize로 정한 request body 크기 제한 (0이면 제한 없음)
+	maxBodySize int64
 }
 
+// Shutdown이 남은 연결들이 끝났는지 확인하는 주기
+const shutdownPollInterval = 10 * time.Millisecond
+
 // request 처리를 하는 함수들의 타입으로 쓰일 Handler 정의
 // type Handler func(w io.Writer, req *request.Request) *HandlerError
 // @@@ Handler가 header, status code, body를 직접 작성 가능하도록 구조 변경
+// WebSocket, CONNECT 터널 등 HTTP가 아닌 방식으로 연결을 써야 하면 w.Hijack()으로 net.Conn을 넘겨받을 수 있다
+// (그 뒤로 server는 그 연결을 건드리지 않으며 닫는 것도 handler의 책임)
+// Expect: 100-continue request와 WithStreamingBody에 맞는 request는 body를 미리 읽지 않으므로
+// body가 필요하면 req.ReadBody()나 req.BodyReader()로 읽어야 한다
 type Handler func(w *response.Writer, req *request.Request)
 
 // type HandlerError struct {
@@ -31,34 +74,75 @@ type Handler func(w *response.Writer, req *request.Request)
 // }
 
 // Server 구조체를 초기화하고 반환하면서 Server.listen 메소드를 고 루틴으로 시작하는 함수
-func Serve(port int, handler Handler) (*Server, error) {
-	// @@@ 예시의 경우 어차피 *Server를 반환하므로 구조체 선언때도 &Server{}로 바로 포인터 생성
-	server := Server{
-		port:    port,
-		handler: handler,
-		closed:  atomic.Bool{},
-	}
+// 모든 네트워크 인터페이스의 주어진 port에서 tcp 연결을 받는다
+func Serve(port int, handler Handler, opts ...Option) (*Server, error) {
+	return ServeAddr("tcp", fmt.Sprintf(":%v", port), handler, opts...)
+}
 
-	// tcp listener 생성 및 Server 구조체에 저장
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%v", port))
+// 주어진 network("tcp", "tcp4", "tcp6", "unix")와 주소로 listener를 만들어 서버를 시작하는 함수
+// ex) "127.0.0.1:8080", "[::1]:0", "/tmp/httpfromtcp.sock"
+// @@@ port를 0으로 주면 OS가 빈 port를 고르므로 실제 주소는 Server.Addr로 확인
+func ServeAddr(network, address string, handler Handler, opts ...Option) (*Server, error) {
+	// listener 생성
+	listener, err := net.Listen(network, address)
 	if err != nil {
 		return nil, fmt.Errorf("error creating listener: %w", err)
 	}
-	// @@@ 예시의 경우 listener 생성을 먼저 하고 그 뒤에 Server 구조체 선언
-	server.listener = listener
+
+	return ServeListener(listener, handler, opts...)
+}
+
+// 이미 만들어진 listener로 서버를 시작하는 함수
+// listener는 서버가 소유하게 되어 Close/Shutdown 시 함께 닫힌다
+func ServeListener(listener net.Listener, handler Handler, opts ...Option) (*Server, error) {
+	if listener == nil {
+		return nil, errors.New("listener must not be nil")
+	}
+
+	// @@@ 예시의 경우 어차피 *Server를 반환하므로 구조체 선언때도 &Server{}로 바로 포인터 생성
+	server := &Server{
+		handler:    handler,
+		listener:   listener,
+		done:       make(chan struct{}),
+		conns:      make(map[net.Conn]ConnState),
+		connsPerIP: make(map[string]int),
+	}
+
+	for _, opt := range opts {
+		opt(server)
+	}
+
+	// TLS 설정이 있으면 Accept된 연결이 *tls.Conn이 되도록 listener 감싸기
+	if server.tlsConfig != nil {
+		server.listener = tls.NewListener(listener, server.tlsConfig)
+	}
 
 	// go 루틴으로 listen 루프 시작
 	go server.listen()
 
-	return &server, nil
+	return server, nil
 }
 
-// tcp listener로 들어오는 연결들 처리하는 메소드
+// 서버가 연결을 받고 있는 실제 주소를 반환하는 메소드
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// listener로 들어오는 연결들 처리하는 메소드
 func (s *Server) listen() {
 
 	// server가 열려 있으면 for 루프
 	// for !s.closed.Load() { // @@@ 이 조건 필요 없음 (서버가 닫히면 s.listener.Accept()가 에러를 반환하므로 그 에러 처리를 이용해 for loop 종료 가능)
+
+	// Accept 에러가 연속으로 발생할 때 재시도 전 대기 시간
+	var backoff time.Duration
+
 	for {
+		// OverloadBlock 정책이면 연결 자리가 날 때까지 Accept하지 않고 대기
+		if !s.waitForSlot() {
+			return
+		}
+
 		curConn, err := s.listener.Accept()
 		// Accept()는 blocking 함수
 		// 즉, 연결이 들어오지 않으면 거기서 계속 멈춰 있다
@@ -72,6 +156,9 @@ func (s *Server) listen() {
 		// 이 조건 처리가 필요
 		// @@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@
 		if err != nil {
+			if s.overload == OverloadBlock {
+				s.releaseSlot()
+			}
 			if s.closed.Load() {
 				// 서버가 닫히면서 s.listener.Accept()가 에러를 반환한 경우 for 루프 종료
 				// break
@@ -80,9 +167,17 @@ func (s *Server) listen() {
 			// 서버가 닫히지 않았는데 에러가 발생했다면 심각한 문제이므로 로그 후 종료
 			// log.Fatalf("error accepting connection: %v", err)
 			// @@@ 예시처럼 에러 발생시에도 handle함수를 종료하지 않고 다음 for 루프로 넘어가기
-			log.Printf("error accepting connection: %v", err)
+			// @@@ 단, fd 고갈(EMFILE) 같은 에러는 바로 재시도해도 계속 실패하므로 대기 시간을 늘려가며 재시도
+			backoff = nextAcceptBackoff(backoff)
+			log.Printf("error accepting connection: %v; retrying in %v", err, backoff)
+			select {
+			case <-time.After(backoff):
+			case <-s.done:
+				return
+			}
 			continue
 		}
+		backoff = 0
 		// @@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@
 
 		// Accept 함수 에러처리 수정전에는 서버가 닫히면서 Accept함수가 에러를 발생하면
@@ -93,21 +188,88 @@ func (s *Server) listen() {
 		// if !s.closed.Load() {
 		// 	go s.handle(curConn)
 		// }
+		// @@@ Accept와 Shutdown 사이에 들어온 연결은 바로 닫기
+		if !s.trackConn(curConn) {
+			curConn.Close()
+			if s.overload == OverloadBlock {
+				s.releaseSlot()
+			}
+			return
+		}
+		// 동시 연결 수 제한을 넘으면 503으로 거절
+		if !s.admit(curConn) {
+			go s.reject(curConn)
+			continue
+		}
 		go s.handle(curConn)
 	}
 }
 
-// net.Conn을 받아서 response를 하는 메소드
+// net.Conn을 받아서 연결이 유지되는 동안 request들을 처리하는 메소드
 func (s *Server) handle(conn net.Conn) {
+	hijacked := false
+
 	// connection 종료 defer
-	defer conn.Close()
+	// @@@ handler가 Hijack한 연결은 handler 쪽에서 닫고, 연결 수 제한의 자리도 그때 돌려놓는다 (hijackedConn)
+	defer func() {
+		if !hijacked {
+			conn.Close()
+			s.setState(conn, StateClosed)
+			s.leave(conn)
+		}
+	}()
 
-	writer := &response.Writer{
-		State: response.WriterStateInitialized,
+	reader := bufio.NewReader(s.metrics.countReads(conn))
+	s.waitForRequest(conn, StateNew)
+
+	for {
+		// 다음 request의 첫 바이트가 들어올 때까지 대기
+		// (idle timeout이 지나거나 Shutdown이 대기 중인 연결을 깨우면 에러를 반환하며 루프 종료)
+		_, err := reader.Peek(1)
+		if err != nil {
+			return
+		}
+		s.startRequest(conn)
+
+		var keepAlive bool
+		keepAlive, hijacked = s.serveRequest(conn, reader)
+		// @@@ 종료 중이어도 client가 이미 보내서 버퍼에 들어온 request(pipelining)는 마저 처리한다
+		if !keepAlive || (s.closed.Load() && reader.Buffered() == 0) {
+			return
+		}
+
+		s.waitForRequest(conn, StateIdle)
 	}
+}
 
-	// internal/request의 RequestFromReader를 이용해 conn이 보낸 request 파싱
-	req, err := request.RequestFromReader(conn)
+// request 하나를 읽고 handler를 호출해 response를 보내는 메소드
+// 연결을 유지하고 다음 request를 받아도 되면 keepAlive true,
+// handler가 연결을 Hijack했으면 hijacked true 반환
+func (s *Server) serveRequest(conn net.Conn, reader *bufio.Reader) (keepAlive, hijacked bool) {
+	// handler가 Flush하거나 Write로 큰 body를 쓰면 바로 conn으로 나간다
+	dst := s.metrics.countWrites(conn)
+	writer := response.NewWriter(dst)
+	watcher := newCloseWatcher(conn, reader, s.done)
+	writer.SetCloseNotifier(watcher.notify)
+	writer.SetHijacker(func() (net.Conn, *bufio.Reader, error) {
+		watcher.stopWatching()
+		// @@@ 관리 대상에서 빼서 Shutdown이 기다리거나 닫지 않도록 한다
+		s.setState(conn, StateHijacked)
+		return &hijackedConn{Conn: conn, release: func() { s.leave(conn) }}, reader, nil
+	})
+
+	// internal/request의 RequestHeadersFromReader를 이용해 conn이 보낸 request line과 헤더 파싱
+	// @@@ body는 Expect: 100-continue나 WithStreamingBody에 맞는 request가 아니면 handler 호출 전에 바로 읽는다
+	req, err := request.RequestHeadersFromReader(reader)
+	if err == nil {
+		// @@@ 헤더를 다 받았으므로 read header timeout은 끝 (body와 handler에는 적용하지 않는다)
+		_ = conn.SetReadDeadline(time.Time{})
+		err = s.prepareBody(req, writer, watcher)
+	}
+	if errors.Is(err, errExpectationFailed) {
+		WriteHandlerError(writer, dst, response.StatusExpectationFailed, []byte(err.Error()))
+		return false, false
+	}
 	if err != nil {
 		// log.Fatalf("error parsing request: %v", err)
 		// @@@ 예시를 따라 HandlerError 이용
@@ -118,9 +280,20 @@ func (s *Server) handle(conn net.Conn) {
 		// 	},
 		// 	conn,
 		// )
-		WriteHandlerError(writer, conn, response.StatusBadRequest, []byte(err.Error()))
+		s.metrics.observeParseError(err)
+		WriteHandlerError(writer, dst, parseErrorStatus(err), []byte(err.Error()))
+		// @@@ 읽지 않은 request가 남은 채로 닫으면 RST가 가서 client가 에러 response를 못 읽을 수 있다
+		linger(conn)
 		// @@@ log.Fatalf 대신 return
-		return
+		return false, false
+	}
+
+	req.RemoteAddr = conn.RemoteAddr().String()
+
+	// HTTPS 연결이면 협상된 TLS 정보를 request에 저장
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		state := tlsConn.ConnectionState()
+		req.TLS = &state
 	}
 
 	// @@@ 구조 변경
@@ -131,7 +304,20 @@ func (s *Server) handle(conn net.Conn) {
 	// @@@ 구조 변경
 
 	// handler 호출
+	start := time.Now()
 	s.handler(writer, req)
+	watcher.stopWatching()
+	s.metrics.observeRequest(req.RequestLine.Method, writer, time.Since(start))
+
+	// Hijack된 연결은 더 이상 건드리지 않는다
+	if writer.Hijacked() {
+		return false, true
+	}
+
+	// @@@ handler가 읽지 않은 body가 연결에 남아있으면 다음 request로 읽을 수 없으므로 연결을 닫는다
+	if !req.BodyComplete() {
+		writer.CloseConnection()
+	}
 
 	// @@@ 구조 변경
 	// err = response.WriteStatusLine(conn, response.StatusOK)
@@ -186,7 +372,9 @@ func (s *Server) handle(conn net.Conn) {
 	// }
 	// @@@ 구조 변경
 
-	n, err := conn.Write(writer.Data)
+	// handler가 아직 내보내지 않고 남겨둔 내용 전송
+	// @@@ conn.Write(writer.Data) 대신 Writer.Flush 사용
+	err = writer.Flush()
 	if err != nil {
 		log.Printf("conn.Write error: %v", err.Error())
 		// @@@ s.handler(writer, req)를 거치고 나면
@@ -198,23 +386,126 @@ func (s *Server) handle(conn net.Conn) {
 		// WriteHandlerError(writer, conn, response.StatusInternalServerError, []byte(err.Error()))
 		// @@@@@@ conn.Write가 에러가 난 경우 (ex: write tcp [::1]:42069->[::1]:43908: write: connection reset by peer)
 		// @@@@@@ 이미 연결이 닫히거나 해서 쓰기가 불가능하므로 WriteHandlerError 안에서 conn.Write를 또하려해도 불가능
-		return
+		return false, false
+	}
+
+	// @@@ 어떤 request가 어떤 response를 받았는지는 middleware.AccessLog로 기록
+
+	// client가 Connection: close를 보냈거나 response가 연결 유지를 할 수 없으면 연결 종료
+	// @@@ "keep-alive, close"처럼 여러 token이 올 수 있으므로 값 전체가 아니라 token으로 확인
+	if headers.HasToken(req.Headers.Get("Connection"), "close") {
+		return false, false
+	}
+
+	return writer.KeepAlive(), false
+}
+
+// Expect 헤더에 100-continue가 아닌 값이 있을 때의 에러
+var errExpectationFailed = errors.New("unsupported expectation")
+
+// handler를 호출하기 전에 request body를 준비하는 메소드
+// Expect: 100-continue면 body를 읽지 않고, handler가 처음 body를 읽을 때 100 Continue를 보내도록 설정한다
+// handler는 body를 읽기 전에 417이나 413 같은 최종 response로 거절할 수 있다
+// WithStreamingBody에 맞는 request도 body를 읽지 않고 handler에 넘긴다
+// WithMaxBodySize보다 큰 Content-Length면 body를 읽지 않고 request.ErrBodyTooLarge 반환 (413)
+func (s *Server) prepareBody(req *request.Request, writer *response.Writer, watcher *closeWatcher) error {
+	expect := req.Headers.Get("expect")
+	if expect != "" && !strings.EqualFold(expect, "100-continue") {
+		return fmt.Errorf("%w: %s", errExpectationFailed, expect)
+	}
+
+	// @@@ handler가 나중에 읽는 body(100-continue, streaming)에도 같은 제한을 건다
+	req.SetMaxBodySize(s.maxBodySize)
+	if req.BodyTooLarge() {
+		return request.ErrBodyTooLarge
+	}
+
+	if !req.ExpectsContinue() {
+		if s.streamBody != nil && s.streamBody(req) {
+			return nil
+		}
+		return req.ReadBody()
 	}
 
-	fmt.Printf("Successfully writes %v to the connection %v\n", n, conn.RemoteAddr())
+	req.SetExpectContinue(func() error {
+		// @@@ body를 읽는 동안 CloseNotify의 백그라운드 읽기와 reader를 같이 쓰지 않도록 멈춘다
+		watcher.pause()
+		// 최종 response를 이미 쓰기 시작했으면 100은 보내지 않는다 (client는 기다리다가 body를 보낸다)
+		err := writer.WriteContinue()
+		if err != nil && !errors.Is(err, response.ErrWriterInvalidState) {
+			return err
+		}
+		return nil
+	})
+
+	return nil
 }
 
 // close 함수
+// 새 연결을 더 받지 않고, 처리 중인 연결까지 포함해 모든 연결을 즉시 닫는다
+// @@@ 처리 중인 request를 기다려야 하면 Shutdown 사용
 func (s *Server) Close() error {
 	// 서버 종료 true 저장
+	s.markClosed()
+
+	err := s.listener.Close()
+
+	s.closeAllConns()
+
+	return err
+}
+
+// 서버 종료 상태를 저장하고 done 채널을 닫는 메소드 (여러 번 호출해도 안전)
+func (s *Server) markClosed() {
 	s.closed.Store(true)
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+// 서버를 graceful하게 종료하는 메소드
+// 새 연결을 더 받지 않고 대기 중인 keep-alive 연결들을 닫은 뒤,
+// 처리 중인 request들이 끝날 때까지 기다린다
+// ctx가 먼저 끝나면 남은 연결들을 강제로 닫고 ctx.Err()를 반환
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.markClosed()
 
 	err := s.listener.Close()
-	if err != nil {
-		return err
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if s.closeIdleConns() {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			s.closeAllConns()
+			return ctx.Err()
+		case <-ticker.C:
+		}
 	}
+}
 
-	return nil
+// request를 파싱하지 못했을 때 보낼 status code를 고르는 함수
+// @@@ 헤더가 너무 크면 431, body가 WithMaxBodySize보다 크면 413, 풀 수 없는 Transfer-Encoding이면 501, 헤더를 read header timeout 안에 다 받지 못하면 408,
+// @@@ 나머지(Transfer-Encoding과 Content-Length가 같이 온 경우 포함)는 400
+// @@@ 어느 경우든 body 경계를 믿을 수 없으므로 연결은 닫는다
+func parseErrorStatus(err error) response.StatusCode {
+	switch {
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		return response.StatusRequestTimeout
+	case errors.Is(err, request.ErrHeadersTooLarge):
+		return response.StatusHeaderFieldsTooLarge
+	case errors.Is(err, request.ErrBodyTooLarge):
+		return response.StatusContentTooLarge
+	case errors.Is(err, request.ErrUnsupportedTransferEncoding):
+		return response.StatusNotImplemented
+	default:
+		return response.StatusBadRequest
+	}
 }
 
 // 주어진 에러 정보를 response.Writer로 쓰는 함수
@@ -0,0 +1,453 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 주어진 body를 200 OK로 보내는 테스트용 handler
+func writeOK(w *response.Writer, body string) {
+	_ = w.WriteStatusLine(response.StatusOK)
+	h := headers.NewHeaders()
+	h.SetOverride("Content-Length", strconv.Itoa(len(body)))
+	h.SetOverride("Content-Type", "text/plain")
+	_ = w.WriteHeaders(h)
+	_, _ = w.WriteBody([]byte(body))
+}
+
+func TestShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	s, err := ServeAddr("tcp", "127.0.0.1:0", func(w *response.Writer, req *request.Request) {
+		if req.RequestLine.RequestTarget == "/slow" {
+			close(started)
+			<-release
+		}
+		writeOK(w, "done")
+	})
+	require.NoError(t, err)
+
+	addr := s.Addr().String()
+
+	// Test: 대기 중인 keep-alive 연결은 Shutdown 시 바로 닫힌다
+	idle, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer idle.Close()
+	_, err = idle.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	buf := make([]byte, 1024)
+	n, err := idle.Read(buf)
+	require.NoError(t, err)
+	assert.Contains(t, string(buf[:n]), "done")
+
+	// Test: 처리 중인 request는 끝날 때까지 기다린다
+	active, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer active.Close()
+	_, err = active.Write([]byte("GET /slow HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	<-started
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		shutdownErr <- s.Shutdown(context.Background())
+	}()
+
+	// idle 연결은 handler가 끝나기 전에 닫혀야 한다
+	require.NoError(t, idle.SetReadDeadline(time.Now().Add(time.Second)))
+	_, err = idle.Read(buf)
+	assert.ErrorIs(t, err, io.EOF)
+
+	select {
+	case <-shutdownErr:
+		t.Fatal("Shutdown returned before the active handler finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	data, err := io.ReadAll(active)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "done")
+	assert.NoError(t, <-shutdownErr)
+
+	// Test: Shutdown 후에는 새 연결을 받지 않는다
+	_, err = net.Dial("tcp", addr)
+	assert.Error(t, err)
+}
+
+func TestShutdownServesBufferedRequests(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	s, err := ServeAddr("tcp", "127.0.0.1:0", func(w *response.Writer, req *request.Request) {
+		if req.RequestLine.RequestTarget == "/slow" {
+			close(started)
+			<-release
+		}
+		writeOK(w, req.RequestLine.RequestTarget)
+	})
+	require.NoError(t, err)
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Test: Shutdown 전에 이미 도착한 request는 처리 중인 request 뒤에 이어서 처리하고 닫는다
+	_, err = conn.Write([]byte("GET /slow HTTP/1.1\r\nHost: localhost\r\n\r\nGET /next HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	<-started
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		shutdownErr <- s.Shutdown(context.Background())
+	}()
+	// closeIdleConns가 몇 번 돌 때까지 기다린다
+	time.Sleep(5 * shutdownPollInterval)
+	close(release)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	data, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(string(data), "\r\n\r\n/next"), string(data))
+	assert.Equal(t, 2, strings.Count(string(data), "HTTP/1.1 200 OK\r\n"))
+	assert.NoError(t, <-shutdownErr)
+}
+
+func TestShutdownContextExpired(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	s, err := ServeAddr("tcp", "127.0.0.1:0", func(w *response.Writer, req *request.Request) {
+		close(started)
+		<-release
+		writeOK(w, "done")
+	})
+	require.NoError(t, err)
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	<-started
+
+	// Test: ctx가 먼저 끝나면 남은 연결을 강제로 닫고 ctx 에러 반환
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = s.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	_, err = io.ReadAll(conn)
+	assert.NoError(t, err)
+}
+
+// addr로 GET request를 보내고 받은 response 전체를 반환하는 함수
+func get(t *testing.T, network, addr string) string {
+	t.Helper()
+
+	conn, err := net.Dial(network, addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"))
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(conn)
+	require.NoError(t, err)
+
+	return string(data)
+}
+
+func TestServeAddr(t *testing.T) {
+	handler := func(w *response.Writer, req *request.Request) {
+		writeOK(w, "hello")
+	}
+
+	// Test: port 0이면 실제로 할당된 port를 Addr로 알려준다
+	s, err := ServeAddr("tcp", "127.0.0.1:0", handler)
+	require.NoError(t, err)
+	defer s.Close()
+	tcpAddr, ok := s.Addr().(*net.TCPAddr)
+	require.True(t, ok)
+	assert.NotZero(t, tcpAddr.Port)
+	assert.Contains(t, get(t, "tcp", s.Addr().String()), "hello")
+
+	// Test: IPv6 loopback
+	s6, err := ServeAddr("tcp6", "[::1]:0", handler)
+	if err != nil {
+		t.Logf("skipping IPv6: %v", err)
+	} else {
+		defer s6.Close()
+		assert.Contains(t, get(t, "tcp6", s6.Addr().String()), "hello")
+	}
+
+	// Test: Unix domain socket
+	sockPath := t.TempDir() + "/server.sock"
+	su, err := ServeAddr("unix", sockPath, handler)
+	require.NoError(t, err)
+	defer su.Close()
+	assert.Equal(t, sockPath, su.Addr().String())
+	assert.Contains(t, get(t, "unix", sockPath), "hello")
+
+	// Test: 잘못된 주소
+	_, err = ServeAddr("tcp", "127.0.0.1:-1", handler)
+	assert.Error(t, err)
+}
+
+func TestServeListener(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s, err := ServeListener(listener, func(w *response.Writer, req *request.Request) {
+		writeOK(w, "from listener")
+	})
+	require.NoError(t, err)
+	assert.Equal(t, listener.Addr(), s.Addr())
+	assert.Contains(t, get(t, "tcp", s.Addr().String()), "from listener")
+
+	// Test: Close하면 넘겨준 listener도 닫힌다
+	require.NoError(t, s.Close())
+	_, err = listener.Accept()
+	assert.Error(t, err)
+
+	// Test: nil listener
+	_, err = ServeListener(nil, nil)
+	assert.Error(t, err)
+}
+
+func TestEarlyHints(t *testing.T) {
+	s, err := ServeAddr("tcp", "127.0.0.1:0", func(w *response.Writer, req *request.Request) {
+		h := headers.NewHeaders()
+		h.SetOverride("Link", "</style.css>; rel=preload; as=style")
+		_ = w.WriteInterim(response.StatusEarlyHints, h)
+		writeOK(w, "final")
+	})
+	require.NoError(t, err)
+	defer s.Close()
+
+	// @@@ net/http client는 1xx response를 httptrace의 Got1xxResponse로 알려준다
+	interim := []int{}
+	links := []string{}
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			interim = append(interim, code)
+			links = append(links, header.Get("Link"))
+			return nil
+		},
+	}
+
+	req, err := http.NewRequest("GET", "http://"+s.Addr().String()+"/", nil)
+	require.NoError(t, err)
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "final", string(body))
+	assert.Equal(t, []int{103}, interim)
+	assert.Equal(t, []string{"</style.css>; rel=preload; as=style"}, links)
+}
+
+func TestPipelinedRequests(t *testing.T) {
+	s, err := ServeAddr("tcp", "127.0.0.1:0", func(w *response.Writer, req *request.Request) {
+		writeOK(w, req.RequestLine.RequestTarget+" "+string(req.Body))
+	})
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Test: 한 번에 도착한 request들도 앞 request가 뒤 request의 바이트를 가져가지 않고 차례대로 처리된다
+	_, err = conn.Write([]byte("POST /a HTTP/1.1\r\nHost: localhost\r\nContent-Length: 3\r\n\r\none" +
+		"GET /b HTTP/1.1\r\nHost: localhost\r\n\r\n" +
+		"POST /c HTTP/1.1\r\nHost: localhost\r\nContent-Length: 5\r\nConnection: close\r\n\r\nthree"))
+	require.NoError(t, err)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	data, err := io.ReadAll(conn)
+	require.NoError(t, err)
+
+	resp, err := response.ResponseFromReader(strings.NewReader(string(data)))
+	require.NoError(t, err)
+	assert.Equal(t, "/a one", string(resp.Body))
+	assert.Contains(t, string(data), "\r\n\r\n/b ")
+	assert.True(t, strings.HasSuffix(string(data), "\r\n\r\n/c three"))
+	assert.Equal(t, 3, strings.Count(string(data), "HTTP/1.1 200 OK\r\n"))
+}
+
+func TestHeadersTooLarge(t *testing.T) {
+	s, err := ServeAddr("tcp", "127.0.0.1:0", func(w *response.Writer, req *request.Request) {
+		writeOK(w, "ok")
+	})
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Test: 필드 수가 headers.MaxFields를 넘으면 빈 줄을 기다리지 않고 431로 응답하고 연결을 닫는다
+	// @@@ 보낸 바이트를 서버가 다 읽도록 빈 줄 없이 제한을 넘는 필드까지만 보낸다 (안 읽은 바이트가 남으면 RST로 response가 사라질 수 있다)
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n" + strings.Repeat("X-A: b\r\n", headers.MaxFields)))
+	require.NoError(t, err)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	resp, err := response.ResponseFromReader(conn)
+	require.NoError(t, err)
+	assert.Equal(t, response.StatusHeaderFieldsTooLarge, resp.StatusLine.StatusCode)
+	assert.Equal(t, "close", resp.Headers.Get("connection"))
+}
+
+func TestTransferEncoding(t *testing.T) {
+	targets := make(chan string, 10)
+	s, err := ServeAddr("tcp", "127.0.0.1:0", func(w *response.Writer, req *request.Request) {
+		targets <- req.RequestLine.RequestTarget
+		writeOK(w, req.RequestLine.RequestTarget+" "+string(req.Body))
+	})
+	require.NoError(t, err)
+	defer s.Close()
+
+	// raw를 한 번에 보내고 연결이 닫힐 때까지 받은 response들을 반환하는 함수
+	send := func(raw string) []*response.Response {
+		conn, err := net.Dial("tcp", s.Addr().String())
+		require.NoError(t, err)
+		defer conn.Close()
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+
+		_, err = conn.Write([]byte(raw))
+		require.NoError(t, err)
+
+		reader := bufio.NewReader(conn)
+		resps := []*response.Response{}
+		for {
+			resp, err := response.ResponseFromReader(reader)
+			if errors.Is(err, response.ErrResponseEmptyReader) {
+				return resps
+			}
+			require.NoError(t, err)
+			resps = append(resps, resp)
+		}
+	}
+
+	// Test: chunked body를 풀어서 handler에 넘기고, 뒤이은 request도 같은 연결로 처리한다
+	resps := send("POST /a HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\n\r\n3\r\none\r\n0\r\n\r\n" +
+		"GET /b HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n")
+	require.Len(t, resps, 2)
+	assert.Equal(t, "/a one", string(resps[0].Body))
+	assert.Equal(t, "/b ", string(resps[1].Body))
+	assert.Equal(t, "/a", <-targets)
+	assert.Equal(t, "/b", <-targets)
+
+	// Test: Transfer-Encoding과 Content-Length가 같이 오면 400으로 응답하고 연결을 닫는다
+	// @@@ body 길이를 Content-Length로 보는 앞단 proxy라면 뒤의 GET /smuggled를 이 request의 body로 여긴다
+	resps = send("POST /c HTTP/1.1\r\nHost: localhost\r\nContent-Length: 43\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\n" +
+		"GET /smuggled HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	require.Len(t, resps, 1)
+	assert.Equal(t, response.StatusBadRequest, resps[0].StatusLine.StatusCode)
+	assert.Equal(t, "close", resps[0].Headers.Get("connection"))
+
+	// Test: 풀 수 없는 transfer coding이면 501로 응답하고 연결을 닫는다
+	resps = send("POST /d HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: gzip\r\n\r\n" +
+		"GET /smuggled HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	require.Len(t, resps, 1)
+	assert.Equal(t, response.StatusNotImplemented, resps[0].StatusLine.StatusCode)
+
+	// handler는 /c, /d, /smuggled 어느 것도 받지 않았다
+	assert.Empty(t, targets)
+}
+
+func TestConnectionCloseToken(t *testing.T) {
+	s, err := ServeAddr("tcp", "127.0.0.1:0", func(w *response.Writer, req *request.Request) {
+		writeOK(w, "ok")
+	})
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Test: Connection 값이 token 목록이어도 close가 있으면 response 뒤에 연결을 닫는다
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nConnection: keep-alive, Close\r\n\r\n"))
+	require.NoError(t, err)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	data, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(data), "HTTP/1.1 200 OK\r\n"))
+}
+
+func TestTimeouts(t *testing.T) {
+	s, err := ServeAddr("tcp", "127.0.0.1:0", func(w *response.Writer, req *request.Request) {
+		writeOK(w, "ok")
+	}, WithReadHeaderTimeout(100*time.Millisecond), WithIdleTimeout(200*time.Millisecond))
+	require.NoError(t, err)
+	defer s.Close()
+
+	// Test: 헤더를 read header timeout 안에 다 보내지 않으면 408을 보내고 연결을 닫는다
+	slow, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer slow.Close()
+	_, err = slow.Write([]byte("GET / HTTP/1.1\r\nHost: loc"))
+	require.NoError(t, err)
+
+	require.NoError(t, slow.SetReadDeadline(time.Now().Add(2*time.Second)))
+	resp, err := response.ResponseFromReader(slow)
+	require.NoError(t, err)
+	assert.Equal(t, response.StatusRequestTimeout, resp.StatusLine.StatusCode)
+
+	// Test: 아무것도 보내지 않는 새 연결도 read header timeout 뒤에 닫는다
+	silent, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer silent.Close()
+	require.NoError(t, silent.SetReadDeadline(time.Now().Add(2*time.Second)))
+	start := time.Now()
+	_, err = silent.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// Test: keep-alive 연결은 idle timeout까지만 다음 request를 기다린다
+	idle, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer idle.Close()
+	require.NoError(t, idle.SetReadDeadline(time.Now().Add(2*time.Second)))
+	reader := bufio.NewReader(idle)
+	for range 2 {
+		_, err = idle.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+		require.NoError(t, err)
+		resp, err = response.ResponseFromReader(reader)
+		require.NoError(t, err)
+		assert.Equal(t, "ok", string(resp.Body))
+		// read header timeout보다 길게 쉬어도 idle timeout 안이면 연결이 유지된다
+		time.Sleep(150 * time.Millisecond)
+	}
+	start = time.Now()
+	_, err = reader.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrNoCertificate = errors.New("no certificate matches the requested server name")
+
+// 서버가 HTTPS로 동작하도록 listener를 TLS로 감싸는 옵션
+// cfg에는 Certificates 또는 GetCertificate가 설정되어 있어야 한다
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(s *Server) {
+		s.tlsConfig = cfg
+	}
+}
+
+// 인증서 파일과 키 파일(PEM)을 읽어 TLS 설정을 만드는 함수
+// 반환된 설정은 WithTLSConfig로 넘긴다
+func LoadTLSConfig(certFile, keyFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading certificate: %w", err)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// client가 보낸 SNI(server name)에 맞는 인증서를 고르는 함수를 반환하는 함수
+// 반환값은 tls.Config.GetCertificate에 넣어서 사용
+// certs의 key는 호스트 이름이며 "*.example.com" 형태의 와일드카드도 가능
+// 맞는 인증서가 없으면 fallback을 쓰고, fallback도 nil이면 에러
+func SNICertificates(certs map[string]*tls.Certificate, fallback *tls.Certificate) func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	// 대소문자 구분 없이 찾을 수 있도록 key를 소문자로 정리
+	byName := make(map[string]*tls.Certificate, len(certs))
+	for name, cert := range certs {
+		byName[strings.ToLower(name)] = cert
+	}
+
+	return func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+
+		if cert, ok := byName[name]; ok {
+			return cert, nil
+		}
+
+		// 첫번째 label을 *로 바꿔서 와일드카드 인증서 찾기 (a.example.com => *.example.com)
+		if i := strings.Index(name, "."); i > 0 {
+			if cert, ok := byName["*"+name[i:]]; ok {
+				return cert, nil
+			}
+		}
+
+		if fallback != nil {
+			return fallback, nil
+		}
+
+		return nil, ErrNoCertificate
+	}
+}
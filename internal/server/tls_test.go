@@ -0,0 +1,155 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 주어진 호스트 이름들로 자체 서명 인증서를 만드는 함수
+func selfSignedCert(t *testing.T, hosts ...string) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: hosts[0]},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              hosts,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}
+}
+
+// TLS로 GET request를 보내고 response와 서버가 보낸 인증서를 반환하는 함수
+func getTLS(t *testing.T, addr, serverName string, roots *x509.CertPool) (string, *x509.Certificate) {
+	t.Helper()
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: serverName, RootCAs: roots})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: " + serverName + "\r\nConnection: close\r\n\r\n"))
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(conn)
+	require.NoError(t, err)
+
+	return string(data), conn.ConnectionState().PeerCertificates[0]
+}
+
+// request의 TLS 정보를 body로 돌려주는 handler
+func tlsInfoHandler(w *response.Writer, req *request.Request) {
+	if req.TLS == nil {
+		writeOK(w, "plaintext")
+		return
+	}
+	writeOK(w, "tls sni="+req.TLS.ServerName)
+}
+
+func TestServeTLS(t *testing.T) {
+	cert := selfSignedCert(t, "localhost")
+	roots := x509.NewCertPool()
+	roots.AddCert(cert.Leaf)
+
+	s, err := ServeAddr("tcp", "127.0.0.1:0", tlsInfoHandler, WithTLSConfig(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+	}))
+	require.NoError(t, err)
+	defer s.Close()
+
+	// Test: TLS 연결의 handler는 협상된 TLS 정보를 받는다
+	resp, _ := getTLS(t, s.Addr().String(), "localhost", roots)
+	assert.Contains(t, resp, "HTTP/1.1 200 OK")
+	assert.Contains(t, resp, "tls sni=localhost")
+}
+
+func TestServeTLSSNI(t *testing.T) {
+	fooCert := selfSignedCert(t, "foo.test")
+	barCert := selfSignedCert(t, "*.bar.test")
+	defaultCert := selfSignedCert(t, "default.test")
+
+	roots := x509.NewCertPool()
+	roots.AddCert(fooCert.Leaf)
+	roots.AddCert(barCert.Leaf)
+	roots.AddCert(defaultCert.Leaf)
+
+	s, err := ServeAddr("tcp", "127.0.0.1:0", tlsInfoHandler, WithTLSConfig(&tls.Config{
+		GetCertificate: SNICertificates(map[string]*tls.Certificate{
+			"foo.test":   &fooCert,
+			"*.bar.test": &barCert,
+		}, &defaultCert),
+	}))
+	require.NoError(t, err)
+	defer s.Close()
+
+	// Test: 정확히 일치하는 이름
+	resp, peer := getTLS(t, s.Addr().String(), "foo.test", roots)
+	assert.Contains(t, resp, "tls sni=foo.test")
+	assert.Equal(t, "foo.test", peer.Subject.CommonName)
+
+	// Test: 와일드카드 (대소문자 구분 없음)
+	_, peer = getTLS(t, s.Addr().String(), "API.bar.test", roots)
+	assert.Equal(t, "*.bar.test", peer.Subject.CommonName)
+
+	// Test: 일치하는 이름이 없으면 fallback
+	_, peer = getTLS(t, s.Addr().String(), "default.test", roots)
+	assert.Equal(t, "default.test", peer.Subject.CommonName)
+
+	// Test: fallback이 없으면 에러
+	getCert := SNICertificates(map[string]*tls.Certificate{"foo.test": &fooCert}, nil)
+	_, err = getCert(&tls.ClientHelloInfo{ServerName: "nope.test"})
+	assert.ErrorIs(t, err, ErrNoCertificate)
+}
+
+func TestLoadTLSConfig(t *testing.T) {
+	cert := selfSignedCert(t, "localhost")
+
+	keyDER, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	cfg, err := LoadTLSConfig(certFile, keyFile)
+	require.NoError(t, err)
+	require.Len(t, cfg.Certificates, 1)
+
+	// Test: 존재하지 않는 파일
+	_, err = LoadTLSConfig(filepath.Join(dir, "missing.pem"), keyFile)
+	assert.Error(t, err)
+}
@@ -0,0 +1,47 @@
+package servertest
+
+import (
+	"bytes"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/paokimsiwoong/httpfromtcp/internal/server"
+)
+
+// handler가 Writer로 작성한 response를 메모리에 기록하는 구조체
+// 연결 없이 handler를 호출하므로 Hijack은 response.ErrNotHijackable을 반환한다
+type Recorder struct {
+	// handler에 넘길 Writer (Flush하면 내용이 Recorder의 버퍼로 옮겨진다)
+	Writer *response.Writer
+
+	buf bytes.Buffer
+}
+
+// 빈 Recorder를 만드는 함수
+func NewRecorder() *Recorder {
+	rec := &Recorder{}
+	rec.Writer = response.NewWriter(&rec.buf)
+	return rec
+}
+
+// 지금까지 작성된 response를 그대로(status line, 헤더, framing 포함) 반환하는 메소드
+// Writer에 남아있는 내용도 먼저 내보낸다
+func (rec *Recorder) Bytes() []byte {
+	// @@@ Hijack된 Writer만 Flush가 에러를 반환하는데 Recorder는 Hijack될 수 없다
+	_ = rec.Writer.Flush()
+	return rec.buf.Bytes()
+}
+
+// 작성된 response를 파싱해 status, 헤더, body를 반환하는 메소드
+// HEAD request에 대한 response면 response.ForMethod("HEAD")를 넘겨야 한다
+// @@@ WriteInterim으로 쓴 1xx response가 있으면 그것부터 반환되므로 Bytes로 직접 확인
+func (rec *Recorder) Result(opts ...response.ParseOption) (*response.Response, error) {
+	return response.ResponseFromReader(bytes.NewReader(rec.Bytes()), opts...)
+}
+
+// req로 handler를 호출하고 handler가 작성한 response를 파싱해서 반환하는 함수
+func Record(handler server.Handler, req *request.Request) (*response.Response, error) {
+	rec := NewRecorder()
+	handler(rec.Writer, req)
+	return rec.Result(response.ForMethod(req.RequestLine.Method))
+}
@@ -0,0 +1,37 @@
+package servertest
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+)
+
+// NewRequest로 만든 request의 RemoteAddr (RFC 5737 문서용 주소)
+const RemoteAddr = "192.0.2.1:1234"
+
+// method, target, body로 handler에 넘길 request를 만드는 함수
+// Host: example.com 헤더가 들어가고, body가 있으면 Content-Length도 들어간다
+// 헤더를 더 넣으려면 반환된 request의 Headers를 고치거나 ParseRequest를 사용
+// @@@ server와 같은 파서를 거치므로 잘못된 method나 target이면 panic
+func NewRequest(method, target, body string) *request.Request {
+	raw := method + " " + target + " HTTP/1.1\r\nHost: example.com\r\n"
+	if body != "" {
+		raw += "Content-Length: " + strconv.Itoa(len(body)) + "\r\n"
+	}
+
+	return ParseRequest(raw + "\r\n" + body)
+}
+
+// wire 형식 그대로 적은 request 문자열을 파싱해서 handler에 넘길 request를 만드는 함수
+// ex) "GET /coffee HTTP/1.1\r\nHost: localhost\r\nAccept: */*\r\n\r\n"
+// body까지 모두 읽은 상태로 반환하며, 파싱할 수 없으면 panic (테스트 코드용)
+func ParseRequest(raw string) *request.Request {
+	req, err := request.RequestFromReader(strings.NewReader(raw))
+	if err != nil {
+		panic("servertest: invalid request: " + err.Error())
+	}
+	req.RemoteAddr = RemoteAddr
+
+	return req
+}
@@ -0,0 +1,29 @@
+package servertest
+
+import (
+	"github.com/paokimsiwoong/httpfromtcp/internal/server"
+)
+
+// 테스트용으로 loopback 주소의 빈 port에서 동작하는 server
+// 사용이 끝나면 Close로 닫는다
+type Server struct {
+	*server.Server
+
+	// server에 접속할 수 있는 주소 (ex: "http://127.0.0.1:51234")
+	URL string
+}
+
+// handler로 127.0.0.1의 빈 port에서 server를 시작하는 함수
+// opts는 server.ServeAddr에 그대로 넘긴다 (server.WithTLSConfig를 넘기면 URL의 scheme은 직접 https로 바꿔서 사용)
+// @@@ 42069처럼 고정된 port를 쓰지 않으므로 여러 테스트가 동시에 server를 띄워도 된다
+func NewServer(handler server.Handler, opts ...server.Option) *Server {
+	s, err := server.ServeAddr("tcp", "127.0.0.1:0", handler, opts...)
+	if err != nil {
+		panic("servertest: failed to listen on a loopback port: " + err.Error())
+	}
+
+	return &Server{
+		Server: s,
+		URL:    "http://" + s.Addr().String(),
+	}
+}
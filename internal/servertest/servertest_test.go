@@ -0,0 +1,101 @@
+package servertest
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/client"
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// request method, target, body를 그대로 돌려주는 handler
+func echo(w *response.Writer, req *request.Request) {
+	body := []byte(req.RequestLine.Method + " " + req.RequestLine.RequestTarget + " " + string(req.Body))
+
+	_ = w.WriteStatusLine(response.StatusOK)
+	h := headers.NewHeaders()
+	h.SetOverride("Content-Type", "text/plain")
+	h.SetOverride("Content-Length", strconv.Itoa(len(body)))
+	h.SetOverride("X-Remote-Addr", req.RemoteAddr)
+	_ = w.WriteHeaders(h)
+	_, _ = w.WriteBody(body)
+}
+
+func TestRecord(t *testing.T) {
+	resp, err := Record(echo, NewRequest("POST", "/coffee", "two shots"))
+	require.NoError(t, err)
+
+	assert.Equal(t, response.StatusOK, resp.StatusLine.StatusCode)
+	assert.Equal(t, RemoteAddr, resp.Headers.Get("X-Remote-Addr"))
+	assert.Equal(t, "POST /coffee two shots", string(resp.Body))
+
+	// Test: HEAD response는 Content-Length가 있어도 body 없이 파싱
+	resp, err = Record(echo, NewRequest("HEAD", "/", ""))
+	require.NoError(t, err)
+	assert.Equal(t, "7", resp.Headers.Get("Content-Length"))
+	assert.Empty(t, resp.Body)
+}
+
+func TestRecorderChunked(t *testing.T) {
+	rec := NewRecorder()
+
+	require.NoError(t, rec.Writer.WriteStatusLine(response.StatusOK))
+	h := headers.NewHeaders()
+	h.SetOverride("Transfer-Encoding", "chunked")
+	require.NoError(t, rec.Writer.WriteHeaders(h))
+	for _, part := range []string{"hello, ", "world"} {
+		_, err := rec.Writer.WriteChunkedBody([]byte(part))
+		require.NoError(t, err)
+	}
+	_, err := rec.Writer.WriteChunkedBodyDone()
+	require.NoError(t, err)
+
+	// Test: framing이 그대로 기록되고 Result는 풀린 body를 반환
+	assert.Contains(t, string(rec.Bytes()), "7\r\nhello, \r\n")
+	resp, err := rec.Result()
+	require.NoError(t, err)
+	assert.Equal(t, "hello, world", string(resp.Body))
+
+	// Test: 연결이 없으므로 Hijack 불가
+	_, _, err = rec.Writer.Hijack()
+	assert.ErrorIs(t, err, response.ErrNotHijackable)
+}
+
+func TestParseRequest(t *testing.T) {
+	req := ParseRequest("GET /search?q=go HTTP/1.1\r\nHost: localhost\r\nAccept: text/html\r\n\r\n")
+
+	assert.Equal(t, "GET", req.RequestLine.Method)
+	assert.Equal(t, "/search?q=go", req.RequestLine.RequestTarget)
+	assert.Equal(t, "text/html", req.Headers.Get("Accept"))
+	assert.True(t, req.BodyComplete())
+
+	// Test: 파싱할 수 없는 request면 panic
+	assert.Panics(t, func() { ParseRequest("not a request\r\n\r\n") })
+	assert.Panics(t, func() { NewRequest("get", "/", "") })
+}
+
+func TestNewServer(t *testing.T) {
+	s := NewServer(echo)
+	defer s.Close()
+
+	// Test: 서로 다른 빈 port를 쓰므로 동시에 여러 개를 띄울 수 있다
+	other := NewServer(echo)
+	defer other.Close()
+	assert.NotEqual(t, s.URL, other.URL)
+
+	resp, err := client.DefaultClient.Get(context.Background(), s.URL+"/ping")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, response.StatusOK, resp.StatusCode)
+	assert.Equal(t, "GET /ping ", string(body))
+	assert.Contains(t, resp.Headers.Get("X-Remote-Addr"), "127.0.0.1:")
+}
@@ -0,0 +1,285 @@
+package sse
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+)
+
+// keep-alive comment를 보내는 기본 주기
+// @@@ 중간의 proxy나 load balancer가 idle 연결을 끊는 시간(보통 30~60초)보다 짧게
+const DefaultKeepAlive = 15 * time.Second
+
+var ErrStreamClosed = errors.New("sse: stream is closed")
+var ErrInvalidField = errors.New("sse: event name and id must not contain line breaks or NUL")
+
+// 클라이언트로 보낼 이벤트 하나
+type Event struct {
+	// 이벤트 종류 (비어있으면 client에서는 "message" 이벤트)
+	Event string
+	// client가 재연결할 때 Last-Event-ID로 돌려보내는 값
+	ID string
+	// 이벤트 내용 (여러 줄이면 줄마다 data: 필드로 나눠 보낸다)
+	Data string
+	// client가 재연결 전에 기다릴 시간 (0이면 보내지 않는다)
+	Retry time.Duration
+}
+
+// NewStream에 넘겨 stream 설정을 바꾸는 옵션 함수 타입
+type Option func(*Stream)
+
+// keep-alive comment를 보내는 주기를 정하는 옵션 (0 이하면 보내지 않는다)
+func WithKeepAlive(interval time.Duration) Option {
+	return func(s *Stream) {
+		s.keepAlive = interval
+	}
+}
+
+// stream을 시작할 때 retry: 필드로 client의 재연결 대기 시간을 정하는 옵션
+func WithRetry(d time.Duration) Option {
+	return func(s *Stream) {
+		s.retry = d
+	}
+}
+
+// text/event-stream response 하나
+// 이벤트마다 chunk 하나로 작성하고 바로 연결로 내보낸다
+// Send 등은 여러 고루틴에서 동시에 호출해도 되지만, Close는 handler가 반환하기 전에 한번 호출해야 한다
+type Stream struct {
+	w           *response.Writer
+	lastEventID string
+	keepAlive   time.Duration
+	retry       time.Duration
+
+	mu     sync.Mutex
+	closed bool
+	err    error // 처음 실패한 쓰기의 에러
+
+	done     chan struct{} // client가 떠났거나, server가 종료를 시작했거나, 쓰기에 실패하면 close
+	doneOnce sync.Once
+	stop     chan struct{} // Close가 keep-alive 고루틴을 멈출 때 close
+	stopped  chan struct{}
+}
+
+// 200 text/event-stream 헤더를 보내고 Stream을 시작하는 함수
+// 헤더는 바로 연결로 내보내므로 client는 첫 이벤트 전에도 연결이 열렸다는 것을 안다
+func NewStream(w *response.Writer, req *request.Request, opts ...Option) (*Stream, error) {
+	s := &Stream{
+		w:           w,
+		lastEventID: req.Headers.Get("last-event-id"),
+		keepAlive:   DefaultKeepAlive,
+		done:        make(chan struct{}),
+		stop:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	err := w.WriteStatusLine(response.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+
+	h := headers.NewHeaders()
+	h.SetOverride("Content-Type", "text/event-stream; charset=utf-8")
+	h.SetOverride("Cache-Control", "no-cache")
+	h.SetOverride("Transfer-Encoding", "chunked")
+	// @@@ nginx 같은 reverse proxy가 response를 모아뒀다 보내지 않도록
+	h.SetOverride("X-Accel-Buffering", "no")
+
+	err = w.WriteHeaders(h)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.retry > 0 {
+		err = s.write("retry: " + strconv.FormatInt(s.retry.Milliseconds(), 10) + "\n\n")
+	} else {
+		err = s.flush()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	go s.watch(w.CloseNotify())
+
+	return s, nil
+}
+
+// client가 재연결하면서 보낸 Last-Event-ID 헤더 값을 반환하는 메소드 (처음 연결이면 "")
+// handler는 이 값 다음의 이벤트부터 다시 보내면 된다
+func (s *Stream) LastEventID() string {
+	return s.lastEventID
+}
+
+// client가 연결을 끊었거나 쓰기에 실패해서 더 보낼 수 없게 되면 닫히는 채널을 반환하는 메소드
+// (server가 종료를 시작할 때도 닫힌다)
+func (s *Stream) Done() <-chan struct{} {
+	return s.done
+}
+
+// 처음 실패한 쓰기의 에러를 반환하는 메소드
+func (s *Stream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// 이벤트 하나를 보내는 메소드
+func (s *Stream) Send(ev Event) error {
+	if strings.ContainsAny(ev.Event, "\r\n\x00") || strings.ContainsAny(ev.ID, "\r\n\x00") {
+		return ErrInvalidField
+	}
+
+	var b strings.Builder
+	if ev.Event != "" {
+		b.WriteString("event: " + ev.Event + "\n")
+	}
+	if ev.ID != "" {
+		b.WriteString("id: " + ev.ID + "\n")
+	}
+	if ev.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(ev.Retry.Milliseconds(), 10) + "\n")
+	}
+	// @@@ \r\n, \r도 줄바꿈으로 인정되므로 모두 \n으로 맞춘 뒤 줄마다 data: 필드로
+	data := strings.ReplaceAll(ev.Data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\r", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+
+	return s.write(b.String())
+}
+
+// data만 있는 이벤트를 보내는 메소드
+func (s *Stream) SendData(data string) error {
+	return s.Send(Event{Data: data})
+}
+
+// comment 줄을 보내는 메소드 (client의 EventSource는 무시한다)
+func (s *Stream) Comment(text string) error {
+	text = strings.ReplaceAll(text, "\r", "")
+	var b strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		b.WriteString(": " + line + "\n")
+	}
+	b.WriteString("\n")
+
+	return s.write(b.String())
+}
+
+// keep-alive를 멈추고 마지막 chunk를 작성해서 response를 끝내는 메소드
+// client가 이미 떠났으면 남은 것을 쓰지 않고 연결을 닫도록 표시한다
+func (s *Stream) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.stop)
+	<-s.stopped
+
+	// @@@ client가 이미 떠났으면 마지막 chunk를 써도 받을 쪽이 없다
+	select {
+	case <-s.done:
+		s.w.CloseConnection()
+		return s.Err()
+	default:
+	}
+	s.markDone()
+
+	_, err := s.w.WriteChunkedBodyDone()
+	if err != nil {
+		return err
+	}
+
+	return s.w.Flush()
+}
+
+// 주어진 frame을 chunk 하나로 작성하고 바로 내보내는 메소드
+func (s *Stream) write(frame string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrStreamClosed
+	}
+	if s.err != nil {
+		return s.err
+	}
+
+	_, err := s.w.WriteChunkedBody([]byte(frame))
+	if err == nil {
+		err = s.w.Flush()
+	}
+	if err != nil {
+		s.fail(err)
+	}
+
+	return err
+}
+
+// 쌓인 내용을 바로 내보내는 메소드
+func (s *Stream) flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.w.Flush()
+	if err != nil {
+		s.fail(err)
+	}
+
+	return err
+}
+
+// 쓰기 에러를 기록하고 Done을 닫는 메소드 (mu를 잡은 상태에서 호출)
+func (s *Stream) fail(err error) {
+	s.err = err
+	s.markDone()
+}
+
+func (s *Stream) markDone() {
+	s.doneOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+// keep-alive comment를 주기적으로 보내고 client가 떠나는지 지켜보는 고루틴
+// @@@ 연결 종료 알림이 없는 환경에서도 keep-alive 쓰기가 실패하면 client가 떠난 것을 알 수 있다
+func (s *Stream) watch(clientGone <-chan struct{}) {
+	defer close(s.stopped)
+
+	var tick <-chan time.Time
+	if s.keepAlive > 0 {
+		ticker := time.NewTicker(s.keepAlive)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-clientGone:
+			s.markDone()
+			return
+		case <-tick:
+			err := s.write(": keep-alive\n\n")
+			if err != nil {
+				return
+			}
+		}
+	}
+}
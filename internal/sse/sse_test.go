@@ -0,0 +1,164 @@
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/paokimsiwoong/httpfromtcp/internal/server"
+	"github.com/paokimsiwoong/httpfromtcp/internal/servertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// handler로 server를 띄우고 주소를 반환하는 함수
+func start(t *testing.T, handler server.Handler) string {
+	t.Helper()
+
+	s := servertest.NewServer(handler)
+	t.Cleanup(func() { _ = s.Close() })
+
+	return s.URL
+}
+
+func newRequest(h map[string]string) *request.Request {
+	hs := headers.NewHeaders()
+	for k, v := range h {
+		hs[k] = v
+	}
+	return &request.Request{
+		RequestLine: request.RequestLine{Method: "GET", RequestTarget: "/events", HttpVersion: "1.1"},
+		Headers:     hs,
+	}
+}
+
+func TestSendFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		event Event
+		want  string
+	}{
+		{"data only", Event{Data: "hello"}, "data: hello\n\n"},
+		{"all fields", Event{Event: "update", ID: "42", Data: "x", Retry: 1500 * time.Millisecond}, "event: update\nid: 42\nretry: 1500\ndata: x\n\n"},
+		{"multi line", Event{Data: "a\nb\r\nc\rd"}, "data: a\ndata: b\ndata: c\ndata: d\n\n"},
+		{"empty data", Event{ID: "7"}, "id: 7\ndata: \n\n"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			w := response.NewWriter(buf)
+			s, err := NewStream(w, newRequest(nil), WithKeepAlive(0))
+			require.NoError(t, err)
+			buf.Reset()
+
+			require.NoError(t, s.Send(tc.event))
+
+			// chunk 하나로 바로 내보낸다
+			chunk := strings.SplitN(buf.String(), "\r\n", 2)
+			require.Len(t, chunk, 2)
+			assert.Equal(t, tc.want+"\r\n", chunk[1])
+			require.NoError(t, s.Close())
+		})
+	}
+}
+
+func TestSendInvalidField(t *testing.T) {
+	w := response.NewWriter(&bytes.Buffer{})
+	s, err := NewStream(w, newRequest(nil), WithKeepAlive(0))
+	require.NoError(t, err)
+	defer s.Close()
+
+	assert.ErrorIs(t, s.Send(Event{Event: "a\nb", Data: "x"}), ErrInvalidField)
+	assert.ErrorIs(t, s.Send(Event{ID: "1\r", Data: "x"}), ErrInvalidField)
+	assert.ErrorIs(t, s.Send(Event{ID: "1\x00", Data: "x"}), ErrInvalidField)
+}
+
+func TestStream(t *testing.T) {
+	url := start(t, func(w *response.Writer, req *request.Request) {
+		s, err := NewStream(w, req, WithRetry(3*time.Second), WithKeepAlive(0))
+		require.NoError(t, err)
+		_ = s.Send(Event{Event: "greeting", ID: "1", Data: "hello"})
+		_ = s.Comment("just a comment")
+		_ = s.SendData("line1\nline2")
+		_ = s.Close()
+
+		assert.ErrorIs(t, s.SendData("late"), ErrStreamClosed)
+	})
+
+	resp, err := http.Get(url + "/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, "text/event-stream; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "no-cache", resp.Header.Get("Cache-Control"))
+	assert.Equal(t, []string{"chunked"}, resp.TransferEncoding)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "retry: 3000\n\n"+
+		"event: greeting\nid: 1\ndata: hello\n\n"+
+		": just a comment\n\n"+
+		"data: line1\ndata: line2\n\n", string(body))
+}
+
+func TestLastEventID(t *testing.T) {
+	url := start(t, func(w *response.Writer, req *request.Request) {
+		s, err := NewStream(w, req, WithKeepAlive(0))
+		require.NoError(t, err)
+		_ = s.SendData("resume after " + s.LastEventID())
+		_ = s.Close()
+	})
+
+	req, err := http.NewRequest("GET", url+"/events", nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", "41")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "data: resume after 41\n\n", string(body))
+}
+
+func TestKeepAliveAndDisconnect(t *testing.T) {
+	stopped := make(chan error, 1)
+	url := start(t, func(w *response.Writer, req *request.Request) {
+		s, err := NewStream(w, req, WithKeepAlive(20*time.Millisecond))
+		require.NoError(t, err)
+
+		// 이벤트를 보내지 않고 client가 떠날 때까지 기다린다
+		select {
+		case <-s.Done():
+		case <-time.After(5 * time.Second):
+		}
+		stopped <- s.Close()
+	})
+
+	resp, err := http.Get(url + "/events")
+	require.NoError(t, err)
+
+	// Test: 이벤트가 없어도 keep-alive comment가 주기적으로 온다
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, ": keep-alive\n", line)
+
+	// Test: client가 연결을 끊으면 Done이 닫혀서 handler가 멈춘다
+	require.NoError(t, resp.Body.Close())
+
+	select {
+	case <-stopped:
+	case <-time.After(3 * time.Second):
+		t.Fatal("stream did not stop after the client disconnected")
+	}
+}
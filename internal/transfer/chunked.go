@@ -0,0 +1,173 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+)
+
+var ErrInvalidChunk = errors.New("chunked body is malformed")
+
+// chunked transfer coding을 풀면서 읽는 reader (RFC 9112 7.1)
+// 마지막 chunk 뒤의 trailer 필드들은 trailers에 넣고 io.EOF를 반환한다
+// 중간에 r이 끝나면 io.ErrUnexpectedEOF, 형식이 틀리면(trailer 필드 포함) ErrInvalidChunk를 반환한다
+// @@@ r에서 마지막 chunk와 trailer 뒤의 바이트는 읽지 않으므로 같은 연결의 다음 메시지를 이어서 읽을 수 있다
+type ChunkedReader struct {
+	r        *bufio.Reader
+	trailers headers.Headers
+	lines    headers.LineReader
+
+	remaining int64 // 지금 chunk에서 남은 데이터 바이트 수
+	started   bool  // 첫 chunk size 줄을 읽었는지
+	err       error // 한번 실패하거나 끝나면 계속 반환
+}
+
+func NewChunkedReader(r *bufio.Reader, trailers headers.Headers) *ChunkedReader {
+	return &ChunkedReader{r: r, trailers: trailers}
+}
+
+func (c *ChunkedReader) Read(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+
+	if c.remaining == 0 {
+		err := c.nextChunk()
+		if err != nil {
+			c.err = err
+			return 0, err
+		}
+	}
+
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+	n, err := c.r.Read(p)
+	c.remaining -= int64(n)
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		c.err = err
+	}
+
+	return n, err
+}
+
+// 앞 chunk의 끝 CRLF와 다음 chunk size 줄을 읽는 메소드
+// 마지막 chunk(크기 0)면 trailer까지 읽고 io.EOF 반환
+func (c *ChunkedReader) nextChunk() error {
+	c.lines.Reset(c.r, headers.MaxHeaderBytes)
+
+	if c.started {
+		line, err := c.readLine()
+		if err != nil {
+			return err
+		}
+		if len(line) != len("\r\n") {
+			return fmt.Errorf("%w: chunk data is longer than its size", ErrInvalidChunk)
+		}
+	}
+	c.started = true
+
+	line, err := c.readLine()
+	if err != nil {
+		return err
+	}
+
+	size, err := parseChunkSize(line[:len(line)-len("\r\n")])
+	if err != nil {
+		return err
+	}
+
+	if size == 0 {
+		err = c.lines.ReadHeaders(c.trailers)
+		if errors.Is(err, io.EOF) {
+			return io.ErrUnexpectedEOF
+		}
+		if errors.Is(err, headers.ErrMalformed) {
+			return fmt.Errorf("%w: %w", ErrInvalidChunk, err)
+		}
+		if err != nil {
+			return err
+		}
+		return io.EOF
+	}
+
+	c.remaining = size
+	return nil
+}
+
+// chunk size 줄이나 chunk 끝의 CRLF를 읽는 메소드 (여기서 r이 끝나면 body가 잘린 것)
+func (c *ChunkedReader) readLine() ([]byte, error) {
+	line, err := c.lines.ReadLine()
+	if errors.Is(err, io.EOF) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if errors.Is(err, headers.ErrBareLF) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidChunk, err)
+	}
+	return line, err
+}
+
+// chunk size 줄(CRLF 제외, ex: "1a3f", "10;name=value")에서 크기를 파싱하는 함수
+// @@@ chunk extension은 의미를 정의하지 않았으므로 무시한다
+func parseChunkSize(line []byte) (int64, error) {
+	hex, _, _ := bytes.Cut(line, []byte(";"))
+	hex = bytes.TrimRight(hex, " \t")
+
+	// @@@ 16자리를 넘으면 int64를 넘어서므로 거절 ("+", "-" 같은 부호도 받지 않는다)
+	if len(hex) == 0 || len(hex) > 16 || bytes.IndexFunc(hex, func(c rune) bool {
+		return !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F')
+	}) != -1 {
+		return 0, fmt.Errorf("%w: invalid chunk size %q", ErrInvalidChunk, line)
+	}
+
+	size, err := strconv.ParseInt(string(hex), 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid chunk size %q", ErrInvalidChunk, line)
+	}
+
+	return size, nil
+}
+
+// 쓰는 데이터를 chunk 하나씩으로 감싸서 w에 쓰는 writer (ChunkedReader의 반대)
+// Close는 마지막 chunk(0 크기)와 빈 trailer를 쓰고, w는 닫지 않는다
+// @@@ 빈 chunk는 body의 끝이라는 뜻이므로 길이 0인 Write는 아무것도 쓰지 않는다
+type ChunkedWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+func NewChunkedWriter(w io.Writer) *ChunkedWriter {
+	return &ChunkedWriter{w: w}
+}
+
+func (c *ChunkedWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	// @@@ 크기 줄, 데이터, CRLF를 한 번에 보내서 작은 패킷이 여러 개 나가지 않도록 한다
+	c.buf = strconv.AppendInt(c.buf[:0], int64(len(p)), 16)
+	c.buf = append(c.buf, "\r\n"...)
+	c.buf = append(c.buf, p...)
+	c.buf = append(c.buf, "\r\n"...)
+
+	_, err := c.w.Write(c.buf)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+func (c *ChunkedWriter) Close() error {
+	_, err := io.WriteString(c.w, "0\r\n\r\n")
+	return err
+}
@@ -0,0 +1,77 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"maps"
+	"strconv"
+	"testing"
+	"testing/iotest"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+)
+
+// src를 ChunkedReader로 풀어서 p 크기씩 끝까지 읽는 함수
+func readChunked(src io.Reader, p []byte) ([]byte, headers.Headers, error) {
+	trailers := headers.NewHeaders()
+	r := NewChunkedReader(bufio.NewReaderSize(src, 16), trailers)
+
+	body := []byte{}
+	for {
+		n, err := r.Read(p)
+		body = append(body, p[:n]...)
+		if err == io.EOF {
+			return body, trailers, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// seed corpus는 testdata/fuzz/FuzzChunkedReader (curl로 받은 chunked response body들)
+func FuzzChunkedReader(f *testing.F) {
+	f.Add([]byte("5\r\nhello\r\n7;ext=1\r\n, world\r\n0\r\n\r\n"), uint8(3))
+	f.Add([]byte("A\r\n0123456789\r\n0\r\nX-Checksum: 42\r\n\r\n"), uint8(1))
+
+	f.Fuzz(func(t *testing.T, chunked []byte, readSize uint8) {
+		want, wantTrailers, wantErr := readChunked(bytes.NewReader(chunked), make([]byte, 4096))
+
+		// Property: 연결에서 한 번에 받는 크기, Read에 넘기는 버퍼 크기와 상관없이 같은 결과
+		n := int(readSize)%64 + 1
+		got, gotTrailers, err := readChunked(iotest.OneByteReader(bytes.NewReader(chunked)), make([]byte, n))
+		if (err == nil) != (wantErr == nil) {
+			t.Fatalf("read size %d: error %v, reading at once: %v", n, err, wantErr)
+		}
+		if wantErr != nil {
+			return
+		}
+		if !bytes.Equal(got, want) || !maps.Equal(gotTrailers, wantTrailers) {
+			t.Fatalf("read size %d: body %q trailers %v, reading at once: %q %v", n, got, gotTrailers, want, wantTrailers)
+		}
+
+		// Property: 풀어낸 body와 trailers를 다른 크기의 chunk로 다시 인코딩해도 같은 결과
+		buf := &bytes.Buffer{}
+		for rest := want; len(rest) > 0; {
+			size := min(n, len(rest))
+			buf.WriteString(strconv.FormatInt(int64(size), 16) + "\r\n")
+			buf.Write(rest[:size])
+			buf.WriteString("\r\n")
+			rest = rest[size:]
+		}
+		buf.WriteString("0\r\n")
+		for key, value := range wantTrailers {
+			buf.WriteString(key + ": " + value + "\r\n")
+		}
+		buf.WriteString("\r\n")
+
+		again, againTrailers, err := readChunked(bytes.NewReader(buf.Bytes()), make([]byte, 4096))
+		if err != nil {
+			t.Fatalf("re-encoded body cannot be read: %v\n%q", err, buf.String())
+		}
+		if !bytes.Equal(again, want) || !maps.Equal(againTrailers, wantTrailers) {
+			t.Fatalf("round trip changed body: %q %v\n%q %v", want, wantTrailers, again, againTrailers)
+		}
+	})
+}
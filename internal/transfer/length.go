@@ -0,0 +1,68 @@
+package transfer
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidContentLength = errors.New("content length must be a non-negative number")
+
+// Content-Length 값을 파싱하는 함수
+// @@@ 같은 헤더가 여러 번 와서 "5, 5"처럼 합쳐진 경우 값이 모두 같을 때만 받는다 (RFC 9110 8.6)
+// @@@ strconv.ParseInt는 "+5" 같은 부호도 받으므로 숫자만 있는지 먼저 확인
+func ParseContentLength(value string) (int64, error) {
+	var length int64 = -1
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" || strings.IndexFunc(part, func(c rune) bool { return c < '0' || c > '9' }) != -1 {
+			return 0, ErrInvalidContentLength
+		}
+		n, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || (length != -1 && n != length) {
+			return 0, ErrInvalidContentLength
+		}
+		length = n
+	}
+	return length, nil
+}
+
+// Content-Length 만큼만 읽는 reader
+// @@@ io.LimitReader와 달리 길이를 다 채우기 전에 r이 끝나면 io.ErrUnexpectedEOF
+type LengthReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func NewLengthReader(r io.Reader, length int64) *LengthReader {
+	return &LengthReader{r: r, remaining: length}
+}
+
+func (l *LengthReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if errors.Is(err, io.EOF) {
+		if l.remaining > 0 {
+			return n, io.ErrUnexpectedEOF
+		}
+		err = nil
+	}
+	if err == nil && l.remaining == 0 {
+		err = io.EOF
+	}
+
+	return n, err
+}
+
+// 아직 읽지 않은 바이트 수를 반환하는 메소드
+func (l *LengthReader) Remaining() int64 {
+	return l.remaining
+}
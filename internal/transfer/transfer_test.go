@@ -0,0 +1,95 @@
+package transfer
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseContentLength(t *testing.T) {
+	for value, want := range map[string]int64{"0": 0, "42": 42, "5, 5": 5, " 7 ": 7} {
+		got, err := ParseContentLength(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+	}
+
+	// Test: 부호, 빈 값, 서로 다른 값, int64를 넘는 값은 거절
+	for _, value := range []string{"", "-1", "+5", "5, 6", "1,,1", "0x10", "99999999999999999999"} {
+		_, err := ParseContentLength(value)
+		assert.ErrorIs(t, err, ErrInvalidContentLength, value)
+	}
+}
+
+func TestLengthReader(t *testing.T) {
+	// Test: 길이 뒤의 바이트는 읽지 않는다
+	src := strings.NewReader("hello, world")
+	body, err := io.ReadAll(NewLengthReader(src, 5))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(body))
+	rest, _ := io.ReadAll(src)
+	assert.Equal(t, ", world", string(rest))
+
+	// Test: 길이를 채우기 전에 끝나면 io.ErrUnexpectedEOF
+	r := NewLengthReader(strings.NewReader("short"), 10)
+	_, err = io.ReadAll(r)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Equal(t, int64(5), r.Remaining())
+}
+
+func TestChunkedReader(t *testing.T) {
+	// Test: chunk extension은 무시하고 trailer는 trailers에 넣는다, 마지막 chunk 뒤의 바이트는 남겨둔다
+	src := bufio.NewReader(iotest.OneByteReader(strings.NewReader(
+		"5;ext=1\r\nhello\r\n7\r\n, world\r\n0\r\nX-Checksum: 42\r\n\r\nGET / HTTP/1.1\r\n")))
+	trailers := headers.NewHeaders()
+	body, err := io.ReadAll(NewChunkedReader(src, trailers))
+	require.NoError(t, err)
+	assert.Equal(t, "hello, world", string(body))
+	assert.Equal(t, "42", trailers.Get("x-checksum"))
+	rest, _ := io.ReadAll(src)
+	assert.Equal(t, "GET / HTTP/1.1\r\n", string(rest))
+
+	tests := []struct {
+		name string
+		raw  string
+		err  error
+	}{
+		{"signed size", "+5\r\nhello\r\n0\r\n\r\n", ErrInvalidChunk},
+		{"size overflows", "10000000000000000\r\n", ErrInvalidChunk},
+		{"chunk longer than size", "2\r\nabc\r\n0\r\n\r\n", ErrInvalidChunk},
+		{"bare LF", "2\nab\r\n0\r\n\r\n", ErrInvalidChunk},
+		{"invalid trailer", "0\r\nBad Name: x\r\n\r\n", ErrInvalidChunk},
+		{"missing last chunk", "2\r\nab\r\n", io.ErrUnexpectedEOF},
+		{"missing end of trailers", "0\r\nX-Checksum: 42\r\n", io.ErrUnexpectedEOF},
+		{"short chunk", "a\r\nabc", io.ErrUnexpectedEOF},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := io.ReadAll(NewChunkedReader(bufio.NewReader(strings.NewReader(tc.raw)), headers.NewHeaders()))
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestChunkedWriter(t *testing.T) {
+	// Test: 쓴 데이터를 ChunkedReader로 다시 풀면 같은 body (빈 Write는 chunk를 만들지 않는다)
+	buf := &strings.Builder{}
+	w := NewChunkedWriter(buf)
+	for _, part := range []string{"hello", "", ", world"} {
+		n, err := w.Write([]byte(part))
+		require.NoError(t, err)
+		assert.Equal(t, len(part), n)
+	}
+	require.NoError(t, w.Close())
+	assert.Equal(t, "5\r\nhello\r\n7\r\n, world\r\n0\r\n\r\n", buf.String())
+
+	body, err := io.ReadAll(NewChunkedReader(bufio.NewReader(strings.NewReader(buf.String())), headers.NewHeaders()))
+	require.NoError(t, err)
+	assert.Equal(t, "hello, world", string(body))
+}
@@ -0,0 +1,125 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+)
+
+// wss:// 연결에 쓸 TLS 설정을 지정하는 옵션 (Dial에서만 쓰인다)
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(c *config) {
+		c.tlsConfig = tlsConfig
+	}
+}
+
+// ws:// 또는 wss:// URL로 연결해서 opening handshake를 하고 client 쪽 Conn을 반환하는 함수
+// ctx의 deadline은 TCP 연결과 handshake에만 적용된다
+func Dial(ctx context.Context, rawURL string, opts ...Option) (*Conn, error) {
+	cfg := newConfig(opts)
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	address := u.Host
+	var conn net.Conn
+	switch u.Scheme {
+	case "ws":
+		if u.Port() == "" {
+			address = net.JoinHostPort(u.Hostname(), "80")
+		}
+		dialer := &net.Dialer{}
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	case "wss":
+		if u.Port() == "" {
+			address = net.JoinHostPort(u.Hostname(), "443")
+		}
+		dialer := &tls.Dialer{Config: cfg.tlsConfig}
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	default:
+		return nil, fmt.Errorf("websocket: unsupported URL scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := clientHandshake(ctx, conn, u, cfg)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// conn으로 upgrade request를 보내고 101 response를 검증하는 함수
+func clientHandshake(ctx context.Context, conn net.Conn, u *url.URL, cfg *config) (*Conn, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	nonce := make([]byte, 16)
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	var b strings.Builder
+	b.WriteString("GET " + u.RequestURI() + " HTTP/1.1\r\n")
+	b.WriteString("Host: " + u.Host + "\r\n")
+	b.WriteString("Upgrade: websocket\r\n")
+	b.WriteString("Connection: Upgrade\r\n")
+	b.WriteString("Sec-WebSocket-Key: " + key + "\r\n")
+	b.WriteString("Sec-WebSocket-Version: " + protocolVersion + "\r\n")
+	if len(cfg.subprotocols) > 0 {
+		b.WriteString("Sec-WebSocket-Protocol: " + strings.Join(cfg.subprotocols, ", ") + "\r\n")
+	}
+	b.WriteString("\r\n")
+
+	_, err = conn.Write([]byte(b.String()))
+	if err != nil {
+		return nil, err
+	}
+
+	// @@@ 101 뒤에 server가 바로 보낸 frame이 br에 남아있을 수 있으므로 Conn도 같은 br로 읽는다
+	// @@@ 101은 body가 없어서 ResponseFromReader가 헤더 뒤의 바이트를 읽지 않는다
+	br := bufio.NewReader(conn)
+	resp, err := response.ResponseFromReader(br)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusLine.StatusCode != response.StatusSwitchingProtocols {
+		return nil, fmt.Errorf("%w: unexpected status %d %s", ErrBadHandshake, resp.StatusLine.StatusCode, resp.StatusLine.ReasonPhrase)
+	}
+	if !headers.HasToken(resp.Headers.Get("upgrade"), "websocket") || !headers.HasToken(resp.Headers.Get("connection"), "upgrade") {
+		return nil, fmt.Errorf("%w: missing upgrade headers", ErrBadHandshake)
+	}
+	if resp.Headers.Get("sec-websocket-accept") != AcceptKey(key) {
+		return nil, fmt.Errorf("%w: Sec-WebSocket-Accept mismatch", ErrBadHandshake)
+	}
+
+	subprotocol := resp.Headers.Get("sec-websocket-protocol")
+	if subprotocol != "" && selectSubprotocol(subprotocol, cfg.subprotocols) != subprotocol {
+		return nil, fmt.Errorf("%w: server selected unrequested subprotocol %q", ErrBadHandshake, subprotocol)
+	}
+
+	c := newConn(conn, br, false, cfg)
+	c.subprotocol = subprotocol
+
+	return c, nil
+}
@@ -0,0 +1,450 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 기본 최대 메시지 크기 (1MiB)
+const DefaultMaxMessageSize = 1 << 20
+
+// 보낼 메시지를 나누는 기본 frame 크기 (64KiB)
+const DefaultFragmentSize = 64 << 10
+
+// Close가 상대의 close frame을 기다리는 최대 시간
+const closeTimeout = 5 * time.Second
+
+// close frame의 status code (RFC 6455 7.4.1)
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	// 1005, 1006은 frame으로 보내지 않고 status code가 없었거나 비정상 종료였음을 알리는 데만 쓴다
+	CloseNoStatus        = 1005
+	CloseAbnormal        = 1006
+	CloseInvalidPayload  = 1007
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+	CloseInternalError   = 1011
+)
+
+var ErrProtocol = errors.New("websocket: protocol error")
+var ErrMessageTooLarge = errors.New("websocket: message too large")
+var ErrInvalidUTF8 = errors.New("websocket: invalid UTF-8 in text message")
+var ErrCloseSent = errors.New("websocket: close frame already sent")
+var ErrInvalidOpcode = errors.New("websocket: invalid opcode for this operation")
+var ErrControlTooLong = errors.New("websocket: control frame payload exceeds 125 bytes")
+
+// 상대가 보낸 close frame을 받았을 때 ReadMessage가 반환하는 에러
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Reason)
+}
+
+// 규칙을 어긴 frame을 받았을 때의 에러를 만드는 함수
+func protocolError(msg string) error {
+	return fmt.Errorf("%w: %s", ErrProtocol, msg)
+}
+
+// handshake가 끝난 WebSocket 연결
+// ReadMessage는 한 고루틴에서만 호출해야 하고, 쓰기 메소드들은 여러 고루틴에서 동시에 호출해도 된다
+type Conn struct {
+	conn     net.Conn
+	br       *bufio.Reader
+	isServer bool
+
+	maxMessageSize int64
+	fragmentSize   int
+	subprotocol    string
+
+	// 읽기 쪽 상태 (ReadMessage를 호출하는 고루틴만 사용)
+	readErr       error
+	closeReceived bool
+	pongHandler   func(data []byte)
+
+	// 쓰기 쪽 상태 (frame 단위가 아니라 메시지 단위로 잠가서 다른 메시지의 조각과 섞이지 않게 한다)
+	writeMu   sync.Mutex
+	closeSent bool
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, isServer bool, cfg *config) *Conn {
+	return &Conn{
+		conn:           conn,
+		br:             br,
+		isServer:       isServer,
+		maxMessageSize: cfg.maxMessageSize,
+		fragmentSize:   cfg.fragmentSize,
+	}
+}
+
+// handshake에서 정해진 subprotocol을 반환하는 메소드 (없으면 "")
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// 상대의 주소를 반환하는 메소드
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// 내부 연결의 읽기 deadline을 설정하는 메소드 (지나면 ReadMessage가 에러를 반환한다)
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// 내부 연결의 쓰기 deadline을 설정하는 메소드
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// pong frame을 받을 때마다 호출할 함수를 설정하는 메소드 (ReadMessage를 호출하는 고루틴에서 불린다)
+// @@@ ping은 ReadMessage가 자동으로 pong으로 답하므로 따로 설정할 필요가 없다
+func (c *Conn) SetPongHandler(h func(data []byte)) {
+	c.pongHandler = h
+}
+
+// 다음 data 메시지(OpText 또는 OpBinary)를 읽어 반환하는 메소드
+// 조각난 메시지는 다 합쳐서 반환하고, 사이에 끼어든 control frame은 여기서 처리한다
+// (ping에는 pong으로 답하고, close를 받으면 같은 code로 답한 뒤 *CloseError를 반환한다)
+// 규칙 위반, 최대 크기 초과, 잘못된 UTF-8이면 알맞은 code로 close frame을 보내고 에러를 반환하며
+// 한번 에러를 반환한 뒤에는 계속 같은 에러를 반환한다
+func (c *Conn) ReadMessage() (Opcode, []byte, error) {
+	if c.readErr != nil {
+		return 0, nil, c.readErr
+	}
+
+	op, data, err := c.readMessage()
+	if err != nil {
+		c.readErr = err
+		c.fail(err)
+		return 0, nil, err
+	}
+
+	return op, data, nil
+}
+
+// frame들을 읽어 메시지 하나를 만드는 메소드
+func (c *Conn) readMessage() (Opcode, []byte, error) {
+	var msgType Opcode
+	message := []byte{}
+
+	for {
+		fh, err := readFrameHeader(c.br)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		err = c.checkFrame(fh, msgType != 0)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if fh.opcode.isControl() {
+			payload := make([]byte, fh.length)
+			err = c.readPayload(fh, payload)
+			if err != nil {
+				return 0, nil, err
+			}
+
+			err = c.handleControl(fh.opcode, payload)
+			if err != nil {
+				return 0, nil, err
+			}
+			continue
+		}
+
+		// @@@ payload를 읽기 전에 길이로 먼저 확인해서 큰 길이를 보낸 것만으로 메모리를 잡지 못하게 한다
+		if int64(len(message))+fh.length > c.maxMessageSize {
+			return 0, nil, ErrMessageTooLarge
+		}
+
+		start := len(message)
+		message = append(message, make([]byte, fh.length)...)
+		err = c.readPayload(fh, message[start:])
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if fh.opcode != OpContinuation {
+			msgType = fh.opcode
+		}
+
+		if !fh.fin {
+			continue
+		}
+
+		if msgType == OpText && !utf8.Valid(message) {
+			return 0, nil, ErrInvalidUTF8
+		}
+
+		return msgType, message, nil
+	}
+}
+
+// frame header가 RFC 6455의 규칙을 지키는지 확인하는 메소드
+// inMessage는 조각난 메시지를 읽는 중인지 여부
+func (c *Conn) checkFrame(fh frameHeader, inMessage bool) error {
+	// @@@ 확장(permessage-deflate 등)을 협상하지 않으므로 RSV 비트는 모두 0이어야 한다
+	if fh.rsv != 0 {
+		return protocolError("reserved bits set")
+	}
+
+	// client가 보내는 frame은 반드시 mask, server가 보내는 frame은 mask하면 안 된다 (RFC 6455 5.1)
+	if c.isServer && !fh.masked {
+		return protocolError("client frame is not masked")
+	}
+	if !c.isServer && fh.masked {
+		return protocolError("server frame is masked")
+	}
+
+	switch {
+	case fh.opcode == OpClose || fh.opcode == OpPing || fh.opcode == OpPong:
+		if !fh.fin {
+			return protocolError("fragmented control frame")
+		}
+		if fh.length > maxControlPayload {
+			return protocolError("control frame too long")
+		}
+	case fh.opcode == OpContinuation:
+		if !inMessage {
+			return protocolError("continuation frame without a message")
+		}
+	case fh.opcode.isData():
+		if inMessage {
+			return protocolError("new message before the previous one finished")
+		}
+	default:
+		return protocolError(fmt.Sprintf("unknown opcode %d", fh.opcode))
+	}
+
+	return nil
+}
+
+// frame의 payload를 dst에 읽고 mask를 푸는 메소드
+func (c *Conn) readPayload(fh frameHeader, dst []byte) error {
+	_, err := io.ReadFull(c.br, dst)
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+
+	if fh.masked {
+		maskBytes(fh.key, dst)
+	}
+
+	return nil
+}
+
+// control frame을 처리하는 메소드
+func (c *Conn) handleControl(op Opcode, payload []byte) error {
+	switch op {
+	case OpPing:
+		err := c.writeControl(OpPong, payload)
+		// @@@ 이미 close를 보냈으면 pong은 보내지 않아도 된다
+		if err != nil && !errors.Is(err, ErrCloseSent) {
+			return err
+		}
+		return nil
+	case OpPong:
+		if c.pongHandler != nil {
+			c.pongHandler(payload)
+		}
+		return nil
+	}
+
+	// OpClose
+	code := CloseNoStatus
+	reason := ""
+	switch {
+	case len(payload) == 1:
+		return protocolError("close frame with 1-byte payload")
+	case len(payload) >= 2:
+		code = int(binary.BigEndian.Uint16(payload))
+		reason = string(payload[2:])
+		if !validCloseCode(code) {
+			return protocolError(fmt.Sprintf("invalid close code %d", code))
+		}
+		if !utf8.ValidString(reason) {
+			return ErrInvalidUTF8
+		}
+	}
+
+	c.closeReceived = true
+
+	// 받은 code를 그대로 돌려보내서 close handshake를 마친다 (code가 없었으면 빈 close frame)
+	echo := []byte{}
+	if code != CloseNoStatus {
+		echo = payload[:2]
+	}
+	// @@@ 상대가 close를 보내고 바로 연결을 닫았을 수도 있으므로 답장 쓰기 에러는 무시한다
+	_ = c.writeControl(OpClose, echo)
+
+	return &CloseError{Code: code, Reason: reason}
+}
+
+// 읽기 에러의 종류에 맞는 code로 close frame을 보내는 메소드 (연결 자체의 에러면 보내지 않는다)
+func (c *Conn) fail(err error) {
+	code := 0
+	switch {
+	case errors.Is(err, ErrProtocol):
+		code = CloseProtocolError
+	case errors.Is(err, ErrMessageTooLarge):
+		code = CloseMessageTooBig
+	case errors.Is(err, ErrInvalidUTF8):
+		code = CloseInvalidPayload
+	}
+
+	if code != 0 {
+		_ = c.WriteClose(code, "")
+	}
+}
+
+// frame으로 보낼 수 있는 close code인지 확인하는 함수 (RFC 6455 7.4)
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003:
+		return true
+	case code >= 1007 && code <= 1011:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+// data 메시지를 보내는 메소드 (op는 OpText 또는 OpBinary)
+// fragment size보다 긴 메시지는 여러 frame으로 나눠 보낸다
+func (c *Conn) WriteMessage(op Opcode, data []byte) error {
+	if !op.isData() {
+		return ErrInvalidOpcode
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closeSent {
+		return ErrCloseSent
+	}
+
+	frameOp := op
+	for {
+		n := len(data)
+		if c.fragmentSize > 0 && n > c.fragmentSize {
+			n = c.fragmentSize
+		}
+		fin := n == len(data)
+
+		err := c.writeFrame(fin, frameOp, data[:n])
+		if err != nil {
+			return err
+		}
+		if fin {
+			return nil
+		}
+
+		data = data[n:]
+		frameOp = OpContinuation
+	}
+}
+
+// ping frame을 보내는 메소드 (data는 125바이트 이하)
+func (c *Conn) Ping(data []byte) error {
+	return c.writeControl(OpPing, data)
+}
+
+// close frame을 보내서 close handshake를 시작하는 메소드
+// 이후 쓰기 메소드는 ErrCloseSent를 반환하고, 상대가 답한 close는 ReadMessage가 *CloseError로 반환한다
+func (c *Conn) WriteClose(code int, reason string) error {
+	if !validCloseCode(code) {
+		return fmt.Errorf("websocket: invalid close code %d", code)
+	}
+
+	payload := make([]byte, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	copy(payload[2:], reason)
+
+	return c.writeControl(OpClose, payload)
+}
+
+// close handshake를 마치고 연결을 닫는 메소드
+// 아직 close를 보내지 않았으면 1000으로 보내고, 상대의 close를 아직 못 받았으면 잠시 기다린다
+// @@@ 다른 고루틴이 ReadMessage를 호출 중이면 Close 대신 WriteClose를 호출하고,
+// @@@ ReadMessage가 *CloseError를 반환한 뒤 그 고루틴에서 Close를 호출해야 한다
+func (c *Conn) Close() error {
+	err := c.WriteClose(CloseNormal, "")
+	writeOK := err == nil || errors.Is(err, ErrCloseSent)
+
+	if writeOK && c.readErr == nil && !c.closeReceived {
+		_ = c.conn.SetReadDeadline(time.Now().Add(closeTimeout))
+		for c.readErr == nil {
+			_, _, _ = c.ReadMessage()
+		}
+	}
+
+	return c.conn.Close()
+}
+
+// control frame을 보내는 메소드
+func (c *Conn) writeControl(op Opcode, payload []byte) error {
+	if len(payload) > maxControlPayload {
+		return ErrControlTooLong
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closeSent {
+		return ErrCloseSent
+	}
+
+	err := c.writeFrame(true, op, payload)
+	if op == OpClose {
+		c.closeSent = true
+	}
+
+	return err
+}
+
+// frame 하나를 연결에 쓰는 메소드 (writeMu를 잡은 상태에서 호출)
+// client는 frame마다 새 masking key를 만들어 payload를 mask한다
+func (c *Conn) writeFrame(fin bool, op Opcode, payload []byte) error {
+	fh := frameHeader{fin: fin, opcode: op, length: int64(len(payload))}
+
+	var header [maxHeaderSize]byte
+
+	if c.isServer {
+		n := encodeFrameHeader(header[:], fh)
+		// @@@ payload를 복사하지 않도록 header와 payload를 net.Buffers로 한번에 보낸다 (writev)
+		buffers := net.Buffers{header[:n], payload}
+		_, err := buffers.WriteTo(c.conn)
+		return err
+	}
+
+	fh.masked = true
+	_, err := rand.Read(fh.key[:])
+	if err != nil {
+		return err
+	}
+
+	n := encodeFrameHeader(header[:], fh)
+	// @@@ 호출한 쪽의 payload를 바꾸지 않도록 복사본을 mask
+	frame := make([]byte, n+len(payload))
+	copy(frame, header[:n])
+	copy(frame[n:], payload)
+	maskBytes(fh.key, frame[n:])
+
+	_, err = c.conn.Write(frame)
+	return err
+}
@@ -0,0 +1,150 @@
+package websocket
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// frame의 종류 (RFC 6455 5.2)
+type Opcode byte
+
+const (
+	OpContinuation Opcode = 0x0
+	OpText         Opcode = 0x1
+	OpBinary       Opcode = 0x2
+	OpClose        Opcode = 0x8
+	OpPing         Opcode = 0x9
+	OpPong         Opcode = 0xA
+)
+
+// control frame(close, ping, pong)의 payload 최대 길이
+const maxControlPayload = 125
+
+// 최대 frame header 길이: 2 + 확장 길이 8 + masking key 4
+const maxHeaderSize = 14
+
+const (
+	finBit  = 0x80
+	rsvBits = 0x70
+	opMask  = 0x0F
+	maskBit = 0x80
+	lenMask = 0x7F
+)
+
+// close, ping, pong인지 확인하는 메소드
+func (op Opcode) isControl() bool {
+	return op&0x8 != 0
+}
+
+// 메시지를 시작할 수 있는 data frame인지 확인하는 메소드
+func (op Opcode) isData() bool {
+	return op == OpText || op == OpBinary
+}
+
+// 파싱한 frame header
+type frameHeader struct {
+	fin    bool
+	rsv    byte
+	opcode Opcode
+	masked bool
+	key    [4]byte
+	length int64
+}
+
+// r에서 frame header 하나를 읽는 함수
+// 확장 길이는 최소 바이트로 인코딩되어 있어야 하고 64비트 길이의 최상위 비트는 0이어야 한다
+func readFrameHeader(r io.Reader) (frameHeader, error) {
+	var fh frameHeader
+	var buf [8]byte
+
+	_, err := io.ReadFull(r, buf[:2])
+	if err != nil {
+		return fh, err
+	}
+
+	fh.fin = buf[0]&finBit != 0
+	fh.rsv = buf[0] & rsvBits
+	fh.opcode = Opcode(buf[0] & opMask)
+	fh.masked = buf[1]&maskBit != 0
+	fh.length = int64(buf[1] & lenMask)
+
+	switch fh.length {
+	case 126:
+		_, err = io.ReadFull(r, buf[:2])
+		if err != nil {
+			return fh, unexpectedEOF(err)
+		}
+		fh.length = int64(binary.BigEndian.Uint16(buf[:2]))
+		if fh.length < 126 {
+			return fh, protocolError("non-minimal payload length")
+		}
+	case 127:
+		_, err = io.ReadFull(r, buf[:8])
+		if err != nil {
+			return fh, unexpectedEOF(err)
+		}
+		length := binary.BigEndian.Uint64(buf[:8])
+		if length>>63 != 0 || length <= 0xFFFF {
+			return fh, protocolError("invalid 64-bit payload length")
+		}
+		fh.length = int64(length)
+	}
+
+	if fh.masked {
+		_, err = io.ReadFull(r, fh.key[:])
+		if err != nil {
+			return fh, unexpectedEOF(err)
+		}
+	}
+
+	return fh, nil
+}
+
+// frame header를 dst 앞쪽에 인코딩하고 사용한 바이트 수를 반환하는 함수 (dst는 maxHeaderSize 이상)
+func encodeFrameHeader(dst []byte, fh frameHeader) int {
+	b0 := byte(fh.opcode) | fh.rsv
+	if fh.fin {
+		b0 |= finBit
+	}
+	dst[0] = b0
+
+	var b1 byte
+	if fh.masked {
+		b1 = maskBit
+	}
+
+	n := 2
+	switch {
+	case fh.length <= maxControlPayload:
+		dst[1] = b1 | byte(fh.length)
+	case fh.length <= 0xFFFF:
+		dst[1] = b1 | 126
+		binary.BigEndian.PutUint16(dst[2:], uint16(fh.length))
+		n += 2
+	default:
+		dst[1] = b1 | 127
+		binary.BigEndian.PutUint64(dst[2:], uint64(fh.length))
+		n += 8
+	}
+
+	if fh.masked {
+		n += copy(dst[n:], fh.key[:])
+	}
+
+	return n
+}
+
+// masking key로 payload를 XOR하는 함수 (같은 key로 한번 더 하면 원래 값)
+func maskBytes(key [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= key[i&3]
+	}
+}
+
+// 헤더를 읽던 중 연결이 끊기면 io.EOF 대신 io.ErrUnexpectedEOF로 바꾸는 함수
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
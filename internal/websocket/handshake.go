@@ -0,0 +1,222 @@
+package websocket
+
+import (
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+)
+
+// Sec-WebSocket-Accept를 만들 때 key 뒤에 붙이는 고정 GUID (RFC 6455 1.3)
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// 지원하는 프로토콜 버전 (RFC 6455는 13만 정의)
+const protocolVersion = "13"
+
+var ErrBadHandshake = errors.New("websocket: bad handshake")
+var ErrBadOrigin = errors.New("websocket: request origin not allowed")
+var ErrUnsupportedVersion = errors.New("websocket: unsupported protocol version")
+
+// 연결 설정을 바꾸는 옵션 함수 타입 (Upgrade, Dial 공용)
+type Option func(*config)
+
+type config struct {
+	maxMessageSize int64
+	fragmentSize   int
+	subprotocols   []string
+	checkOrigin    func(req *request.Request) bool
+	tlsConfig      *tls.Config
+}
+
+func newConfig(opts []Option) *config {
+	cfg := &config{
+		maxMessageSize: DefaultMaxMessageSize,
+		fragmentSize:   DefaultFragmentSize,
+		checkOrigin:    sameOrigin,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// 받을 수 있는 메시지 하나의 최대 크기(조각들을 합친 크기)를 정하는 옵션
+// 넘으면 1009 Message Too Big으로 연결을 닫는다
+func WithMaxMessageSize(n int64) Option {
+	return func(c *config) {
+		c.maxMessageSize = n
+	}
+}
+
+// 보낼 메시지를 이 크기 단위의 frame들로 나눠 보내도록 하는 옵션 (0 이하면 나누지 않는다)
+func WithFragmentSize(n int) Option {
+	return func(c *config) {
+		c.fragmentSize = n
+	}
+}
+
+// 지원하는 subprotocol들을 선호 순서대로 지정하는 옵션
+// server는 client가 보낸 Sec-WebSocket-Protocol 중 이 목록에서 처음 맞는 것을 고르고,
+// client(Dial)는 이 목록을 Sec-WebSocket-Protocol로 보낸다
+func WithSubprotocols(protocols ...string) Option {
+	return func(c *config) {
+		c.subprotocols = protocols
+	}
+}
+
+// Origin 헤더 검사 함수를 바꾸는 옵션 (false를 반환하면 403 Forbidden)
+// 기본값은 Origin이 없거나 Origin의 host가 Host 헤더와 같을 때만 허용
+// @@@ 브라우저는 다른 사이트의 페이지에서도 쿠키를 붙여 WebSocket 연결을 열 수 있으므로 기본은 same-origin
+func WithCheckOrigin(check func(req *request.Request) bool) Option {
+	return func(c *config) {
+		c.checkOrigin = check
+	}
+}
+
+// Sec-WebSocket-Key에 대응하는 Sec-WebSocket-Accept 값을 계산하는 함수
+// base64(SHA-1(key + GUID))
+func AcceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// request가 WebSocket upgrade 요청인지 확인하는 함수 (Upgrade: websocket, Connection: upgrade)
+func IsUpgradeRequest(req *request.Request) bool {
+	return headers.HasToken(req.Headers.Get("connection"), "upgrade") &&
+		headers.HasToken(req.Headers.Get("upgrade"), "websocket")
+}
+
+// opening handshake를 검증하고 101 Switching Protocols를 보낸 뒤 연결을 넘겨받아 Conn을 만드는 함수
+// 검증에 실패하면 알맞은 에러 response(400, 403, 405, 426)를 w에 작성하고 에러를 반환하므로
+// 호출한 handler는 에러일 때 그냥 반환하면 된다
+// 성공하면 연결은 server의 관리에서 빠지므로 Conn을 다 쓴 뒤 Close를 호출해야 한다
+func Upgrade(w *response.Writer, req *request.Request, opts ...Option) (*Conn, error) {
+	cfg := newConfig(opts)
+
+	if req.RequestLine.Method != "GET" {
+		h := headers.NewHeaders()
+		h.SetOverride("Allow", "GET")
+		writeStatus(w, response.StatusMethodNotAllowed, h)
+		return nil, ErrBadHandshake
+	}
+
+	if !IsUpgradeRequest(req) {
+		// @@@ upgrade가 필요하다고 알려주는 426 (RFC 9110 15.5.22)
+		h := headers.NewHeaders()
+		h.SetOverride("Upgrade", "websocket")
+		h.SetOverride("Connection", "Upgrade")
+		writeStatus(w, response.StatusUpgradeRequired, h)
+		return nil, ErrBadHandshake
+	}
+
+	if req.Headers.Get("sec-websocket-version") != protocolVersion {
+		// @@@ 지원하는 버전을 Sec-WebSocket-Version으로 알려준다 (RFC 6455 4.4)
+		h := headers.NewHeaders()
+		h.SetOverride("Sec-WebSocket-Version", protocolVersion)
+		writeStatus(w, response.StatusUpgradeRequired, h)
+		return nil, ErrUnsupportedVersion
+	}
+
+	key := strings.TrimSpace(req.Headers.Get("sec-websocket-key"))
+	if !validKey(key) || req.Headers.Get("host") == "" {
+		writeStatus(w, response.StatusBadRequest, headers.NewHeaders())
+		return nil, ErrBadHandshake
+	}
+
+	if cfg.checkOrigin != nil && !cfg.checkOrigin(req) {
+		writeStatus(w, response.StatusForbidden, headers.NewHeaders())
+		return nil, ErrBadOrigin
+	}
+
+	h := headers.NewHeaders()
+	h.SetOverride("Upgrade", "websocket")
+	h.SetOverride("Connection", "Upgrade")
+	h.SetOverride("Sec-WebSocket-Accept", AcceptKey(key))
+	subprotocol := selectSubprotocol(req.Headers.Get("sec-websocket-protocol"), cfg.subprotocols)
+	if subprotocol != "" {
+		h.SetOverride("Sec-WebSocket-Protocol", subprotocol)
+	}
+
+	err := w.WriteStatusLine(response.StatusSwitchingProtocols)
+	if err != nil {
+		return nil, err
+	}
+	err = w.WriteHeaders(h)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, reader, err := w.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	c := newConn(conn, reader, true, cfg)
+	c.subprotocol = subprotocol
+
+	return c, nil
+}
+
+// Sec-WebSocket-Key가 16바이트 값을 base64로 인코딩한 것인지 확인하는 함수
+func validKey(key string) bool {
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	return err == nil && len(decoded) == 16
+}
+
+// client가 보낸 subprotocol 목록에서 server가 지원하는 것을 server의 선호 순서대로 고르는 함수
+func selectSubprotocol(requested string, supported []string) string {
+	for _, s := range supported {
+		if headers.HasToken(requested, s) {
+			return s
+		}
+	}
+	return ""
+}
+
+// Origin이 없거나(브라우저가 아닌 client) Origin의 host가 Host 헤더와 같은지 확인하는 함수
+func sameOrigin(req *request.Request) bool {
+	origin := req.Headers.Get("origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Host, req.Headers.Get("host"))
+}
+
+// 주어진 헤더에 짧은 text/plain body를 붙여 response를 보내는 함수
+func writeStatus(w *response.Writer, statusCode response.StatusCode, h headers.Headers) {
+	body := strconv.Itoa(int(statusCode)) + " " + response.StatusText(statusCode) + "\n"
+
+	err := w.WriteStatusLine(statusCode)
+	if err != nil {
+		log.Printf("error writing status line: %v", err)
+		return
+	}
+
+	h.SetOverride("Content-Length", strconv.Itoa(len(body)))
+	h.SetOverride("Content-Type", "text/plain; charset=utf-8")
+
+	err = w.WriteHeaders(h)
+	if err != nil {
+		log.Printf("error writing headers: %v", err)
+		return
+	}
+
+	_, err = w.WriteBody([]byte(body))
+	if err != nil {
+		log.Printf("error writing body: %v", err)
+	}
+}
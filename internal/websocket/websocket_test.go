@@ -0,0 +1,362 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paokimsiwoong/httpfromtcp/internal/headers"
+	"github.com/paokimsiwoong/httpfromtcp/internal/request"
+	"github.com/paokimsiwoong/httpfromtcp/internal/response"
+	"github.com/paokimsiwoong/httpfromtcp/internal/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 받은 메시지를 그대로 돌려보내는 WebSocket server를 띄우고 ws:// URL을 반환하는 함수
+// server 쪽 ReadMessage가 마지막으로 반환한 에러는 done으로 보낸다
+func startEcho(t *testing.T, done chan<- error, opts ...Option) string {
+	t.Helper()
+
+	handler := func(w *response.Writer, req *request.Request) {
+		conn, err := Upgrade(w, req, opts...)
+		if err != nil {
+			return
+		}
+
+		go func() {
+			defer conn.Close()
+			for {
+				op, data, err := conn.ReadMessage()
+				if err != nil {
+					if done != nil {
+						done <- err
+					}
+					return
+				}
+				_ = conn.WriteMessage(op, data)
+			}
+		}()
+	}
+
+	s, err := server.ServeAddr("tcp", "127.0.0.1:0", handler)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+
+	return "ws://" + s.Addr().String() + "/echo"
+}
+
+func dial(t *testing.T, url string, opts ...Option) *Conn {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := Dial(ctx, url, opts...)
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	t.Cleanup(func() { _ = conn.conn.Close() })
+
+	return conn
+}
+
+func TestAcceptKey(t *testing.T) {
+	// RFC 6455 1.3의 예시
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", AcceptKey("dGhlIHNhbXBsZSBub25jZQ=="))
+}
+
+func TestUpgradeRejects(t *testing.T) {
+	valid := func() *request.Request {
+		h := headers.NewHeaders()
+		h["host"] = "example.com"
+		h["upgrade"] = "websocket"
+		h["connection"] = "keep-alive, Upgrade"
+		h["sec-websocket-version"] = "13"
+		h["sec-websocket-key"] = "dGhlIHNhbXBsZSBub25jZQ=="
+		return &request.Request{
+			RequestLine: request.RequestLine{Method: "GET", RequestTarget: "/ws", HttpVersion: "1.1"},
+			Headers:     h,
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(req *request.Request)
+		status string
+		header string
+		err    error
+	}{
+		{"POST", func(r *request.Request) { r.RequestLine.Method = "POST" }, "405", "Allow: GET", ErrBadHandshake},
+		{"no upgrade", func(r *request.Request) { delete(r.Headers, "upgrade") }, "426", "Upgrade: websocket", ErrBadHandshake},
+		{"connection without upgrade", func(r *request.Request) { r.Headers["connection"] = "keep-alive" }, "426", "", ErrBadHandshake},
+		{"old version", func(r *request.Request) { r.Headers["sec-websocket-version"] = "8" }, "426", "Sec-WebSocket-Version: 13", ErrUnsupportedVersion},
+		{"short key", func(r *request.Request) { r.Headers["sec-websocket-key"] = "c2hvcnQ=" }, "400", "", ErrBadHandshake},
+		{"missing host", func(r *request.Request) { delete(r.Headers, "host") }, "400", "", ErrBadHandshake},
+		{"cross origin", func(r *request.Request) { r.Headers["origin"] = "https://evil.example" }, "403", "", ErrBadOrigin},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := valid()
+			tc.modify(req)
+
+			buf := &bytes.Buffer{}
+			w := response.NewWriter(buf)
+			conn, err := Upgrade(w, req)
+			require.ErrorIs(t, err, tc.err)
+			assert.Nil(t, conn)
+			require.NoError(t, w.Flush())
+
+			assert.True(t, strings.HasPrefix(buf.String(), "HTTP/1.1 "+tc.status+" "), buf.String())
+			assert.Contains(t, buf.String(), tc.header)
+		})
+	}
+
+	// Test: 같은 origin이거나 WithCheckOrigin으로 허용하면 Hijack 단계까지 간다 (연결이 없으므로 ErrNotHijackable)
+	req := valid()
+	req.Headers["origin"] = "http://example.com"
+	_, err := Upgrade(response.NewWriter(&bytes.Buffer{}), req)
+	assert.ErrorIs(t, err, response.ErrNotHijackable)
+
+	req = valid()
+	req.Headers["origin"] = "https://evil.example"
+	_, err = Upgrade(response.NewWriter(&bytes.Buffer{}), req, WithCheckOrigin(func(*request.Request) bool { return true }))
+	assert.ErrorIs(t, err, response.ErrNotHijackable)
+}
+
+func TestEcho(t *testing.T) {
+	url := startEcho(t, nil)
+
+	// @@@ client 쪽 fragment를 작게 해서 조각난 메시지를 server가 합치는지 확인
+	conn := dial(t, url, WithFragmentSize(7))
+
+	messages := []struct {
+		op   Opcode
+		data []byte
+	}{
+		{OpText, []byte("hello, websocket")},
+		{OpBinary, []byte{0x00, 0xFF, 0x10}},
+		{OpText, []byte{}},
+		// 16비트 길이
+		{OpBinary, bytes.Repeat([]byte("a"), 300)},
+		// 64비트 길이
+		{OpText, bytes.Repeat([]byte("b"), 70000)},
+	}
+
+	for _, m := range messages {
+		require.NoError(t, conn.WriteMessage(m.op, m.data))
+
+		op, data, err := conn.ReadMessage()
+		require.NoError(t, err)
+		assert.Equal(t, m.op, op)
+		assert.Equal(t, m.data, data)
+	}
+
+	assert.ErrorIs(t, conn.WriteMessage(OpPing, nil), ErrInvalidOpcode)
+}
+
+func TestPingPong(t *testing.T) {
+	url := startEcho(t, nil)
+	conn := dial(t, url)
+
+	pongs := []string{}
+	conn.SetPongHandler(func(data []byte) {
+		pongs = append(pongs, string(data))
+	})
+
+	// Test: server는 ping에 같은 payload의 pong으로 답하고, 그 사이의 data 메시지도 잃지 않는다
+	require.NoError(t, conn.Ping([]byte("are you there")))
+	require.NoError(t, conn.WriteMessage(OpText, []byte("after ping")))
+
+	op, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, OpText, op)
+	assert.Equal(t, "after ping", string(data))
+	assert.Equal(t, []string{"are you there"}, pongs)
+
+	assert.ErrorIs(t, conn.Ping(bytes.Repeat([]byte("x"), 126)), ErrControlTooLong)
+}
+
+func TestCloseHandshake(t *testing.T) {
+	done := make(chan error, 1)
+	url := startEcho(t, done)
+	conn := dial(t, url)
+
+	require.NoError(t, conn.WriteClose(CloseGoingAway, "bye"))
+	assert.ErrorIs(t, conn.WriteMessage(OpText, []byte("too late")), ErrCloseSent)
+
+	// Test: server는 close를 *CloseError로 받고
+	var serverErr *CloseError
+	require.ErrorAs(t, <-done, &serverErr)
+	assert.Equal(t, CloseGoingAway, serverErr.Code)
+	assert.Equal(t, "bye", serverErr.Reason)
+
+	// Test: 같은 code로 답한다
+	_, _, err := conn.ReadMessage()
+	var clientErr *CloseError
+	require.ErrorAs(t, err, &clientErr)
+	assert.Equal(t, CloseGoingAway, clientErr.Code)
+
+	assert.NoError(t, conn.Close())
+}
+
+func TestServerClose(t *testing.T) {
+	handler := func(w *response.Writer, req *request.Request) {
+		conn, err := Upgrade(w, req)
+		if err != nil {
+			return
+		}
+		go func() {
+			_ = conn.WriteMessage(OpText, []byte("goodbye"))
+			_ = conn.Close()
+		}()
+	}
+	s, err := server.ServeAddr("tcp", "127.0.0.1:0", handler)
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn := dial(t, "ws://"+s.Addr().String()+"/")
+
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "goodbye", string(data))
+
+	// Test: server의 Close는 1000 close를 보내고 client의 답을 기다린다
+	_, _, err = conn.ReadMessage()
+	var closeErr *CloseError
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, CloseNormal, closeErr.Code)
+}
+
+func TestMessageTooLarge(t *testing.T) {
+	done := make(chan error, 1)
+	url := startEcho(t, done, WithMaxMessageSize(10))
+	conn := dial(t, url, WithFragmentSize(4))
+
+	require.NoError(t, conn.WriteMessage(OpText, []byte("0123456789")))
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789", string(data))
+
+	// Test: 조각들을 합친 크기가 최대를 넘으면 1009로 닫는다
+	require.NoError(t, conn.WriteMessage(OpText, []byte("0123456789a")))
+	assert.ErrorIs(t, <-done, ErrMessageTooLarge)
+
+	_, _, err = conn.ReadMessage()
+	var closeErr *CloseError
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, CloseMessageTooBig, closeErr.Code)
+}
+
+// client Conn의 연결에 frame을 그대로 쓰는 함수 (규칙을 어긴 frame을 보내기 위해 사용)
+func writeRaw(t *testing.T, conn *Conn, frames ...[]byte) {
+	t.Helper()
+	for _, f := range frames {
+		_, err := conn.conn.Write(f)
+		require.NoError(t, err)
+	}
+}
+
+// masking key 0으로 mask된 client frame을 만드는 함수 (key가 0이면 payload는 그대로)
+func maskedFrame(b0 byte, payload []byte) []byte {
+	f := []byte{b0, 0x80 | byte(len(payload)), 0, 0, 0, 0}
+	return append(f, payload...)
+}
+
+func TestProtocolErrors(t *testing.T) {
+	closePayload := make([]byte, 2)
+	binary.BigEndian.PutUint16(closePayload, 999)
+
+	tests := []struct {
+		name   string
+		frames [][]byte
+		err    error
+		code   int
+	}{
+		{"unmasked", [][]byte{{0x81, 0x02, 'h', 'i'}}, ErrProtocol, CloseProtocolError},
+		{"reserved bits", [][]byte{maskedFrame(0xC1, []byte("hi"))}, ErrProtocol, CloseProtocolError},
+		{"unknown opcode", [][]byte{maskedFrame(0x83, nil)}, ErrProtocol, CloseProtocolError},
+		{"continuation without start", [][]byte{maskedFrame(0x80, []byte("x"))}, ErrProtocol, CloseProtocolError},
+		{"new message while fragmented", [][]byte{maskedFrame(0x01, []byte("a")), maskedFrame(0x81, []byte("b"))}, ErrProtocol, CloseProtocolError},
+		{"fragmented ping", [][]byte{maskedFrame(0x09, nil)}, ErrProtocol, CloseProtocolError},
+		{"long ping", [][]byte{{0x89, 0x80 | 126, 0, 126, 0, 0, 0, 0}}, ErrProtocol, CloseProtocolError},
+		{"invalid close code", [][]byte{maskedFrame(0x88, closePayload)}, ErrProtocol, CloseProtocolError},
+		{"invalid utf-8", [][]byte{maskedFrame(0x81, []byte{0xFF, 0xFE})}, ErrInvalidUTF8, CloseInvalidPayload},
+		{"utf-8 split across fragments", [][]byte{maskedFrame(0x01, []byte{0xE2, 0x82}), maskedFrame(0x80, []byte{0xAC})}, nil, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			done := make(chan error, 1)
+			url := startEcho(t, done)
+			conn := dial(t, url)
+
+			writeRaw(t, conn, tc.frames...)
+
+			op, data, err := conn.ReadMessage()
+			if tc.err == nil {
+				// Test: 조각 경계에서 잘린 UTF-8은 합친 뒤에 검사한다
+				require.NoError(t, err)
+				assert.Equal(t, OpText, op)
+				assert.Equal(t, "€", string(data))
+				return
+			}
+
+			var closeErr *CloseError
+			require.ErrorAs(t, err, &closeErr)
+			assert.Equal(t, tc.code, closeErr.Code)
+			assert.True(t, errors.Is(<-done, tc.err))
+		})
+	}
+}
+
+func TestSubprotocol(t *testing.T) {
+	url := startEcho(t, nil, WithSubprotocols("v2.chat", "v1.chat"))
+
+	conn := dial(t, url, WithSubprotocols("v1.chat", "v2.chat"))
+	assert.Equal(t, "v2.chat", conn.Subprotocol())
+
+	conn = dial(t, url, WithSubprotocols("other"))
+	assert.Equal(t, "", conn.Subprotocol())
+}
+
+func TestDialRejected(t *testing.T) {
+	handler := func(w *response.Writer, req *request.Request) {
+		writeStatus(w, response.StatusNotFound, headers.NewHeaders())
+	}
+	s, err := server.ServeAddr("tcp", "127.0.0.1:0", handler)
+	require.NoError(t, err)
+	defer s.Close()
+
+	_, err = Dial(context.Background(), "ws://"+s.Addr().String()+"/")
+	assert.ErrorIs(t, err, ErrBadHandshake)
+
+	_, err = Dial(context.Background(), "http://"+s.Addr().String()+"/")
+	assert.Error(t, err)
+}
+
+func TestDialKeepsFramesAfterHandshake(t *testing.T) {
+	handler := func(w *response.Writer, req *request.Request) {
+		conn, err := Upgrade(w, req)
+		if err != nil {
+			return
+		}
+		// @@@ 101 바로 뒤에 보낸 frame이 client에서 response와 같은 버퍼로 읽힌다
+		_ = conn.WriteMessage(OpText, []byte("welcome"))
+		_ = conn.Close()
+	}
+	s, err := server.ServeAddr("tcp", "127.0.0.1:0", handler)
+	require.NoError(t, err)
+	defer s.Close()
+
+	// Test: handshake response 뒤에 이미 도착한 frame도 잃지 않는다
+	conn := dial(t, "ws://"+s.Addr().String()+"/")
+	op, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, OpText, op)
+	assert.Equal(t, "welcome", string(data))
+}